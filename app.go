@@ -28,10 +28,13 @@
 package kingpin
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strings"
+	"sync"
+	"unicode"
 )
 
 type Dispatch func(*ParseContext) error
@@ -44,22 +47,73 @@ type Application struct {
 	*flagGroup
 	*argGroup
 	*cmdGroup
-	initialized bool
-	Name        string
-	Help        string
-	validator   ApplicationValidator
+	initialized     bool
+	Name            string
+	Help            string
+	HelpFlag        *FlagClause // The built-in --help flag, which may be customised.
+	VersionFlag     *FlagClause // The --version flag added by Version(), or nil.
+	validator       ApplicationValidator
+	valueValidators []func(ValueLookup) error
+	structSeparator string
+	collectErrors   bool
+	separateBools   bool
+	messages        *Messages
+	exitCodes       ExitCodes
+	terminate       func(status int)
+	onTerminate     []func(status int)
+	onParsed        []func(command string, context *ParseContext)
+	writer          io.Writer
+	errorWriter     io.Writer
+	usageWriter     io.Writer
+	noExit          bool
+	onWarning       func(string)
+	usageOnError    UsageOnErrorMode
+	usePager        bool
+	flagsBeforeCmd  bool
+	slashFlags      bool
+	redefine        bool
+	optionsEnvar    string
+	widthDetector   WidthDetector
+	layout          *UsageLayout
+	numberFormat    *NumberFormat
+	dotEnv          []string
+	usageTemplate   string
+	onTemplateError func(error)
+	parseLock       sync.Mutex
+	parsed          bool
+	owner           *ParseContext
+	version         string
+	author          string
+	homepage        string
+	bugReports      string
+	helpCommand     *CmdClause
+	helpShort       byte
+	mixPositional   bool
 }
 
 // New creates a new Kingpin application instance.
 func New(name, help string) *Application {
 	a := &Application{
-		flagGroup: newFlagGroup(),
-		argGroup:  newArgGroup(),
-		Name:      name,
-		Help:      help,
+		flagGroup:       newFlagGroup(),
+		argGroup:        newArgGroup(),
+		Name:            name,
+		Help:            help,
+		structSeparator: ".",
+		messages:        &DefaultMessages,
+		layout:          &DefaultUsageLayout,
+		exitCodes:       ExitCodes{},
+		terminate:       os.Exit,
+		writer:          os.Stdout,
+		errorWriter:     os.Stderr,
+		usageWriter:     os.Stderr,
+		helpShort:       'h',
+	}
+	for category, code := range DefaultExitCodes {
+		a.exitCodes[category] = code
 	}
 	a.cmdGroup = newCmdGroup(a)
-	a.Flag("help", "Show help.").Dispatch(a.onHelp).Bool()
+	a.HelpFlag = a.Flag("help", "Show help.").Dispatch(a.onHelp)
+	a.HelpFlag.Bool()
 	return a
 }
 
@@ -69,38 +123,304 @@ func (a *Application) Validate(validator ApplicationValidator) *Application {
 	return a
 }
 
+// ValidateValues adds a function to check invariants between the values of
+// flags and arguments, such as that --start precedes --end. Validators run
+// once all values are parsed and before any Dispatch() actions of commands,
+// in the order they were added.
+func (a *Application) ValidateValues(validator func(ValueLookup) error) *Application {
+	a.valueValidators = append(a.valueValidators, validator)
+	return a
+}
+
+// validateValues runs the validators added by ValidateValues().
+func (a *Application) validateValues(context *ParseContext) error {
+	if len(a.valueValidators) > 0 {
+		context.retained = true
+	}
+	for _, validator := range a.valueValidators {
+		if err := validator(ValueLookup{context}); err != nil {
+			return categorize(ValidationError, err)
+		}
+	}
+	return nil
+}
+
+// AllowArgsAndCommands permits an application or command to define both
+// positional arguments and sub-commands. When parsing, if the next token
+// names a command it is selected, otherwise the token is consumed by the
+// arguments. This allows eg. "tool FILE" and "tool serve" to coexist.
+func (a *Application) AllowArgsAndCommands() *Application {
+	a.mixPositional = true
+	return a
+}
+
+// CollectErrors makes parsing continue after recoverable errors such as
+// unknown flags and missing required flags or arguments. If more than one
+// error is encountered, Parse() returns them all as ParseErrors.
+func (a *Application) CollectErrors() *Application {
+	a.collectErrors = true
+	return a
+}
+
+// SeparateBoolValues allows the value of a boolean flag to be given as a
+// separate argument, as in "--verbose false", as well as attached, as in
+// "--verbose=false". Only "true", "false", "1" and "0" are taken as values;
+// any other argument following the flag is parsed as usual. This is opt-in
+// as it changes the meaning of existing command lines, eg. where a boolean
+// flag precedes a positional argument of "1".
+func (a *Application) SeparateBoolValues() *Application {
+	a.separateBools = true
+	return a
+}
+
 // Parse parses command-line arguments. It returns the selected command and an
 // error. The selected command will be a space separated subcommand, if
 // subcommands have been configured.
 func (a *Application) Parse(args []string) (command string, err error) {
-	if err := a.init(); err != nil {
+	context, err := a.parseContext(Tokenize(args))
+	if err != nil {
 		return "", err
 	}
+	return context.command, nil
+}
+
+// ParseContext parses command-line arguments, returning the resulting
+// ParseContext.
+func (a *Application) ParseContext(args []string) (*ParseContext, error) {
 	context := Tokenize(args)
-	command, err = a.parse(context)
+	context.retained = true
+	return a.parseContext(context)
+}
+
+// ParsePartial parses command-line arguments up to the first unrecognised
+// flag, command or argument, returning the selected command and the
+// remaining unparsed arguments. This allows for two-stage parsing, where a
+// wrapper handles its own arguments then delegates the remainder.
+func (a *Application) ParsePartial(args []string) (command string, rest []string, err error) {
+	context := Tokenize(args)
+	context.partial = true
+	context, err = a.parseContext(context)
 	if err != nil {
-		return "", err
+		return "", nil, err
+	}
+	return context.command, context.rest, nil
+}
+
+// ParseArgs parses command-line arguments without side effects. It never
+// reads the environment, writes output or terminates the process, even when
+// help or the version is requested, in which case ErrHelp or ErrVersion is
+// returned. Warnings are available from ParseContext.Warnings(). Dispatch()
+// actions and validators are still run.
+//
+// This makes ParseArgs suitable as a fuzzing target.
+func (a *Application) ParseArgs(args []string) (*ParseContext, error) {
+	context := Tokenize(args)
+	context.pure = true
+	context.retained = true
+	return a.parseContext(context)
+}
+
+// parseContext parses context, then calls the OnParsed() functions. Parses
+// of the same application are serialised, except while Dispatch() actions
+// run, and values left by a previous parse are reset, so an application may
+// safely be parsed concurrently or repeatedly, including from an action. As
+// the targets of values are shared, results should be read from the returned
+// context when parsing concurrently.
+func (a *Application) parseContext(context *ParseContext) (*ParseContext, error) {
+	context, err := a.parseLocked(context)
+	if err != nil {
+		return nil, err
+	}
+	if !context.pure {
+		for _, hook := range a.onParsed {
+			hook(context.command, context)
+		}
+	}
+	return context, nil
+}
+
+// parseLocked parses context while holding the parse lock.
+func (a *Application) parseLocked(context *ParseContext) (_ *ParseContext, err error) {
+	a.parseLock.Lock()
+	defer a.parseLock.Unlock()
+	if err := a.init(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil && !context.pure {
+			a.reportUsageError(context, err)
+		}
+	}()
+	if !context.pure && len(a.onParsed) > 0 {
+		context.retained = true
+	}
+	a.takeValues(context)
+	var slashFlag slashFlagFunc
+	if a.slashFlags {
+		slashFlag = a.slashFlag
+	}
+	var options Tokens
+	var optionPositions []TokenPosition
+	context.app = a
+	context.collectErrors = a.collectErrors
+	context.separateBools = a.separateBools
+	context.messages = a.messages
+	context.numberFormat = a.numberFormat
+	if !context.pure {
+		context.onWarning = a.warn
+		if context.dotEnv, err = readDotEnv(a.dotEnv); err != nil {
+			return nil, fmt.Errorf(a.messages.DotEnvFailed, err)
+		}
+		if options, optionPositions, err = a.optionTokens(context, slashFlag); err != nil {
+			return nil, err
+		}
+	}
+	if a.slashFlags {
+		context.Tokens, context.positions, context.indexes = tokenize(context.args, slashFlag)
+		context.allTokens = context.Tokens
+	}
+	if len(options) > 0 {
+		context.options = make(map[*Token]TokenPosition, len(options))
+		for i, token := range options {
+			context.options[token] = optionPositions[i]
+		}
+		context.Tokens = append(options, context.Tokens...)
+	}
+	command, err := a.parse(context)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(context.Tokens) == 1 {
-		return "", fmt.Errorf("unexpected argument '%s'", context.Tokens)
-	} else if len(context.Tokens) > 0 {
-		return "", fmt.Errorf("unexpected arguments '%s'", context.Tokens)
+	switch {
+	case context.stopPartial():
+	case len(context.Tokens) == 1:
+		err = context.fail(fmt.Errorf(a.messages.UnexpectedArgument, context.Tokens))
+	case len(context.Tokens) > 0:
+		err = context.fail(fmt.Errorf(a.messages.UnexpectedArguments, context.Tokens))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := context.err(); err != nil {
+		return nil, err
 	}
 
-	return command, err
+	context.command = command
+	return context, nil
+}
+
+// takeValues gives the values of the clauses to context, which must hold the
+// parse lock. The values are copied into the context that last held them
+// first, if it may still be read, and then reset. Copying only when the
+// application is parsed again keeps a single parse free of copies.
+func (a *Application) takeValues(context *ParseContext) {
+	if a.owner == context {
+		return
+	}
+	if a.owner != nil && a.owner.retained {
+		a.owner.snapshotValues()
+	}
+	if a.parsed {
+		a.resetValues()
+	}
+	a.parsed = true
+	a.owner = context
+}
+
+// OnParsed adds a function to be called after each successful parse, with the
+// selected command, if any, and the resulting ParseContext. This is a single
+// point at which to record usage metrics, eg. with ParseContext.VisitSet().
+// Functions are called in the order they were added, but not by ParseArgs().
+// They are called once parsing has finished, so may parse the application
+// again.
+func (a *Application) OnParsed(hook func(command string, context *ParseContext)) *Application {
+	a.onParsed = append(a.onParsed, hook)
+	return a
+}
+
+// resetValues returns every value to its zero state before parsing again.
+// Files opened by the previous parse are closed.
+func (a *Application) resetValues() {
+	a.Walk(func(clause interface{}, path []string) error {
+		switch clause := clause.(type) {
+		case *FlagClause:
+			resetValue(clause.value)
+		case *ArgClause:
+			resetValue(clause.value)
+		}
+		return nil
+	})
 }
 
 // Version adds a --version flag for displaying the application version.
 func (a *Application) Version(version string) *Application {
-	a.Flag("version", "Show application version.").Dispatch(func(*ParseContext) error {
-		fmt.Println(version)
-		os.Exit(0)
-		return nil
-	}).Bool()
+	a.version = version
+	a.VersionFlag = a.Flag("version", "Show application version.").Dispatch(a.onVersion)
+	a.VersionFlag.Bool()
 	return a
 }
 
+// Author sets the author of the application, shown at the end of help.
+func (a *Application) Author(author string) *Application {
+	a.author = author
+	return a
+}
+
+// Homepage sets the URL of the application's homepage, shown at the end of
+// help.
+func (a *Application) Homepage(url string) *Application {
+	a.homepage = url
+	return a
+}
+
+// BugReports sets where bugs in the application should be reported, eg. the
+// URL of an issue tracker, shown at the end of help.
+func (a *Application) BugReports(url string) *Application {
+	a.bugReports = url
+	return a
+}
+
+func (a *Application) onVersion(context *ParseContext) error {
+	if context.pure {
+		return categorize(HelpRequested, ErrVersion)
+	}
+	fmt.Fprintln(a.writer, a.version)
+	return a.finish(ErrVersion)
+}
+
+// Writer sets the writer used for regular output, such as the version. The
+// default is os.Stdout.
+func (a *Application) Writer(w io.Writer) *Application {
+	a.writer = w
+	return a
+}
+
+// ErrorWriter sets the writer used by the package level error functions. The
+// default is os.Stderr.
+func (a *Application) ErrorWriter(w io.Writer) *Application {
+	a.errorWriter = w
+	return a
+}
+
+// UsageWriter sets the writer used to display help. The default is
+// os.Stderr.
+func (a *Application) UsageWriter(w io.Writer) *Application {
+	a.usageWriter = w
+	return a
+}
+
+// Capture replaces the writers of the application with stdout, for regular
+// output, and stderr, for errors and help, and its Terminate() function with
+// terminate. The returned function restores the previous writers and
+// function. This is intended for test helpers such as kingpintest.Run().
+func (a *Application) Capture(stdout, stderr io.Writer, terminate func(status int)) (restore func()) {
+	writer, errorWriter, usageWriter, previous := a.writer, a.errorWriter, a.usageWriter, a.terminate
+	a.Writer(stdout).ErrorWriter(stderr).UsageWriter(stderr).Terminate(terminate)
+	return func() {
+		a.writer, a.errorWriter, a.usageWriter, a.terminate = writer, errorWriter, usageWriter, previous
+	}
+}
+
 // Command adds a new top-level command.
 func (a *Application) Command(name, help string) *CmdClause {
 	return a.addCommand(name, help)
@@ -110,18 +430,20 @@ func (a *Application) init() error {
 	if a.initialized {
 		return nil
 	}
-	if a.cmdGroup.have() && a.argGroup.have() {
+	a.replaceRedefined()
+	if a.cmdGroup.have() && a.argGroup.have() && !a.mixPositional {
 		return fmt.Errorf("can't mix top-level Arg()s with Command()s")
 	}
 
 	if len(a.commands) > 0 {
-		cmd := a.Command("help", "Show help for a command.").Dispatch(a.onHelp)
-		cmd.Arg("command", "Command name.").String()
+		a.helpCommand = a.Command("help", "Show help for a command.").Dispatch(a.onHelp)
+		a.helpCommand.Arg("command", "Command name.").String()
 		// Make "help" command first in order. Also, Go's slice operations are woeful.
 		l := len(a.commandOrder) - 1
 		a.commandOrder = append(a.commandOrder[l:], a.commandOrder[:l]...)
 	}
 
+	a.assignHelpShort(a.HelpFlag, a.flagOrder)
 	if err := a.flagGroup.init(); err != nil {
 		return err
 	}
@@ -131,16 +453,187 @@ func (a *Application) init() error {
 	if err := a.argGroup.init(); err != nil {
 		return err
 	}
-	for _, cmd := range a.commands {
-		if err := cmd.init(); err != nil {
-			return err
-		}
-	}
 	a.initialized = true
 	return nil
 }
 
+// OptionsEnvar sets an environment variable holding options to be prepended
+// to the command-line arguments, in the manner of JAVA_OPTS, so that users
+// can set default flags for every invocation. The variable is split into
+// arguments as by the shell, honouring quotes and backslashes. If name is ""
+// the variable is named after the application, eg. "MY_APP_OPTS" for the
+// application "my-app".
+//
+// As the options precede the arguments, they may only be application flags,
+// unless AllowFlagsBeforeCommand() is also used, and flags given on the
+// command line override them. The options are not included in the remaining
+// arguments returned by ParsePartial(), nor in ParseContext.AllTokens().
+func (a *Application) OptionsEnvar(name string) *Application {
+	if name == "" {
+		name = strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToUpper(r)
+			}
+			return '_'
+		}, a.Name) + "_OPTS"
+	}
+	a.optionsEnvar = name
+	return a
+}
+
+// optionTokens returns the tokens of the options from OptionsEnvar(), if
+// any, and their positions within the options. They are tokenized separately
+// from the command-line arguments, so they are not reported in token
+// positions or in the remaining arguments of ParsePartial().
+func (a *Application) optionTokens(context *ParseContext, slashFlag slashFlagFunc) (Tokens, []TokenPosition, error) {
+	if a.optionsEnvar == "" {
+		return nil, nil, nil
+	}
+	options, err := splitWords(context.getenv(a.optionsEnvar))
+	if err != nil {
+		return nil, nil, fmt.Errorf(a.messages.InvalidOptions, a.optionsEnvar, err)
+	}
+	tokens, positions, _ := tokenize(options, slashFlag)
+	return tokens, positions, nil
+}
+
+// AllowRedefinition replaces a flag, argument or command that is defined
+// again with the same name, rather than reporting it as a duplicate. The last
+// definition wins, in the position of the first. This allows frameworks that
+// assemble applications from plugins to override the definitions of earlier
+// plugins. See also RemoveFlag() and RemoveCommand().
+func (a *Application) AllowRedefinition() *Application {
+	a.redefine = true
+	return a
+}
+
+// replaceRedefined applies AllowRedefinition() to the top-level definitions.
+func (a *Application) replaceRedefined() {
+	if a.redefine {
+		a.flagGroup.replaceRedefined()
+		a.argGroup.replaceRedefined()
+		a.cmdGroup.replaceRedefined()
+	}
+}
+
+// AllowFlagsBeforeCommand allows the flags of a command to be given before the
+// command on the command line, eg. "chat --channel=general post", as well
+// as after it.
+func (a *Application) AllowFlagsBeforeCommand() *Application {
+	a.flagsBeforeCmd = true
+	return a
+}
+
+// SlashFlags additionally accepts flags in the Windows style "/name" and
+// "/name:value", with "/?" requesting help. An argument is only treated as a
+// flag if it names one defined by the application or any of its commands, so
+// absolute paths continue to be positional arguments.
+func (a *Application) SlashFlags() *Application {
+	a.slashFlags = true
+	return a
+}
+
+// slashFlag resolves the name of a flag given as "/name".
+func (a *Application) slashFlag(name string) (TokenType, string, bool) {
+	if name == "?" {
+		return TokenLong, "help", true
+	}
+	long := strings.TrimPrefix(name, "no-")
+	var found TokenType = TokenEOL
+	a.Walk(func(clause interface{}, path []string) error {
+		if flag, ok := clause.(*FlagClause); ok {
+			if flag.hasName(name) || flag.hasName(long) {
+				found = TokenLong
+			} else if found == TokenEOL && flag.shorthand != 0 && string(flag.shorthand) == name {
+				found = TokenShort
+			}
+		}
+		return nil
+	})
+	return found, name, found != TokenEOL
+}
+
+// HelpShort sets the short flag given to the help flags of the application
+// and its commands, if they don't already have one and no other flag of the
+// same application or command uses it. The default is 'h'. Pass 0 to disable.
+func (a *Application) HelpShort(name byte) *Application {
+	a.helpShort = name
+	return a
+}
+
+// assignHelpShort gives a help flag the default short flag unless one of the
+// other flags available alongside it already uses it.
+func (a *Application) assignHelpShort(help *FlagClause, groups ...[]*FlagClause) {
+	if a.helpShort == 0 || help == nil || help.shorthand != 0 {
+		return
+	}
+	for _, flags := range groups {
+		for _, flag := range flags {
+			if flag.shorthand == a.helpShort {
+				return
+			}
+		}
+	}
+	help.shorthand = a.helpShort
+}
+
+// Check validates the definitions of all flags, arguments and commands
+// without parsing, returning every problem found rather than just the first.
+// Default values are also checked by setting them on copies of the values,
+// so the targets of the flags and arguments are untouched. Defaults of
+// cumulative values, and of custom values that can not be copied, are not
+// checked. The application itself is not modified: definitions replaced
+// under AllowRedefinition() are resolved on a copy. This is intended to be
+// called from tests, to assert the validity of large applications.
+func (a *Application) Check() (errs []error) {
+	a.parseLock.Lock()
+	c := a.Clone()
+	a.parseLock.Unlock()
+	c.replaceRedefined()
+	if c.cmdGroup.have() && c.argGroup.have() && !c.mixPositional {
+		errs = append(errs, fmt.Errorf("can't mix top-level Arg()s with Command()s"))
+	}
+	if !c.initialized && c.cmdGroup.have() {
+		if _, ok := c.commands["help"]; ok {
+			errs = append(errs, fmt.Errorf("duplicate command 'help'"))
+		}
+	}
+	errs = append(errs, c.flagGroup.check()...)
+	errs = append(errs, c.argGroup.check()...)
+	errs = append(errs, c.cmdGroup.check()...)
+	c.Walk(func(clause interface{}, path []string) error {
+		switch clause := clause.(type) {
+		case *FlagClause:
+			if err := checkDefault(clause.value, clause.defaultValues); err != nil {
+				errs = append(errs, fmt.Errorf(c.messages.InvalidFlagDefault, clause.name, localizeError(err, c.messages)))
+			}
+		case *ArgClause:
+			if err := checkDefault(clause.value, clause.defaultValues); err != nil {
+				errs = append(errs, fmt.Errorf(c.messages.InvalidArgDefault, clause.defaultValues[0], clause.name, localizeError(err, c.messages)))
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// checkDefault returns an error if the single, non-cumulative default value
+// can not be set on a copy of value.
+func checkDefault(value Value, defaults []string) error {
+	if len(defaults) != 1 || value == nil || isCumulative(value) {
+		return nil
+	}
+	c, ok := value.(cloner)
+	if !ok {
+		return nil
+	}
+	return c.clone().Set(defaults[0])
+}
+
 func (a *Application) onHelp(context *ParseContext) error {
+	if context.pure {
+		return categorize(HelpRequested, ErrHelp)
+	}
 	candidates := []string{}
 	for {
 		token := context.Peek()
@@ -157,15 +650,11 @@ func (a *Application) onHelp(context *ParseContext) error {
 		command := strings.Join(candidates[:i], " ")
 		cmd = a.findCommand(command)
 		if cmd != nil {
-			a.CommandUsage(os.Stderr, command)
 			break
 		}
 	}
-	if cmd == nil {
-		a.Usage(os.Stderr)
-	}
-	os.Exit(0)
-	return nil
+	a.showHelp(cmd)
+	return a.finish(ErrHelp)
 }
 
 func (a *Application) parse(context *ParseContext) (string, error) {
@@ -173,33 +662,124 @@ func (a *Application) parse(context *ParseContext) (string, error) {
 	runHelp := (context.Peek().Value == "help")
 
 	var err error
+	context.lenient(a, a.cmdGroup)
 	err = a.flagGroup.parse(context, runHelp)
 	if err != nil {
 		return "", err
 	}
-
-	selected := []string{}
+	context.recordValues("", a.flagGroup, a.argGroup)
 
 	// Parse arguments or commands.
-	if a.argGroup.have() {
-		err = a.argGroup.parse(context)
-	} else if a.cmdGroup.have() {
-		selected, err = a.cmdGroup.parse(context)
+	selected, err := parsePositional(context, a.argGroup, a.cmdGroup)
+	if err == nil && len(context.deferred) > 0 {
+		// No command defining the flag was selected.
+		token := context.deferred[0]
+		if token.Type == TokenShort {
+			err = fmt.Errorf(a.messages.UnknownShortFlag, token)
+		} else {
+			err = fmt.Errorf(a.messages.UnknownLongFlag, token)
+		}
 	}
-	if a.validator != nil {
-		err = a.validator(a)
+	if err == nil && a.validator != nil {
+		err = categorize(ValidationError, a.validator(a))
 	}
 	return strings.Join(selected, " "), err
 }
 
+// UsageOnErrorMode controls what is displayed when parsing fails.
+type UsageOnErrorMode int
+
+// Modes for UsageOnError().
+const (
+	// UsageOnErrorOff displays nothing, leaving the caller to report the
+	// error. This is the default.
+	UsageOnErrorOff UsageOnErrorMode = iota
+	// UsageOnErrorShortSummary displays the error and a hint to use --help.
+	UsageOnErrorShortSummary
+	// UsageOnErrorFullUsage displays the error followed by the usage of the
+	// selected command, or of the application.
+	UsageOnErrorFullUsage
+)
+
+// UsageOnError sets what is displayed on the error writer when parsing fails
+// due to a usage error. If this is set, MustParse() does not display the
+// error again.
+func (a *Application) UsageOnError(mode UsageOnErrorMode) *Application {
+	a.usageOnError = mode
+	return a
+}
+
+// reportUsageError displays err according to the UsageOnError() mode.
+func (a *Application) reportUsageError(context *ParseContext, err error) {
+	if ErrorCategoryOf(err) != UsageError {
+		return
+	}
+	cmd := context.SelectedCommand
+	switch a.usageOnError {
+	case UsageOnErrorShortSummary:
+		if cmd != nil {
+			a.Errorf(a.errorWriter, a.messages.TryCommandHelp, err, a.Name, cmd.FullCommand())
+		} else {
+			a.Errorf(a.errorWriter, a.messages.TryHelp, err)
+		}
+	case UsageOnErrorFullUsage:
+		a.Errorf(a.errorWriter, "%s", err)
+		if cmd != nil {
+			a.commandUsage(a.errorWriter, cmd, a.width(a.errorWriter))
+		} else {
+			a.Usage(a.errorWriter)
+		}
+	}
+}
+
+// OnWarning sets a function to be called with each warning produced while
+// parsing. By default warnings are printed to the error writer.
+func (a *Application) OnWarning(handler func(warning string)) *Application {
+	a.onWarning = handler
+	return a
+}
+
+func (a *Application) warn(warning string) {
+	if a.onWarning != nil {
+		a.onWarning(warning)
+		return
+	}
+	fmt.Fprintf(a.errorWriter, a.messages.WarningPrefix, a.Name)
+	fmt.Fprintln(a.errorWriter, warning)
+}
+
+// MustParse can be used with Parse(args) to exit with an error if parsing
+// fails, using the application's exit codes, messages and error writer.
+//
+//	command := app.MustParse(app.Parse(os.Args[1:]))
+func (a *Application) MustParse(command string, err error) string {
+	if errors.Is(err, ErrHelp) || errors.Is(err, ErrVersion) {
+		a.exit(HelpRequested)
+		return ""
+	}
+	var cmdErr *CommandError
+	if err != nil && a.usageOnError != UsageOnErrorOff && ErrorCategoryOf(err) == UsageError {
+		// Already reported by Parse().
+		a.exit(ErrorCategoryOf(err))
+	} else if errors.As(err, &cmdErr) {
+		a.Errorf(a.errorWriter, a.messages.TryCommandHelp, err, a.Name, cmdErr.Command.FullCommand())
+		a.exit(ErrorCategoryOf(err))
+	} else if err != nil {
+		a.Errorf(a.errorWriter, a.messages.TryHelp, err)
+		a.exit(ErrorCategoryOf(err))
+	}
+	return command
+}
+
 // Errorf prints an error message to w.
 func (a *Application) Errorf(w io.Writer, format string, args ...interface{}) {
-	fmt.Fprintf(w, a.Name+": error: "+format+"\n", args...)
+	fmt.Fprintf(w, a.messages.ErrorPrefix, a.Name)
+	fmt.Fprintf(w, format+"\n", args...)
 }
 
 func (a *Application) Fatalf(w io.Writer, format string, args ...interface{}) {
 	a.Errorf(w, format, args...)
-	os.Exit(1)
+	a.exit(RuntimeError)
 }
 
 // UsageErrorf prints an error message followed by usage information, then
@@ -207,7 +787,28 @@ func (a *Application) Fatalf(w io.Writer, format string, args ...interface{}) {
 func (a *Application) UsageErrorf(w io.Writer, format string, args ...interface{}) {
 	a.Errorf(w, format, args...)
 	a.Usage(w)
-	os.Exit(1)
+	a.exit(UsageError)
+}
+
+// FatalUsage prints an error message followed by usage information to the
+// error writer, then exits with a non-zero status.
+func (a *Application) FatalUsage(format string, args ...interface{}) {
+	a.Errorf(a.errorWriter, format, args...)
+	a.Usage(a.errorWriter)
+	a.exit(UsageError)
+}
+
+// FatalUsageContext is like FatalUsage() but displays usage for the command
+// selected in context, if any. This is useful for bailing out of a Dispatch()
+// action with contextual help.
+func (a *Application) FatalUsageContext(context *ParseContext, format string, args ...interface{}) {
+	a.Errorf(a.errorWriter, format, args...)
+	if context.SelectedCommand != nil {
+		a.CommandUsage(a.errorWriter, context.SelectedCommand.FullCommand())
+	} else {
+		a.Usage(a.errorWriter)
+	}
+	a.exit(UsageError)
 }
 
 // FatalIfError prints an error and exits if err is not nil. The error is printed
@@ -218,6 +819,6 @@ func (a *Application) FatalIfError(w io.Writer, err error, prefix string) {
 			prefix += ": "
 		}
 		a.Errorf(w, prefix+"%s", err)
-		os.Exit(1)
+		a.exit(RuntimeError)
 	}
 }
@@ -1,6 +1,13 @@
 package kingpin
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -127,3 +134,501 @@ func TestArgsLooksLikeFlagsWithConsumeRemainder(t *testing.T) {
 	_, err := a.Parse([]string{"hello", "-world"})
 	assert.Error(t, err)
 }
+
+func TestParseContextValues(t *testing.T) {
+	app := New("test", "")
+	app.Flag("debug", "").Bool()
+	post := app.Command("post", "")
+	post.Flag("channel", "").Default("general").String()
+	post.Arg("count", "").Int()
+	app.Command("other", "").Flag("other", "").String()
+
+	context, err := app.ParseContext([]string{"--debug", "post", "3"})
+	assert.NoError(t, err)
+	values := context.Values()
+	assert.Equal(t, "true", values["debug"])
+	assert.Equal(t, "general", values["post.channel"])
+	assert.Equal(t, "3", values["post.count"])
+	_, ok := values["other.other"]
+	assert.False(t, ok)
+	assert.Equal(t, 3, context.TypedValues()["post.count"])
+}
+
+func TestCollectErrors(t *testing.T) {
+	app := New("test", "").CollectErrors()
+	app.Flag("name", "").Required().String()
+	cmd := app.Command("cmd", "")
+	cmd.Flag("flag", "").String()
+	cmd.Arg("a", "").Required().String()
+	cmd.Arg("b", "").Required().String()
+
+	_, err := app.Parse([]string{"--unknown", "-x", "cmd"})
+	assert.Error(t, err)
+	errs, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 5, len(errs))
+	assert.Contains(t, err.Error(), "unknown long flag '--unknown'")
+	assert.Contains(t, err.Error(), "required flag --name not provided")
+	assert.Contains(t, err.Error(), "'b' is required")
+}
+
+func TestCollectErrorsSkipsUnknownFlagValues(t *testing.T) {
+	app := New("test", "").CollectErrors()
+	files := app.Arg("files", "").Strings()
+	_, err := app.Parse([]string{"--bogus", "value", "--other=x", "-q", "a", "b"})
+	errs, ok := err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(errs))
+	assert.Contains(t, err.Error(), "unknown long flag '--bogus'")
+	assert.Contains(t, err.Error(), "unknown long flag '--other'")
+	assert.Contains(t, err.Error(), "unknown short flag '-q'")
+	assert.Equal(t, []string{"b"}, *files)
+
+	// A command name following an unknown flag still selects the command.
+	app = New("test", "").CollectErrors()
+	app.Command("cmd", "").Arg("name", "").Required().String()
+	_, err = app.Parse([]string{"--bogus", "cmd"})
+	errs, ok = err.(ParseErrors)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(errs))
+	assert.Contains(t, err.Error(), "unknown long flag '--bogus'")
+	assert.Contains(t, err.Error(), "'name' is required")
+}
+
+func TestWriters(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	usage := bytes.NewBuffer(nil)
+	app := New("test", "").Writer(out).UsageWriter(usage).Terminate(func(int) {})
+	app.Version("1.2.3")
+	app.Parse([]string{"--version"})
+	assert.Equal(t, "1.2.3\n", out.String())
+	app.Parse([]string{"--help"})
+	assert.Contains(t, usage.String(), "usage: test")
+}
+
+func TestParsePartial(t *testing.T) {
+	app := New("wrapper", "")
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	app.Command("run", "").Flag("dry-run", "").Bool()
+
+	command, rest, err := app.ParsePartial([]string{"-v", "run", "--dry-run", "--other", "x", "y"})
+	assert.NoError(t, err)
+	assert.Equal(t, "run", command)
+	assert.Equal(t, []string{"--other", "x", "y"}, rest)
+	assert.True(t, *verbose)
+
+	command, rest, err = app.ParsePartial([]string{"-vq", "run"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", command)
+	assert.Equal(t, []string{"-q", "run"}, rest)
+
+	_, rest, err = app.ParsePartial([]string{"tool", "--", "-x"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tool", "--", "-x"}, rest)
+
+	_, rest, err = app.ParsePartial([]string{"run"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{}, rest)
+}
+
+func TestParseArgsHasNoSideEffects(t *testing.T) {
+	os.Setenv("KINGPIN_TEST_PARSE_ARGS", "from-env")
+	defer os.Unsetenv("KINGPIN_TEST_PARSE_ARGS")
+	w := &bytes.Buffer{}
+	app := New("test", "").Version("1.0").Writer(w).UsageWriter(w).ErrorWriter(w)
+	app.Terminate(func(int) { t.Fatal("terminated") })
+	name := app.Flag("name", "").Default("default").OverrideDefaultFromEnvar("KINGPIN_TEST_PARSE_ARGS").String()
+	app.Flag("old", "").Deprecated("use --name").Bool()
+	cmd := app.Command("cmd", "")
+
+	context, err := app.ParseArgs([]string{"--old", "cmd"})
+	assert.NoError(t, err)
+	assert.Equal(t, cmd, context.SelectedCommand)
+	assert.Equal(t, "default", *name)
+	assert.Equal(t, 1, len(context.Warnings()))
+
+	_, err = app.ParseArgs([]string{"--help"})
+	assert.Equal(t, ErrHelp, errors.Unwrap(err))
+	_, err = app.ParseArgs([]string{"cmd", "--help"})
+	assert.Equal(t, ErrHelp, errors.Unwrap(err))
+	_, err = app.ParseArgs([]string{"--version"})
+	assert.Equal(t, ErrVersion, errors.Unwrap(err))
+	assert.Equal(t, "", w.String())
+
+	_, err = app.Parse([]string{"cmd"})
+	assert.NoError(t, err)
+	assert.Equal(t, "from-env", *name)
+}
+
+func TestCheck(t *testing.T) {
+	app := New("test", "")
+	app.Flag("a", "").Short('x').Bool()
+	app.Flag("b", "").Short('x').Bool()
+	app.Flag("port", "").Default("invalid").Int()
+	app.Flag("untyped", "")
+	cmd := app.Command("cmd", "")
+	cmd.Arg("optional", "").String()
+	cmd.Arg("required", "").Required().String()
+	cmd.Command("sub", "")
+	errs := app.Check()
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	assert.Equal(t, []string{
+		"duplicate short flag -x",
+		"no type defined for --untyped (eg. .String())",
+		"can't mix Arg()s with Command()s",
+		"required arguments found after non-required",
+		`default value for --port is invalid: strconv.ParseInt: parsing "invalid": invalid syntax`,
+	}, messages)
+
+	assert.Empty(t, New("test", "").Check())
+}
+
+func TestCheckLeavesTargetsUntouched(t *testing.T) {
+	app := New("test", "")
+	var port int
+	var name string
+	app.Flag("port", "").Default("8080").IntVar(&port)
+	app.Arg("name", "").Default("web").StringVar(&name)
+	port, name = 1, "set"
+	assert.Empty(t, app.Check())
+	assert.Equal(t, 1, port)
+	assert.Equal(t, "set", name)
+}
+
+func TestCheckLeavesDefinitionsUntouched(t *testing.T) {
+	app := New("test", "").AllowRedefinition()
+	app.Flag("name", "").String()
+	app.Flag("name", "").Int()
+	app.Arg("count", "").Default("many").Int()
+	errs := app.Check()
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], `invalid default value 'many' for argument 'count': strconv.ParseInt: parsing "many": invalid syntax`)
+	assert.Len(t, app.flagOrder, 3)
+	assert.IsType(t, (*stringValue)(nil), app.flagOrder[1].value)
+}
+
+func BenchmarkParseLargeApplication(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		app := New("test", "").Version("1.0").NoExit().Writer(ioutil.Discard)
+		for c := 0; c < 500; c++ {
+			cmd := app.Command(fmt.Sprintf("cmd%d", c), "")
+			for f := 0; f < 10; f++ {
+				cmd.Flag(fmt.Sprintf("flag%d", f), "").Short(byte('a' + f)).String()
+			}
+		}
+		app.Parse([]string{"--version"})
+	}
+}
+
+func TestCustomizeHelpAndVersionFlags(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").Writer(w).UsageWriter(w).Version("1.0")
+	app.HelpFlag.Short('h').Rename("usage")
+	app.VersionFlag.Short('V').Hidden()
+	_, err := app.ParseArgs([]string{"-h"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.ParseArgs([]string{"--usage"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.ParseArgs([]string{"--help"})
+	assert.EqualError(t, err, "unknown long flag '--help'")
+	_, err = app.ParseArgs([]string{"-V"})
+	assert.True(t, errors.Is(err, ErrVersion))
+
+	app.Usage(w)
+	assert.Contains(t, w.String(), "-h, --usage")
+	assert.NotContains(t, w.String(), "--version")
+}
+
+func TestRenameAfterCheck(t *testing.T) {
+	app := New("test", "")
+	assert.Empty(t, app.Check())
+	app.HelpFlag.Rename("usage")
+	assert.Empty(t, app.Check())
+	_, err := app.ParseArgs([]string{"--usage"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.ParseArgs([]string{"--help"})
+	assert.EqualError(t, err, "unknown long flag '--help'")
+}
+
+func TestHelpShort(t *testing.T) {
+	app := New("test", "")
+	app.Command("cmd", "")
+	_, err := app.ParseArgs([]string{"-h"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.ParseArgs([]string{"cmd", "-h"})
+	assert.True(t, errors.Is(err, ErrHelp))
+
+	app = New("test", "")
+	host := app.Flag("host", "").Short('h').String()
+	_, err = app.ParseArgs([]string{"-h", "example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", *host)
+
+	app = New("test", "").HelpShort('?')
+	_, err = app.ParseArgs([]string{"-?"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.ParseArgs([]string{"-h"})
+	assert.EqualError(t, err, "unknown short flag '-h'")
+
+	app = New("test", "").HelpShort(0)
+	_, err = app.ParseArgs([]string{"-h"})
+	assert.EqualError(t, err, "unknown short flag '-h'")
+}
+
+func TestUsageOnError(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").ErrorWriter(w)
+	app.Flag("debug", "Enable debug.").Bool()
+	app.Command("cmd", "").Arg("arg", "").Required().String()
+
+	_, err := app.Parse([]string{"--foo"})
+	assert.Error(t, err)
+	assert.Equal(t, "", w.String())
+
+	app.UsageOnError(UsageOnErrorShortSummary)
+	_, err = app.Parse([]string{"--foo"})
+	assert.Error(t, err)
+	assert.Equal(t, "test: error: unknown long flag '--foo', try --help\n", w.String())
+	w.Reset()
+	_, err = app.Parse([]string{"cmd"})
+	assert.Error(t, err)
+	assert.Equal(t, "test: error: cmd: 'arg' is required, try 'test cmd --help'\n", w.String())
+	w.Reset()
+	_, err = app.ParseArgs([]string{"--foo"})
+	assert.Error(t, err)
+	assert.Equal(t, "", w.String())
+
+	app.UsageOnError(UsageOnErrorFullUsage)
+	_, err = app.Parse([]string{"--foo"})
+	assert.Error(t, err)
+	assert.Contains(t, w.String(), "test: error: unknown long flag '--foo'\nusage: test")
+	assert.Contains(t, w.String(), "Enable debug.")
+}
+
+func TestSlashFlags(t *testing.T) {
+	app := New("test", "").SlashFlags()
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	copyCmd := app.Command("copy", "")
+	mode := copyCmd.Flag("mode", "").String()
+	dest := copyCmd.Arg("dest", "").String()
+
+	selected, err := app.Parse([]string{"/v", "copy", "/mode:fast", "/tmp/out"})
+	assert.NoError(t, err)
+	assert.Equal(t, "copy", selected)
+	assert.True(t, *verbose)
+	assert.Equal(t, "fast", *mode)
+	assert.Equal(t, "/tmp/out", *dest)
+
+	_, err = app.Parse([]string{"/no-verbose", "copy", "--mode", "slow"})
+	assert.NoError(t, err)
+	assert.False(t, *verbose)
+	assert.Equal(t, "slow", *mode)
+	assert.Equal(t, "", *dest)
+
+	_, err = app.ParseArgs([]string{"/?"})
+	assert.True(t, errors.Is(err, ErrHelp))
+}
+
+func TestValidateValues(t *testing.T) {
+	dispatched := false
+	app := New("test", "")
+	app.Flag("start", "").Int()
+	app.Flag("end", "").Default("10").Int()
+	deploy := app.Command("deploy", "").Dispatch(func(*ParseContext) error {
+		dispatched = true
+		return nil
+	})
+	deploy.Flag("url", "").String()
+	deploy.Flag("host", "").String()
+	app.ValidateValues(func(v ValueLookup) error {
+		if v.Value("start").(int) >= v.Value("end").(int) {
+			return fmt.Errorf("--start must precede --end")
+		}
+		return nil
+	})
+	app.ValidateValues(func(v ValueLookup) error {
+		if v.IsSet("deploy.url") == v.IsSet("deploy.host") {
+			return fmt.Errorf("exactly one of --url and --host is required")
+		}
+		assert.Equal(t, SourceDefault, v.Source("end"))
+		assert.Nil(t, v.Value("missing"))
+		return nil
+	})
+
+	_, err := app.Parse([]string{"--start=1", "deploy", "--url=http://example.com"})
+	assert.NoError(t, err)
+	assert.True(t, dispatched)
+
+	dispatched = false
+	_, err = app.Parse([]string{"--start=10", "deploy", "--url=http://example.com"})
+	assert.EqualError(t, err, "--start must precede --end")
+	assert.Equal(t, ValidationError, ErrorCategoryOf(err))
+	assert.False(t, dispatched)
+
+	_, err = app.Parse([]string{"deploy", "--url=http://example.com", "--host=example.com"})
+	assert.EqualError(t, err, "exactly one of --url and --host is required")
+	assert.False(t, dispatched)
+}
+
+func TestGetClauses(t *testing.T) {
+	app := New("test", "")
+	debug := app.Flag("debug", "").Bool()
+	remote := app.Command("remote", "")
+	add := remote.Command("add", "")
+	add.Arg("name", "").String()
+	add.Flag("fetch", "").Bool()
+
+	assert.Equal(t, remote, app.GetCommand("remote"))
+	assert.Equal(t, add, app.GetCommand("remote", "add"))
+	assert.Equal(t, add, remote.GetCommand("add"))
+	assert.Nil(t, app.GetCommand("remote", "remove"))
+	assert.Nil(t, app.GetCommand())
+	assert.Nil(t, app.GetFlag("verbose"))
+	assert.Nil(t, add.GetArg("url"))
+
+	app.GetFlag("debug").Short('d')
+	app.GetCommand("remote", "add").GetArg("name").Required()
+	app.GetCommand("remote", "add").GetFlag("fetch").Default("true")
+	_, err := app.Parse([]string{"-d", "remote", "add"})
+	assert.EqualError(t, err, "remote add: 'name' is required")
+	_, err = app.Parse([]string{"-d", "remote", "add", "origin"})
+	assert.NoError(t, err)
+	assert.True(t, *debug)
+}
+
+func TestRemoveClauses(t *testing.T) {
+	app := New("test", "")
+	app.Flag("debug", "").Bool()
+	remote := app.Command("remote", "")
+	remote.Command("add", "")
+	remote.Command("remove", "")
+	app.Command("push", "")
+
+	assert.NotNil(t, app.RemoveFlag("help"))
+	assert.Nil(t, app.RemoveFlag("help"))
+	assert.NotNil(t, remote.RemoveCommand("remove"))
+	assert.NotNil(t, app.RemoveCommand("push"))
+	assert.Nil(t, app.RemoveCommand("pull"))
+
+	_, err := app.Parse([]string{"--help"})
+	assert.EqualError(t, err, "unknown long flag '--help'")
+	_, err = app.Parse([]string{"push"})
+	assert.Error(t, err)
+	_, err = app.Parse([]string{"remote", "remove"})
+	assert.Error(t, err)
+	selected, err := app.Parse([]string{"--debug", "remote", "add"})
+	assert.NoError(t, err)
+	assert.Equal(t, "remote add", selected)
+	assert.NotPanics(t, func() { app.Clone() })
+}
+
+func TestAllowRedefinition(t *testing.T) {
+	define := func(app *Application) (level, port *int) {
+		app.Flag("level", "").Int()
+		app.Flag("debug", "").Bool()
+		app.Command("serve", "Original.").Flag("port", "").Default("80").Int()
+		app.Command("check", "")
+		// A plugin overrides the definitions of the application.
+		level = app.Flag("level", "").Default("3").Int()
+		port = app.Command("serve", "Replacement.").Flag("port", "").Default("8080").Int()
+		return
+	}
+
+	app := New("test", "")
+	define(app)
+	_, err := app.Parse([]string{"serve"})
+	assert.EqualError(t, err, "duplicate long flag --level")
+
+	app = New("test", "").AllowRedefinition()
+	level, port := define(app)
+	assert.Empty(t, app.Check())
+	selected, err := app.Parse([]string{"serve"})
+	assert.NoError(t, err)
+	assert.Equal(t, "serve", selected)
+	assert.Equal(t, 3, *level)
+	assert.Equal(t, 8080, *port)
+	assert.Equal(t, "level", app.flagOrder[1].name)
+	assert.Equal(t, "Replacement.", app.GetCommand("serve").help)
+	assert.Equal(t, []*CmdClause{app.helpCommand, app.GetCommand("serve"), app.GetCommand("check")}, app.commandOrder)
+}
+
+func TestOptionsEnvar(t *testing.T) {
+	app := New("my-app", "").OptionsEnvar("")
+	assert.Equal(t, "MY_APP_OPTS", app.optionsEnvar)
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	level := app.Flag("level", "").Default("1").Int()
+	files := app.Arg("files", "").Strings()
+
+	os.Setenv("MY_APP_OPTS", "-v --level=2")
+	defer os.Unsetenv("MY_APP_OPTS")
+	_, err := app.Parse([]string{"--level=3", "a"})
+	assert.NoError(t, err)
+	assert.True(t, *verbose)
+	assert.Equal(t, 3, *level)
+	assert.Equal(t, []string{"a"}, *files)
+
+	// ParseArgs() does not read the environment.
+	_, err = app.ParseArgs([]string{"a"})
+	assert.NoError(t, err)
+	assert.False(t, *verbose)
+
+	os.Setenv("MY_APP_OPTS", "'-v")
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "invalid $MY_APP_OPTS: unterminated quote or escape")
+}
+
+func TestOptionsEnvarParsePartial(t *testing.T) {
+	app := New("wrapper", "").OptionsEnvar("")
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	level := app.Flag("level", "").Int()
+	app.Command("run", "")
+
+	os.Setenv("WRAPPER_OPTS", "-v --level 2")
+	defer os.Unsetenv("WRAPPER_OPTS")
+	command, rest, err := app.ParsePartial([]string{"run", "--other", "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "run", command)
+	assert.Equal(t, []string{"--other", "x"}, rest)
+	assert.True(t, *verbose)
+	assert.Equal(t, 2, *level)
+
+	// Unknown options are not passed on as command-line arguments.
+	os.Setenv("WRAPPER_OPTS", "--other")
+	_, rest, err = app.ParsePartial([]string{"x"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"x"}, rest)
+
+	// Positions refer to the command-line arguments alone.
+	os.Setenv("WRAPPER_OPTS", "--level=3")
+	context, err := app.ParseContext([]string{"-v", "run"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Token{{TokenShort, "v"}, {TokenArg, "run"}}, context.AllTokens())
+	assert.Equal(t, TokenPosition{Arg: 1}, context.Position(1))
+	assert.Equal(t, 3, *level)
+}
+
+func TestOnParsed(t *testing.T) {
+	app := New("test", "")
+	app.Flag("debug", "").Bool()
+	app.Flag("level", "").Default("1").Int()
+	app.Command("remote", "").Command("add", "").Arg("name", "").String()
+	invocations := []string{}
+	app.OnParsed(func(command string, context *ParseContext) {
+		flags := []string{}
+		context.VisitSet(func(flag *FlagClause, value string) {
+			flags = append(flags, flag.Model().Name)
+		})
+		invocations = append(invocations, command+" "+strings.Join(flags, ","))
+	})
+
+	_, err := app.Parse([]string{"--debug", "remote", "add", "origin"})
+	assert.NoError(t, err)
+	_, err = app.Parse([]string{"remote", "remove"})
+	assert.Error(t, err)
+	_, err = app.ParseArgs([]string{"remote", "add", "origin"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"remote add debug"}, invocations)
+}
@@ -20,6 +20,27 @@ func (a *argGroup) Arg(name, help string) *ArgClause {
 	return arg
 }
 
+// GetArg returns the argument with the given name, or nil, so that it may be
+// configured further.
+func (a *argGroup) GetArg(name string) *ArgClause {
+	for _, arg := range a.args {
+		if arg.name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+func (a *argGroup) visibleArgs() int {
+	count := 0
+	for _, arg := range a.args {
+		if !arg.hidden {
+			count++
+		}
+	}
+	return count
+}
+
 func (a *argGroup) parse(context *ParseContext) error {
 	i := 0
 	var last *Token
@@ -29,7 +50,15 @@ func (a *argGroup) parse(context *ParseContext) error {
 		token := context.Peek()
 		if token.Type == TokenEOL {
 			if consumed == 0 && arg.required {
-				return fmt.Errorf("'%s' is required", arg.name)
+				if err := context.fail(fmt.Errorf(context.msg().RequiredArg, arg.name)); err != nil {
+					return err
+				}
+				// Report all missing required arguments.
+				for _, arg := range a.args[i+1:] {
+					if arg.required {
+						context.fail(fmt.Errorf(context.msg().RequiredArg, arg.name))
+					}
+				}
 			}
 			break
 		}
@@ -42,7 +71,7 @@ func (a *argGroup) parse(context *ParseContext) error {
 
 		if arg.consumesRemainder() {
 			if last == context.Peek() {
-				return fmt.Errorf("expected positional arguments <%s> but got '%s'", arg.name, last)
+				return fmt.Errorf(context.msg().ExpectedPositional, arg.name, last)
 			}
 			consumed++
 		} else {
@@ -51,54 +80,102 @@ func (a *argGroup) parse(context *ParseContext) error {
 		last = token
 	}
 
-	// Set defaults for all remaining args.
+	// Set defaults for all remaining args, skipping a cumulative argument that
+	// consumed values.
+	if consumed > 0 {
+		i++
+	}
 	for i < len(a.args) {
 		arg := a.args[i]
-		if arg.defaultValue != "" {
-			if err := arg.value.Set(arg.defaultValue); err != nil {
-				return fmt.Errorf("invalid default value '%s' for argument '%s'", arg.defaultValue, arg.name)
+		values, err := arg.defaultFor(context)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := context.set(arg, arg.value, value, SourceDefault); err != nil {
+				return fmt.Errorf(context.msg().InvalidArgDefault, value, arg.name, err)
 			}
 		}
 		i++
 	}
+
+	for _, arg := range a.args {
+		if arg.requiredBy(context, arg) {
+			if err := context.fail(fmt.Errorf(context.msg().RequiredArg, arg.name)); err != nil {
+				return err
+			}
+		}
+		if err := arg.checkOccurrences(context, arg); err != nil {
+			if err := context.fail(err); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// replaceRedefined replaces each argument with the last argument defined
+// with the same name, keeping the position of the first.
+func (a *argGroup) replaceRedefined() {
+	index := map[string]int{}
+	out := a.args[:0]
+	for _, arg := range a.args {
+		if i, ok := index[arg.name]; ok {
+			out[i] = arg
+			continue
+		}
+		index[arg.name] = len(out)
+		out = append(out, arg)
+	}
+	a.args = out
+}
+
 func (a *argGroup) init() error {
+	if errs := a.check(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// check returns all problems with the definitions of the arguments.
+func (a *argGroup) check() (errs []error) {
 	required := 0
 	seen := map[string]struct{}{}
 	previousArgMustBeLast := false
 	for i, arg := range a.args {
 		if previousArgMustBeLast {
-			return fmt.Errorf("Args() can't be followed by another argument '%s'", arg.name)
+			errs = append(errs, fmt.Errorf("Args() can't be followed by another argument '%s'", arg.name))
 		}
 		if arg.consumesRemainder() {
 			previousArgMustBeLast = true
 		}
 		if _, ok := seen[arg.name]; ok {
-			return fmt.Errorf("duplicate argument '%s'", arg.name)
+			errs = append(errs, fmt.Errorf("duplicate argument '%s'", arg.name))
 		}
 		seen[arg.name] = struct{}{}
 		if arg.required && required != i {
-			return fmt.Errorf("required arguments found after non-required")
+			errs = append(errs, fmt.Errorf("required arguments found after non-required"))
 		}
 		if arg.required {
 			required++
 		}
 		if err := arg.init(); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return
 }
 
 type ArgClause struct {
 	parserMixin
-	name         string
-	help         string
-	defaultValue string
-	required     bool
-	dispatch     Dispatch
+	name          string
+	help          string
+	defaultValues []string
+	defaultFunc   func() (string, error)
+	required      bool
+	hidden        bool
+	dispatch      Dispatch
+	validator     ArgValidator
 }
 
 func newArg(name, help string) *ArgClause {
@@ -110,10 +187,7 @@ func newArg(name, help string) *ArgClause {
 }
 
 func (a *ArgClause) consumesRemainder() bool {
-	if r, ok := a.value.(remainderArg); ok {
-		return r.IsCumulative()
-	}
-	return false
+	return isCumulative(a.value)
 }
 
 // Required arguments must be input by the user. They can not have a Default() value provided.
@@ -122,36 +196,143 @@ func (a *ArgClause) Required() *ArgClause {
 	return a
 }
 
+// RequiredIf makes the argument required when predicate returns true. The
+// predicate is called once the arguments have been parsed.
+func (a *ArgClause) RequiredIf(predicate func(*ParseContext) bool) *ArgClause {
+	a.requiredIf = predicate
+	return a
+}
+
+// Hidden hides the argument from usage but still allows it to be used.
+func (a *ArgClause) Hidden() *ArgClause {
+	a.hidden = true
+	return a
+}
+
 // Default value for this argument. It *must* be parseable by the value of the argument.
-func (a *ArgClause) Default(value string) *ArgClause {
-	a.defaultValue = value
+// Cumulative arguments may be given several values, eg. Default("a", "b").
+func (a *ArgClause) Default(values ...string) *ArgClause {
+	a.defaultValues = defaultValues(values)
+	return a
+}
+
+// DefaultFunc sets a function that computes the default value for this
+// argument. It is only called if the argument is not provided, so may be
+// expensive, and is ignored if Default() is also set.
+func (a *ArgClause) DefaultFunc(fn func() (string, error)) *ArgClause {
+	a.defaultFunc = fn
+	return a
+}
+
+// DefaultFrom sets a function that computes the default value for this
+// argument from other values, once they have all been set. See
+// FlagClause.DefaultFrom().
+func (a *ArgClause) DefaultFrom(fn func(*ParseContext) (string, error)) *ArgClause {
+	a.defaultFrom = fn
+	return a
+}
+
+// defaultFor returns the values of the argument when it is not provided, if
+// any.
+func (a *ArgClause) defaultFor(context *ParseContext) ([]string, error) {
+	if len(a.defaultValues) > 0 || a.defaultFunc == nil {
+		return a.defaultValues, nil
+	}
+	v, err := a.defaultFunc()
+	if err != nil {
+		return nil, fmt.Errorf(context.msg().DefaultFuncFailed, "<"+a.name+">", err)
+	}
+	if v == "" {
+		return nil, nil
+	}
+	return []string{v}, nil
+}
+
+// Cumulative marks the argument's value as cumulative, so that it consumes all
+// remaining positional arguments.
+func (a *ArgClause) Cumulative() *ArgClause {
+	a.setCumulative()
+	return a
+}
+
+// MinOccurrences requires a cumulative argument to be provided at least n
+// times. A default value counts as one occurrence.
+func (a *ArgClause) MinOccurrences(n int) *ArgClause {
+	a.minOccurrences = n
+	return a
+}
+
+// MaxOccurrences allows a cumulative argument to be provided at most n times.
+func (a *ArgClause) MaxOccurrences(n int) *ArgClause {
+	a.maxOccurrences = n
+	return a
+}
+
+// HintAction registers a function that provides completion candidates for
+// the argument.
+func (a *ArgClause) HintAction(action HintAction) *ArgClause {
+	a.hintActions = append(a.hintActions, action)
 	return a
 }
 
+// HintOptions registers fixed completion candidates for the argument.
+func (a *ArgClause) HintOptions(options ...string) *ArgClause {
+	return a.HintAction(func() []string { return options })
+}
+
+// ArgValidator validates a single command-line token matched by an argument.
+type ArgValidator func(value string) error
+
+// Validate sets a function to validate each command-line token matched by
+// the argument, before it is parsed. Errors are reported with the name of the
+// argument.
+func (a *ArgClause) Validate(validator ArgValidator) *ArgClause {
+	a.validator = validator
+	return a
+}
+
+// IsSetByUser returns true if the argument was given on the command line in
+// the parse described by context, rather than defaulted.
+func (a *ArgClause) IsSetByUser(context *ParseContext) bool {
+	return context.setByUser(a)
+}
+
 func (a *ArgClause) Dispatch(dispatch Dispatch) *ArgClause {
 	a.dispatch = dispatch
 	return a
 }
 
 func (a *ArgClause) init() error {
-	if a.required && a.defaultValue != "" {
+	if a.required && (len(a.defaultValues) > 0 || a.defaultFunc != nil || a.defaultFrom != nil) {
 		return fmt.Errorf("required argument '%s' with unusable default value", a.name)
 	}
 	if a.value == nil {
 		return fmt.Errorf("no parser defined for arg '%s'", a.name)
 	}
-	return nil
+	if len(a.defaultValues) > 1 && !a.consumesRemainder() {
+		return fmt.Errorf("multiple default values for non-cumulative arg '%s'", a.name)
+	}
+	if (a.minOccurrences > 0 || a.maxOccurrences > 0) && !a.consumesRemainder() {
+		return fmt.Errorf("occurrence limits on non-cumulative arg '%s'", a.name)
+	}
+	return a.checkConstraints("arg '" + a.name + "'")
 }
 
 func (a *ArgClause) parse(context *ParseContext) error {
 	token := context.Peek()
 	if token.Type == TokenArg {
-		if err := a.value.Set(token.Value); err != nil {
+		if a.validator != nil {
+			if err := a.validator(token.Value); err != nil {
+				return fmt.Errorf(context.msg().InvalidArg, a.name, err)
+			}
+		}
+		if err := context.set(a, a.value, token.Value, SourceCommandLine); err != nil {
 			return err
 		}
+		context.argsSeen = append(context.argsSeen, a)
 		if a.dispatch != nil {
-			if err := a.dispatch(context); err != nil {
-				return err
+			if err := context.dispatch(a.dispatch); err != nil {
+				return categorize(RuntimeError, err)
 			}
 		}
 		context.Next()
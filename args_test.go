@@ -1,6 +1,10 @@
 package kingpin
 
 import (
+	"bytes"
+	"fmt"
+	"net"
+
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -45,3 +49,103 @@ func TestInvalidArgsDefaultCanBeOverridden(t *testing.T) {
 	err := a.parse(tokens)
 	assert.Error(t, err)
 }
+
+func TestArgCumulativeCustomValue(t *testing.T) {
+	a := newArgGroup()
+	ips := []net.IP{}
+	a.Arg("ips", "").Cumulative().SetValue(NewAccumulator(&ips, func(v interface{}) Value {
+		return newIPValue(v.(*net.IP))
+	}))
+	assert.NoError(t, a.init())
+	err := a.parse(Tokenize([]string{"10.0.0.1", "10.0.0.2"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}, ips)
+}
+
+func TestHiddenArg(t *testing.T) {
+	app := New("test", "")
+	app.Arg("file", "File.").Required().String()
+	debug := app.Arg("debug", "Internal.").Hidden().String()
+	_, err := app.Parse([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", *debug)
+
+	w := bytes.NewBuffer(nil)
+	app.Usage(w)
+	assert.Contains(t, w.String(), "usage: test <file>\n")
+	assert.Contains(t, w.String(), "<file>")
+	assert.NotContains(t, w.String(), "debug")
+	assert.NotContains(t, w.String(), "Internal.")
+}
+
+func TestArgValidate(t *testing.T) {
+	app := New("test", "")
+	seen := []string{}
+	app.Arg("ids", "").Validate(func(value string) error {
+		seen = append(seen, value)
+		if value == "bad" {
+			return fmt.Errorf("'%s' is not an identifier", value)
+		}
+		return nil
+	}).Strings()
+	_, err := app.Parse([]string{"a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, seen)
+	_, err = app.Parse([]string{"a", "bad"})
+	assert.EqualError(t, err, "invalid argument <ids>: 'bad' is not an identifier")
+}
+
+func TestArgOccurrences(t *testing.T) {
+	app := New("test", "")
+	app.Arg("targets", "").MinOccurrences(1).MaxOccurrences(3).Strings()
+	_, err := app.Parse([]string{"a", "b"})
+	assert.NoError(t, err)
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "<targets> requires at least 1 value(s)")
+	_, err = app.Parse([]string{"a", "b", "c", "d"})
+	assert.EqualError(t, err, "<targets> accepts at most 3 value(s)")
+
+	app = New("test", "")
+	app.Arg("target", "").MaxOccurrences(1).String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "occurrence limits on non-cumulative arg 'target'")
+}
+
+func TestArgRequiredIf(t *testing.T) {
+	app := New("test", "")
+	mode := app.Arg("mode", "").String()
+	app.Arg("target", "").RequiredIf(func(*ParseContext) bool { return *mode == "copy" }).String()
+	_, err := app.Parse([]string{"list"})
+	assert.NoError(t, err)
+	_, err = app.Parse([]string{"copy"})
+	assert.EqualError(t, err, "'target' is required")
+	_, err = app.Parse([]string{"copy", "dst"})
+	assert.NoError(t, err)
+}
+
+func TestArgMultipleDefaults(t *testing.T) {
+	app := New("test", "")
+	files := app.Arg("files", "").Default("a", "b").Strings()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, *files)
+	_, err = app.Parse([]string{"c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, *files)
+
+	app = New("test", "")
+	app.Arg("file", "").Default("a", "b").String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "multiple default values for non-cumulative arg 'file'")
+}
+
+func TestArgDefaultFunc(t *testing.T) {
+	app := New("test", "")
+	branch := app.Arg("branch", "").DefaultFunc(func() (string, error) { return "master", nil }).String()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "master", *branch)
+	_, err = app.Parse([]string{"dev"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", *branch)
+}
@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package kingpin
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+)
+
+// BuildVersion adds a --version flag that displays the version of the main
+// module, and the VCS revision and commit time it was built from, as recorded
+// by the Go toolchain. Use Version() instead to provide the version manually.
+func (a *Application) BuildVersion() *Application {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return a.Version(a.Name + " (unknown version)")
+	}
+	return a.Version(formatBuildInfo(a.Name, info))
+}
+
+// formatBuildInfo renders build information as a multi-line version block.
+func formatBuildInfo(name string, info *debug.BuildInfo) string {
+	version := info.Main.Version
+	if version == "" {
+		version = "(devel)"
+	}
+	lines := []string{name + " " + version}
+	settings := map[string]string{}
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	if revision := settings["vcs.revision"]; revision != "" {
+		if settings["vcs.modified"] == "true" {
+			revision += " (modified)"
+		}
+		lines = append(lines, "revision: "+revision)
+	}
+	if time := settings["vcs.time"]; time != "" {
+		lines = append(lines, "date: "+time)
+	}
+	if info.GoVersion != "" {
+		lines = append(lines, fmt.Sprintf("go: %s", info.GoVersion))
+	}
+	return strings.Join(lines, "\n")
+}
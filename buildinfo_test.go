@@ -0,0 +1,36 @@
+//go:build go1.18
+// +build go1.18
+
+package kingpin
+
+import (
+	"bytes"
+	"errors"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		GoVersion: "go1.21.0",
+		Main:      debug.Module{Path: "example.com/tool", Version: "v1.2.3"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123abcd"},
+			{Key: "vcs.time", Value: "2023-01-02T03:04:05Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+	expected := "tool v1.2.3\nrevision: 0123abcd (modified)\ndate: 2023-01-02T03:04:05Z\ngo: go1.21.0"
+	assert.Equal(t, expected, formatBuildInfo("tool", info))
+	assert.Equal(t, "tool (devel)", formatBuildInfo("tool", &debug.BuildInfo{}))
+}
+
+func TestBuildVersion(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").Writer(w).BuildVersion()
+	_, err := app.ParseArgs([]string{"--version"})
+	assert.True(t, errors.Is(err, ErrVersion))
+	assert.NotEmpty(t, app.version)
+}
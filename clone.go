@@ -0,0 +1,163 @@
+package kingpin
+
+import "time"
+
+// Clone returns a deep copy of the application's definition, including all
+// flags, arguments, commands and settings, so that independent instances can
+// be created from a shared prototype, eg. one per request or per test.
+//
+// Built-in values are copied, with the values they currently hold, into new
+// storage. The targets returned when defining the prototype's flags and
+// arguments therefore do not reflect parses of the clone; read the values of
+// a clone from the ParseContext instead. Values provided by the application,
+// such as those passed to SetValue(), are shared with the clone.
+func (a *Application) Clone() *Application {
+	c := &Application{
+		initialized:     a.initialized,
+		Name:            a.Name,
+		Help:            a.Help,
+		validator:       a.validator,
+		valueValidators: append([]func(ValueLookup) error(nil), a.valueValidators...),
+		structSeparator: a.structSeparator,
+		collectErrors:   a.collectErrors,
+		separateBools:   a.separateBools,
+		messages:        a.messages,
+		exitCodes:       ExitCodes{},
+		terminate:       a.terminate,
+		onTerminate:     append(([]func(int))(nil), a.onTerminate...),
+		onParsed:        append(([]func(string, *ParseContext))(nil), a.onParsed...),
+		writer:          a.writer,
+		errorWriter:     a.errorWriter,
+		usageWriter:     a.usageWriter,
+		noExit:          a.noExit,
+		onWarning:       a.onWarning,
+		usageOnError:    a.usageOnError,
+		usePager:        a.usePager,
+		flagsBeforeCmd:  a.flagsBeforeCmd,
+		slashFlags:      a.slashFlags,
+		redefine:        a.redefine,
+		optionsEnvar:    a.optionsEnvar,
+		widthDetector:   a.widthDetector,
+		layout:          a.layout,
+		numberFormat:    a.numberFormat,
+		dotEnv:          append([]string(nil), a.dotEnv...),
+		usageTemplate:   a.usageTemplate,
+		onTemplateError: a.onTemplateError,
+		version:         a.version,
+		author:          a.author,
+		homepage:        a.homepage,
+		bugReports:      a.bugReports,
+		helpShort:       a.helpShort,
+		mixPositional:   a.mixPositional,
+	}
+	for category, code := range a.exitCodes {
+		c.exitCodes[category] = code
+	}
+	clones := map[interface{}]interface{}{}
+	c.flagGroup = a.flagGroup.clone(clones)
+	c.argGroup = a.argGroup.clone(clones)
+	c.cmdGroup = a.cmdGroup.clone(c, nil, clones)
+	relinkValues(clones)
+
+	// The built-in flags and commands may have been removed or replaced.
+	if flag, ok := clones[a.HelpFlag].(*FlagClause); ok {
+		c.HelpFlag = flag
+		c.HelpFlag.dispatch = c.onHelp
+	}
+	if flag, ok := clones[a.VersionFlag].(*FlagClause); ok {
+		c.VersionFlag = flag
+		c.VersionFlag.dispatch = c.onVersion
+	}
+	if cmd, ok := clones[a.helpCommand].(*CmdClause); ok {
+		c.helpCommand = cmd
+		c.helpCommand.dispatch = c.onHelp
+	}
+	return c
+}
+
+// relinkValues points cloned values that refer to the values of other flags,
+// such as times in a location given by another flag, at the cloned values.
+func relinkValues(clones map[interface{}]interface{}) {
+	locations := map[**time.Location]**time.Location{}
+	for original, clone := range clones {
+		from := clauseParser(original)
+		to := clauseParser(clone)
+		if from == nil || to == nil {
+			continue
+		}
+		if location, ok := from.value.(*locationValue); ok {
+			locations[location.l] = to.value.(*locationValue).l
+		}
+	}
+	for _, clone := range clones {
+		if mixin := clauseParser(clone); mixin != nil {
+			if t, ok := mixin.value.(*timeValue); ok && locations[t.location] != nil {
+				t.location = locations[t.location]
+			}
+		}
+	}
+}
+
+func (f *flagGroup) clone(clones map[interface{}]interface{}) *flagGroup {
+	out := newFlagGroup()
+	for _, flag := range f.flagOrder {
+		clone := *flag
+		clone.parserMixin = flag.parserMixin.clone()
+		if flag.alternates != nil {
+			clone.alternates = map[string]bool{}
+			for name, deprecated := range flag.alternates {
+				clone.alternates[name] = deprecated
+			}
+		}
+		out.AddFlag(&clone)
+		clones[flag] = &clone
+	}
+	out.index()
+	return out
+}
+
+func (a *argGroup) clone(clones map[interface{}]interface{}) *argGroup {
+	out := newArgGroup()
+	for _, arg := range a.args {
+		clone := *arg
+		clone.parserMixin = arg.parserMixin.clone()
+		out.args = append(out.args, &clone)
+		clones[arg] = &clone
+	}
+	return out
+}
+
+func (c *cmdGroup) clone(app *Application, parent *CmdClause, clones map[interface{}]interface{}) *cmdGroup {
+	out := newCmdGroup(app)
+	for _, cmd := range c.commandOrder {
+		clone := &CmdClause{
+			app:       app,
+			name:      cmd.name,
+			help:      cmd.help,
+			dispatch:  cmd.dispatch,
+			validator: cmd.validator,
+		}
+		clone.flagGroup = cmd.flagGroup.clone(clones)
+		clone.argGroup = cmd.argGroup.clone(clones)
+		clone.cmdGroup = cmd.cmdGroup.clone(app, clone, clones)
+		clone.cmdGroup.parent = parent
+		if flag, ok := clones[cmd.helpFlag].(*FlagClause); ok {
+			clone.helpFlag = flag
+			clone.helpFlag.dispatch = clone.onHelp
+		}
+		clone.confirm = cmd.confirm
+		if flag, ok := clones[cmd.yesFlag].(*FlagClause); ok {
+			clone.yesFlag = flag
+		}
+		out.commands[clone.name] = clone
+		out.commandOrder = append(out.commandOrder, clone)
+		clones[cmd] = clone
+	}
+	return out
+}
+
+func (p parserMixin) clone() parserMixin {
+	p.value = cloneValue(p.value)
+	p.hintActions = append([]HintAction(nil), p.hintActions...)
+	return p
+}
@@ -0,0 +1,69 @@
+package kingpin
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClone(t *testing.T) {
+	app := New("test", "").Version("1.0").NoExit()
+	name := app.Flag("name", "").Default("alec").String()
+	post := app.Command("post", "")
+	channel := post.Flag("channel", "").Short('c').String()
+	post.Arg("text", "").Strings()
+
+	clone := app.Clone()
+	context, err := clone.ParseContext([]string{"--name=bob", "post", "-c", "pics", "hello", "world"})
+	assert.NoError(t, err)
+	assert.Equal(t, "post", context.SelectedCommand.FullCommand())
+	assert.Equal(t, map[string]string{
+		"help":         "false",
+		"version":      "false",
+		"name":         "bob",
+		"post.help":    "false",
+		"post.channel": "pics",
+		"post.text":    "hello,world",
+	}, context.Values())
+
+	// The prototype is unaffected.
+	assert.Equal(t, "", *name)
+	assert.Equal(t, "", *channel)
+	assert.NotEqual(t, app.cmdGroup.commands["post"], clone.cmdGroup.commands["post"])
+
+	// Built-in flags and commands act on the clone.
+	w := &bytes.Buffer{}
+	clone.Writer(w).UsageWriter(w)
+	_, err = clone.Parse([]string{"--version"})
+	assert.Equal(t, ErrVersion, err.(*categorizedError).err)
+	_, err = clone.Parse([]string{"post", "--help"})
+	assert.Equal(t, ErrHelp, err.(*categorizedError).err)
+	assert.Contains(t, w.String(), "1.0\n")
+	assert.Contains(t, w.String(), "usage: test [<flags>] post")
+}
+
+func TestCloneSubCommandParents(t *testing.T) {
+	app := New("test", "")
+	app.Command("a", "").Command("b", "").Command("c", "")
+	clone := app.Clone()
+	c := clone.findCommand("a b c")
+	if assert.NotNil(t, c) {
+		assert.Equal(t, "a b c", c.FullCommand())
+		assert.Equal(t, clone, c.app)
+	}
+}
+
+func TestCloneTimeIn(t *testing.T) {
+	app := New("test", "")
+	tz := app.Flag("tz", "").Default("UTC").Location()
+	app.Flag("when", "").TimeIn("2006-01-02 15:04", tz)
+
+	clone := app.Clone()
+	context, err := clone.ParseContext([]string{"--tz=Asia/Tokyo", "--when=2020-01-02 03:04"})
+	assert.NoError(t, err)
+	when := context.TypedValues()["when"].(time.Time)
+	assert.Equal(t, "Asia/Tokyo", when.Location().String())
+	assert.Equal(t, "2020-01-01T18:04:00Z", when.UTC().Format(time.RFC3339))
+}
@@ -2,7 +2,6 @@ package kingpin
 
 import (
 	"fmt"
-	"os"
 	"strings"
 )
 
@@ -37,6 +36,57 @@ func (c *cmdGroup) addCommand(name, help string) *CmdClause {
 	return cmd
 }
 
+// GetCommand returns the command with the given path of names, eg.
+// GetCommand("remote", "add"), or nil, so that it may be configured further.
+func (c *cmdGroup) GetCommand(path ...string) *CmdClause {
+	var cmd *CmdClause
+	group := c
+	for _, name := range path {
+		next, ok := group.commands[name]
+		if !ok {
+			return nil
+		}
+		cmd = next
+		group = next.cmdGroup
+	}
+	return cmd
+}
+
+// RemoveCommand removes the command with the given name, returning it, or nil
+// if there is no such command.
+func (c *cmdGroup) RemoveCommand(name string) *CmdClause {
+	for i, cmd := range c.commandOrder {
+		if cmd.name != name {
+			continue
+		}
+		c.commandOrder = append(c.commandOrder[:i:i], c.commandOrder[i+1:]...)
+		if c.commands[name] == cmd {
+			delete(c.commands, name)
+		}
+		return cmd
+	}
+	return nil
+}
+
+// replaceRedefined replaces each command with the last command defined with
+// the same name, keeping the position of the first.
+func (c *cmdGroup) replaceRedefined() {
+	index := map[string]int{}
+	out := c.commandOrder[:0]
+	for _, cmd := range c.commandOrder {
+		if i, ok := index[cmd.name]; ok {
+			out[i] = cmd
+			continue
+		}
+		index[cmd.name] = len(out)
+		out = append(out, cmd)
+	}
+	c.commandOrder = out
+	for _, cmd := range out {
+		c.commands[cmd.name] = cmd
+	}
+}
+
 func (c *cmdGroup) init() error {
 	seen := map[string]bool{}
 	for _, cmd := range c.commandOrder {
@@ -51,21 +101,41 @@ func (c *cmdGroup) init() error {
 	return nil
 }
 
+// check returns all problems with the definitions of the commands and
+// everything they contain.
+func (c *cmdGroup) check() (errs []error) {
+	seen := map[string]bool{}
+	for _, cmd := range c.commandOrder {
+		if seen[cmd.name] {
+			errs = append(errs, fmt.Errorf("duplicate command '%s'", cmd.name))
+		}
+		seen[cmd.name] = true
+		errs = append(errs, cmd.check()...)
+	}
+	return
+}
+
 func (c *cmdGroup) parse(context *ParseContext) (selected []string, _ error) {
 	token := context.Peek()
 	if token.Type == TokenEOL {
 		return nil, nil
 	}
 	if token.Type != TokenArg {
-		return nil, fmt.Errorf("expected command but got '%s'", token)
+		return nil, fmt.Errorf(context.msg().ExpectedCommand, token)
 	}
 	cmd, ok := c.commands[token.String()]
 	if !ok {
-		return nil, fmt.Errorf("no such command '%s'", token)
+		if context.stopPartial() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(context.msg().NoSuchCommand, token)
 	}
 	context.Next()
-	context.SelectedCommand = cmd.name
+	context.restoreDeferred()
+	context.SelectedCommand = cmd
+	context.commandPath = append(context.commandPath, cmd)
 	selected, err := cmd.parse(context)
+	err = context.annotate(err)
 	if err == nil {
 		selected = append([]string{token.String()}, selected...)
 	}
@@ -76,6 +146,53 @@ func (c *cmdGroup) have() bool {
 	return len(c.commands) > 0
 }
 
+// findFlag returns a flag with the given long or short name defined by any of
+// the commands or their sub-commands, or nil.
+func (c *cmdGroup) findFlag(name string, short bool) *FlagClause {
+	for _, cmd := range c.commandOrder {
+		for _, flag := range cmd.flagOrder {
+			if (short && flag.shorthand != 0 && string(flag.shorthand) == name) || (!short && flag.hasName(name)) {
+				return flag
+			}
+		}
+		if flag := cmd.cmdGroup.findFlag(name, short); flag != nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// selects returns true if the next token names one of the commands.
+func (c *cmdGroup) selects(context *ParseContext) bool {
+	token := context.Peek()
+	_, ok := c.commands[token.Value]
+	return token.Type == TokenArg && ok
+}
+
+// parsePositional parses either a command or the positional arguments. Where
+// both are defined, a command is only selected if the next token names one.
+func parsePositional(context *ParseContext, args *argGroup, commands *cmdGroup) ([]string, error) {
+	if commands.have() && (!args.have() || commands.selects(context)) {
+		return commands.parse(context)
+	}
+	if args.have() {
+		if err := args.parse(context); err != nil {
+			return nil, err
+		}
+	}
+	// This is the last group of values to be parsed.
+	if err := context.finishPersistentFlags(); err != nil {
+		return nil, err
+	}
+	if err := context.applyDeferredDefaults(); err != nil {
+		return nil, err
+	}
+	if err := context.resolveValues(); err != nil {
+		return nil, err
+	}
+	return nil, commands.app.validateValues(context)
+}
+
 type CmdClauseValidator func(*CmdClause) error
 
 // A CmdClause is a single top-level command. It encapsulates a set of flags
@@ -89,6 +206,9 @@ type CmdClause struct {
 	help      string
 	dispatch  Dispatch
 	validator CmdClauseValidator
+	helpFlag  *FlagClause
+	confirm   string
+	yesFlag   *FlagClause
 }
 
 func newCommand(app *Application, name, help string) *CmdClause {
@@ -100,7 +220,8 @@ func newCommand(app *Application, name, help string) *CmdClause {
 		name:      name,
 		help:      help,
 	}
-	c.Flag("help", "Show help on this command.").Hidden().Dispatch(c.onHelp).Bool()
+	c.helpFlag = c.Flag("help", "Show help on this command.").Hidden().Dispatch(c.onHelp)
+	c.helpFlag.Bool()
 	return c
 }
 
@@ -110,6 +231,18 @@ func (c *CmdClause) Validate(validator CmdClauseValidator) *CmdClause {
 	return c
 }
 
+// Name returns the name of the command.
+func (c *CmdClause) Name() string {
+	return c.name
+}
+
+// Parent returns the parent of a sub-command, or nil for a top-level command.
+func (c *CmdClause) Parent() *CmdClause {
+	return c.parent
+}
+
+// FullCommand returns the space separated path to this command, including
+// its parents.
 func (c *CmdClause) FullCommand() string {
 	out := []string{c.name}
 	for p := c.parent; p != nil; p = p.parent {
@@ -119,9 +252,65 @@ func (c *CmdClause) FullCommand() string {
 }
 
 func (c *CmdClause) onHelp(context *ParseContext) error {
-	c.app.CommandUsage(os.Stderr, c.FullCommand())
-	os.Exit(0)
-	return nil
+	if context.pure {
+		return categorize(HelpRequested, ErrHelp)
+	}
+	c.app.showHelp(c)
+	return c.app.finish(ErrHelp)
+}
+
+// PersistentFlag defines a flag that is also available to all of the
+// command's sub-commands, after the sub-command on the command line.
+// Sub-commands may not define flags with the same names, unless they use
+// FlagClause.Override().
+func (c *CmdClause) PersistentFlag(name, help string) *FlagClause {
+	flag := c.Flag(name, help)
+	flag.persistent = true
+	return flag
+}
+
+// inheritedFlags returns the persistent flags of the command's parents,
+// outermost first, excluding those overridden by the command or a closer
+// parent.
+func (c *CmdClause) inheritedFlags() (out []*FlagClause) {
+	overridden := map[string]bool{}
+	for _, flag := range c.flagOrder {
+		if flag.override {
+			overridden[flag.name] = true
+		}
+	}
+	for parent := c.parent; parent != nil; parent = parent.parent {
+		flags := []*FlagClause{}
+		for _, flag := range parent.flagOrder {
+			if flag.persistent && !overridden[flag.name] {
+				flags = append(flags, flag)
+			}
+		}
+		for _, flag := range parent.flagOrder {
+			if flag.persistent {
+				overridden[flag.name] = true
+			}
+		}
+		out = append(flags, out...)
+	}
+	return
+}
+
+// checkInherited returns conflicts between the command's flags and the
+// persistent flags of its parents.
+func (c *CmdClause) checkInherited() (errs []error) {
+	inherited := c.inheritedFlags()
+	for _, flag := range c.flagOrder {
+		if flag.override {
+			continue
+		}
+		for _, parent := range inherited {
+			if flag.name == parent.name || (flag.shorthand != 0 && flag.shorthand == parent.shorthand) {
+				errs = append(errs, fmt.Errorf("flag --%s of command '%s' conflicts with persistent flag --%s", flag.name, c.FullCommand(), parent.name))
+			}
+		}
+	}
+	return
 }
 
 // Command adds a new sub-command.
@@ -137,10 +326,15 @@ func (c *CmdClause) Dispatch(dispatch Dispatch) *CmdClause {
 }
 
 func (c *CmdClause) init() error {
+	c.replaceRedefined()
+	c.app.assignHelpShort(c.helpFlag, c.flagOrder, c.inheritedFlags())
 	if err := c.flagGroup.init(); err != nil {
 		return err
 	}
-	if c.argGroup.have() && c.cmdGroup.have() {
+	if errs := c.checkInherited(); len(errs) > 0 {
+		return errs[0]
+	}
+	if c.argGroup.have() && c.cmdGroup.have() && !c.app.mixPositional {
 		return fmt.Errorf("can't mix Arg()s with Command()s")
 	}
 	if err := c.argGroup.init(); err != nil {
@@ -152,23 +346,55 @@ func (c *CmdClause) init() error {
 	return nil
 }
 
+func (c *CmdClause) check() (errs []error) {
+	c.replaceRedefined()
+	errs = append(errs, c.flagGroup.check()...)
+	errs = append(errs, c.checkInherited()...)
+	if c.argGroup.have() && c.cmdGroup.have() && !c.app.mixPositional {
+		errs = append(errs, fmt.Errorf("can't mix Arg()s with Command()s"))
+	}
+	errs = append(errs, c.argGroup.check()...)
+	errs = append(errs, c.cmdGroup.check()...)
+	return
+}
+
+// replaceRedefined applies AllowRedefinition() to the definitions of the
+// command.
+func (c *CmdClause) replaceRedefined() {
+	if c.app.redefine {
+		c.flagGroup.replaceRedefined()
+		c.argGroup.replaceRedefined()
+		c.cmdGroup.replaceRedefined()
+	}
+}
+
 func (c *CmdClause) parse(context *ParseContext) (selected []string, _ error) {
+	context.lenient(c.app, c.cmdGroup)
 	err := c.flagGroup.parse(context, false)
 	if err != nil {
 		return nil, err
 	}
-	if context.SelectedCommand != "help" {
-		if c.cmdGroup.have() {
-			selected, err = c.cmdGroup.parse(context)
-		} else if c.argGroup.have() {
-			err = c.argGroup.parse(context)
+	context.recordValues(strings.Replace(c.FullCommand(), " ", ".", -1)+".", c.flagGroup, c.argGroup)
+	confirmed := false
+	if context.SelectedCommand.name != "help" {
+		// Sub-commands are dispatched as they are parsed, so the command
+		// must be confirmed before descending into one.
+		if c.cmdGroup.have() && (!c.argGroup.have() || c.cmdGroup.selects(context)) {
+			if err := c.confirmed(context); err != nil {
+				return nil, err
+			}
+			confirmed = true
 		}
+		selected, err = parsePositional(context, c.argGroup, c.cmdGroup)
+	}
+	if err == nil && !confirmed {
+		err = c.confirmed(context)
 	}
 	if err == nil && c.dispatch != nil {
-		err = c.dispatch(context)
+		err = categorize(RuntimeError, context.dispatch(c.dispatch))
 	}
-	if c.validator != nil {
-		err = c.validator(c)
+	if err == nil && c.validator != nil {
+		err = categorize(ValidationError, c.validator(c))
 	}
 	return selected, err
 }
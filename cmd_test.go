@@ -1,6 +1,8 @@
 package kingpin
 
 import (
+	"bytes"
+
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -53,3 +55,181 @@ func TestNestedCommandsWithFlags(t *testing.T) {
 	assert.Equal(t, "x", *a)
 	assert.Equal(t, "x", *b)
 }
+
+func TestCommandErrorsAnnotatedWithCommand(t *testing.T) {
+	app := New("app", "")
+	post := app.Command("post", "").Command("message", "")
+	post.Flag("channel", "").String()
+	_, err := app.Parse([]string{"post", "message", "--chanel=x"})
+	assert.EqualError(t, err, "post message: unknown long flag '--chanel'")
+	cmdErr, ok := err.(*CommandError)
+	assert.True(t, ok)
+	assert.Equal(t, post, cmdErr.Command)
+
+	_, err = app.Parse([]string{"--chanel=x"})
+	assert.EqualError(t, err, "unknown long flag '--chanel'")
+}
+
+func TestAllowArgsAndCommands(t *testing.T) {
+	app := New("tool", "").AllowArgsAndCommands()
+	file := app.Arg("file", "").String()
+	serve := app.Command("serve", "")
+	port := serve.Flag("port", "").Int()
+
+	selected, err := app.Parse([]string{"input.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, "", selected)
+	assert.Equal(t, "input.txt", *file)
+
+	selected, err = app.Parse([]string{"serve", "--port=80"})
+	assert.NoError(t, err)
+	assert.Equal(t, "serve", selected)
+	assert.Equal(t, "", *file)
+	assert.Equal(t, 80, *port)
+
+	app = New("tool", "")
+	app.Arg("file", "").String()
+	app.Command("serve", "")
+	_, err = app.Parse([]string{"serve"})
+	assert.Error(t, err)
+}
+
+func TestPersistentFlags(t *testing.T) {
+	app := New("tool", "")
+	remote := app.Command("remote", "")
+	name := remote.PersistentFlag("name", "Remote name.").Short('n').Default("origin").String()
+	token := remote.PersistentFlag("token", "").Required().String()
+	add := remote.Command("add", "")
+	url := add.Arg("url", "").String()
+	remote.Command("list", "")
+
+	_, err := app.Parse([]string{"remote", "add", "--token=t", "-n", "upstream", "http://example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream", *name)
+	assert.Equal(t, "t", *token)
+	assert.Equal(t, "http://example.com", *url)
+
+	_, err = app.Parse([]string{"remote", "--name=fork", "--token=t", "list"})
+	assert.NoError(t, err)
+	assert.Equal(t, "fork", *name)
+
+	_, err = app.Parse([]string{"remote", "list", "--token=t"})
+	assert.NoError(t, err)
+	assert.Equal(t, "origin", *name)
+
+	_, err = app.Parse([]string{"remote", "list"})
+	assert.EqualError(t, err, "remote list: required flag --token not provided")
+}
+
+func TestPersistentFlagConflicts(t *testing.T) {
+	app := New("tool", "")
+	remote := app.Command("remote", "")
+	remote.PersistentFlag("name", "").Short('n').String()
+	remote.Command("add", "").Flag("number", "").Short('n').Int()
+	_, err := app.Parse([]string{"remote", "add"})
+	assert.EqualError(t, err, "flag --number of command 'remote add' conflicts with persistent flag --name")
+}
+
+func TestPersistentFlagsHelp(t *testing.T) {
+	app := New("tool", "")
+	remote := app.Command("remote", "")
+	remote.PersistentFlag("verbose", "Verbose output.").Bool()
+	add := remote.Command("add", "")
+	add.Flag("fetch", "Fetch.").Bool()
+	assert.NoError(t, app.init())
+
+	w := bytes.NewBuffer(nil)
+	app.commandUsage(w, remote, 80)
+	assert.Contains(t, w.String(), "Flags:\n  --verbose  Verbose output.\n")
+	assert.NotContains(t, w.String(), "Global flags:")
+	w.Reset()
+	app.commandUsage(w, add, 80)
+	assert.Contains(t, w.String(), "Flags:\n  --fetch  Fetch.\n\nGlobal flags:\n  --verbose  Verbose output.\n")
+}
+
+func TestOverridePersistentFlag(t *testing.T) {
+	app := New("tool", "")
+	remote := app.Command("remote", "")
+	verbose := remote.PersistentFlag("verbose", "Verbose.").Short('v').Bool()
+	remote.PersistentFlag("dry-run", "Dry run.").Bool()
+	add := remote.Command("add", "")
+	level := add.Flag("verbose", "Verbosity level.").Short('v').Override().Int()
+	list := remote.Command("list", "")
+	pattern := list.PersistentFlag("dry-run", "Pattern.").Override().String()
+	list.Command("all", "")
+
+	_, err := app.Parse([]string{"remote", "add", "-v", "3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, *level)
+	assert.False(t, *verbose)
+
+	_, err = app.Parse([]string{"remote", "list", "all", "--dry-run=x", "-v"})
+	assert.NoError(t, err)
+	assert.Equal(t, "x", *pattern)
+	assert.True(t, *verbose)
+
+	assert.NoError(t, app.init())
+	w := bytes.NewBuffer(nil)
+	app.commandUsage(w, add, 80)
+	assert.Contains(t, w.String(), "Verbosity level.")
+	assert.NotContains(t, w.String(), "Verbose.")
+	assert.Contains(t, w.String(), "Dry run.")
+	w.Reset()
+	app.commandUsage(w, list.commands["all"], 80)
+	assert.Contains(t, w.String(), "Pattern.")
+	assert.NotContains(t, w.String(), "Dry run.")
+}
+
+func TestSiblingCommandsShareShortFlags(t *testing.T) {
+	app := New("tool", "")
+	push := app.Command("push", "")
+	force := push.Flag("force", "").Short('f').Bool()
+	fetch := app.Command("fetch", "")
+	file := fetch.Flag("file", "").Short('f').String()
+	assert.Empty(t, app.Check())
+
+	_, err := app.Parse([]string{"push", "-f"})
+	assert.NoError(t, err)
+	assert.True(t, *force)
+	_, err = app.Parse([]string{"fetch", "-f", "refs"})
+	assert.NoError(t, err)
+	assert.Equal(t, "refs", *file)
+}
+
+func TestAllowFlagsBeforeCommand(t *testing.T) {
+	app := New("chat", "").AllowFlagsBeforeCommand()
+	debug := app.Flag("debug", "").Bool()
+	post := app.Command("post", "")
+	channel := post.Flag("channel", "").Short('c').String()
+	image := post.Flag("image", "").Bool()
+	text := post.Arg("text", "").String()
+	app.Command("list", "").Command("all", "").Flag("limit", "").Int()
+
+	selected, err := app.Parse([]string{"--channel", "general", "--debug", "--image", "post", "hello"})
+	assert.NoError(t, err)
+	assert.Equal(t, "post", selected)
+	assert.Equal(t, "general", *channel)
+	assert.True(t, *debug)
+	assert.True(t, *image)
+	assert.Equal(t, "hello", *text)
+
+	_, err = app.Parse([]string{"-c", "random", "post", "--image"})
+	assert.NoError(t, err)
+	assert.Equal(t, "random", *channel)
+
+	selected, err = app.Parse([]string{"--limit=3", "list", "all"})
+	assert.NoError(t, err)
+	assert.Equal(t, "list all", selected)
+
+	_, err = app.Parse([]string{"--limit=3", "post"})
+	assert.EqualError(t, err, "post: unknown long flag '--limit'")
+	_, err = app.Parse([]string{"--channel=general"})
+	assert.EqualError(t, err, "unknown long flag '--channel'")
+	_, err = app.Parse([]string{"--missing", "post"})
+	assert.EqualError(t, err, "unknown long flag '--missing'")
+
+	app = New("chat", "")
+	app.Command("post", "").Flag("channel", "").String()
+	_, err = app.Parse([]string{"--channel=general", "post"})
+	assert.EqualError(t, err, "unknown long flag '--channel'")
+}
@@ -0,0 +1,70 @@
+// Package cobra constructs a Kingpin application from an existing
+// spf13/cobra command tree, to ease incremental migration of large CLIs.
+//
+// Flags share their backing values with the cobra flags, and each command's
+// Run or RunE function is dispatched when the command is selected. Leaf
+// commands accept their positional arguments as a list, which is passed
+// through to Run.
+//
+// Persistent flags of the root command become application flags. Persistent
+// flags defined on sub-commands apply only to the command defining them.
+package cobra
+
+import (
+	"github.com/alecthomas/kingpin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// FromCommand creates a new Kingpin application from a cobra command tree.
+func FromCommand(root *cobra.Command) *kingpin.Application {
+	app := kingpin.New(root.Name(), help(root))
+	importFlags(app, root.LocalFlags())
+	for _, child := range root.Commands() {
+		addCommand(app.Command(child.Name(), help(child)), child)
+	}
+	return app
+}
+
+func addCommand(clause *kingpin.CmdClause, cmd *cobra.Command) {
+	importFlags(clause, cmd.LocalFlags())
+	children := cmd.Commands()
+	for _, child := range children {
+		addCommand(clause.Command(child.Name(), help(child)), child)
+	}
+	if len(children) > 0 {
+		return
+	}
+	args := clause.Arg("args", "Arguments.").Strings()
+	clause.Dispatch(func(*kingpin.ParseContext) error {
+		switch {
+		case cmd.RunE != nil:
+			return cmd.RunE(cmd, *args)
+		case cmd.Run != nil:
+			cmd.Run(cmd, *args)
+		}
+		return nil
+	})
+}
+
+type flagImporter interface {
+	ImportPFlagSet(fs *pflag.FlagSet)
+}
+
+func importFlags(target flagImporter, flags *pflag.FlagSet) {
+	// Kingpin provides its own help flag.
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Name != "help" {
+			fs.AddFlag(f)
+		}
+	})
+	target.ImportPFlagSet(fs)
+}
+
+func help(cmd *cobra.Command) string {
+	if cmd.Short != "" {
+		return cmd.Short
+	}
+	return cmd.Long
+}
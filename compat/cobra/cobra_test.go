@@ -0,0 +1,37 @@
+package cobra
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromCommand(t *testing.T) {
+	var (
+		ran     []string
+		verbose bool
+		force   bool
+	)
+	root := &cobra.Command{Use: "tool", Short: "A tool."}
+	root.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose.")
+	remote := &cobra.Command{Use: "remote", Short: "Manage remotes."}
+	remove := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove a remote.",
+		Run: func(cmd *cobra.Command, args []string) {
+			ran = args
+		},
+	}
+	remove.Flags().BoolVarP(&force, "force", "f", false, "Force.")
+	remote.AddCommand(remove)
+	root.AddCommand(remote)
+
+	app := FromCommand(root)
+	selected, err := app.Parse([]string{"-v", "remote", "remove", "-f", "origin"})
+	assert.NoError(t, err)
+	assert.Equal(t, "remote remove", selected)
+	assert.True(t, verbose)
+	assert.True(t, force)
+	assert.Equal(t, []string{"origin"}, ran)
+}
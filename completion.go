@@ -0,0 +1,142 @@
+package kingpin
+
+import "strings"
+
+// HintAction provides completion candidates for a flag or argument value.
+type HintAction func() []string
+
+// Complete returns the completion candidates for the word at index cursorWord
+// of args, as a shell would request them. cursorWord may be len(args), to
+// complete a new, empty word. Candidates are flag names when the word starts
+// with "-", otherwise command names or the hints for the flag value or
+// positional argument at the cursor.
+//
+// No flags or arguments are parsed or dispatched, so this can be used to test
+// the completions an application provides.
+func (a *Application) Complete(args []string, cursorWord int) []string {
+	a.parseLock.Lock()
+	defer a.parseLock.Unlock()
+	if err := a.init(); err != nil {
+		return nil
+	}
+	if cursorWord > len(args) {
+		cursorWord = len(args)
+	}
+	word := ""
+	if cursorWord < len(args) {
+		word = args[cursorWord]
+	}
+
+	flags := []*flagGroup{a.flagGroup}
+	positional := a.argGroup
+	commands := a.cmdGroup
+	argIndex := 0
+	allowFlags := true
+	var expectValue *FlagClause
+	for _, arg := range args[:cursorWord] {
+		switch {
+		case expectValue != nil:
+			expectValue = nil
+
+		case allowFlags && arg == "--":
+			allowFlags = false
+
+		case allowFlags && strings.HasPrefix(arg, "--"):
+			if !strings.Contains(arg, "=") {
+				expectValue = completionFlag(flags, arg[2:], true)
+			}
+
+		case allowFlags && len(arg) == 2 && arg[0] == '-':
+			expectValue = completionFlag(flags, arg[1:], false)
+
+		default:
+			if cmd, ok := commands.commands[arg]; ok && (!positional.have() || a.mixPositional) {
+				flags = append(flags, cmd.flagGroup)
+				positional = cmd.argGroup
+				commands = cmd.cmdGroup
+				argIndex = 0
+			} else if argIndex < len(positional.args) && !positional.args[argIndex].consumesRemainder() {
+				argIndex++
+			}
+		}
+	}
+
+	var candidates []string
+	switch {
+	case expectValue != nil:
+		candidates = valueHints(&expectValue.parserMixin)
+
+	case allowFlags && strings.HasPrefix(word, "-"):
+		for _, group := range flags {
+			for _, flag := range group.flagOrder {
+				if !flag.hidden {
+					candidates = append(candidates, "--"+flag.name)
+				}
+			}
+		}
+
+	case commands.have():
+		for _, cmd := range commands.commandOrder {
+			candidates = append(candidates, cmd.name)
+		}
+
+	case argIndex < len(positional.args):
+		candidates = valueHints(&positional.args[argIndex].parserMixin)
+	}
+
+	out := []string{}
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			out = append(out, candidate)
+		}
+	}
+	return out
+}
+
+// completionFlag returns the flag named by a flag word if it requires a value,
+// searching the innermost command first.
+func completionFlag(groups []*flagGroup, name string, long bool) *FlagClause {
+	for i := len(groups) - 1; i >= 0; i-- {
+		var flag *FlagClause
+		if long {
+			flag = groups[i].long[name]
+		} else {
+			flag = groups[i].short[name]
+		}
+		if flag == nil {
+			continue
+		}
+		if fb, ok := flag.value.(boolFlag); (ok && fb.IsBoolFlag()) || flag.optionalValue {
+			return nil
+		}
+		return flag
+	}
+	return nil
+}
+
+// valueHints returns the completion candidates for a value, from its hint
+// actions or, failing that, the values given to OneOf() or the options of an
+// enum.
+func valueHints(p *parserMixin) []string {
+	if len(p.hintActions) > 0 {
+		hints := []string{}
+		for _, action := range p.hintActions {
+			hints = append(hints, action()...)
+		}
+		return hints
+	}
+	if len(p.oneOf) > 0 {
+		return p.oneOf
+	}
+	value := p.value
+	if c, ok := value.(*cumulativeValue); ok {
+		value = c.Value
+	}
+	switch value := value.(type) {
+	case *enumValue:
+		return value.options
+	case *enumsValue:
+		return value.options
+	}
+	return nil
+}
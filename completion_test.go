@@ -0,0 +1,32 @@
+package kingpin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete(t *testing.T) {
+	app := New("test", "")
+	app.Flag("debug", "").Bool()
+	app.Flag("level", "").Enum("debug", "info", "warn")
+	app.Flag("secret", "").Hidden().Bool()
+	remote := app.Command("remote", "")
+	remove := remote.Command("remove", "")
+	remove.Flag("force", "").Short('f').Bool()
+	remove.Flag("host", "").Short('H').HintOptions("alpha", "beta").String()
+	remove.Arg("name", "").HintAction(func() []string { return []string{"origin", "upstream"} }).String()
+	remote.Command("add", "")
+
+	assert.Equal(t, []string{"help", "remote"}, app.Complete([]string{}, 0))
+	assert.Equal(t, []string{"remote"}, app.Complete([]string{"re"}, 0))
+	assert.Equal(t, []string{"remove", "add"}, app.Complete([]string{"remote"}, 1))
+	assert.Equal(t, []string{"--help", "--debug", "--level"}, app.Complete([]string{"-"}, 0))
+	assert.Equal(t, []string{"--debug"}, app.Complete([]string{"--de"}, 0))
+	assert.Equal(t, []string{"debug", "info", "warn"}, app.Complete([]string{"--level"}, 1))
+	assert.Equal(t, []string{"origin", "upstream"}, app.Complete([]string{"remote", "remove", "-f"}, 3))
+	assert.Equal(t, []string{"upstream"}, app.Complete([]string{"remote", "remove", "--host", "alpha", "u"}, 4))
+	assert.Equal(t, []string{"beta"}, app.Complete([]string{"--debug", "remote", "remove", "-H", "b"}, 4))
+	assert.Equal(t, []string{"--help", "--host"}, app.Complete([]string{"remote", "remove", "--f", "--h"}, 3))
+	assert.Equal(t, []string{}, app.Complete([]string{"remote", "remove", "origin"}, 3))
+}
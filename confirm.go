@@ -0,0 +1,68 @@
+package kingpin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Confirm asks the user to confirm the command before it is dispatched, eg.
+// Confirm("really delete %s?"). The prompt is formatted with the values of
+// the command's arguments, in order. A --yes flag is added to the command to
+// skip the confirmation, eg. in scripts.
+//
+// The question is asked on the controlling terminal, so that piped input is
+// left for the command. If the user does not answer "y" or "yes", or there is
+// no terminal, parsing fails and neither the command nor its sub-commands are
+// dispatched. ParseArgs() never prompts, so fails unless --yes is given.
+func (c *CmdClause) Confirm(prompt string) *CmdClause {
+	c.confirm = prompt
+	if c.yesFlag == nil {
+		c.yesFlag = c.Flag("yes", "Don't ask for confirmation.")
+		c.yesFlag.Bool()
+	}
+	return c
+}
+
+// confirmed asks the user to confirm the command, if required.
+func (c *CmdClause) confirmed(context *ParseContext) error {
+	if c.confirm == "" || (c.yesFlag != nil && c.yesFlag.value.String() == "true") {
+		return nil
+	}
+	if !context.pure {
+		verbs := strings.Count(c.confirm, "%") - 2*strings.Count(c.confirm, "%%")
+		values := []interface{}{}
+		for _, arg := range c.args {
+			if len(values) < verbs {
+				values = append(values, arg.value.String())
+			}
+		}
+		answer, err := promptConfirm(fmt.Sprintf(c.app.messages.ConfirmPrompt, fmt.Sprintf(c.confirm, values...)))
+		if err == nil {
+			switch strings.ToLower(strings.TrimSpace(answer)) {
+			case "y", "yes":
+				return nil
+			}
+		}
+	}
+	return categorize(RuntimeError, errors.New(context.msg().NotConfirmed))
+}
+
+// promptConfirm displays prompt on the controlling terminal and reads a line.
+// It is a variable so that tests can replace it.
+var promptConfirm = func(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return line, nil
+}
@@ -0,0 +1,95 @@
+package kingpin
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withStdin(t *testing.T, input string) {
+	f, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	f.WriteString(input)
+	f.Seek(0, 0)
+	original := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = original
+		f.Close()
+		os.Remove(f.Name())
+	})
+}
+
+func withConfirmPrompt(t *testing.T, answer string, err error) *[]string {
+	prompts := []string{}
+	original := promptConfirm
+	promptConfirm = func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return answer, err
+	}
+	t.Cleanup(func() { promptConfirm = original })
+	return &prompts
+}
+
+func TestConfirm(t *testing.T) {
+	deleted := ""
+	app := New("test", "")
+	remove := app.Command("delete", "").Confirm("really delete %s?").Dispatch(func(context *ParseContext) error {
+		deleted = context.StringValue("delete.name")
+		return nil
+	})
+	remove.Arg("name", "").Required().String()
+
+	prompts := withConfirmPrompt(t, "y\n", nil)
+	_, err := app.Parse([]string{"delete", "prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", deleted)
+	assert.Equal(t, []string{"really delete prod? [y/N] "}, *prompts)
+
+	deleted = ""
+	withConfirmPrompt(t, "\n", nil)
+	_, err = app.Parse([]string{"delete", "prod"})
+	assert.EqualError(t, err, "aborted")
+	assert.Equal(t, RuntimeError, ErrorCategoryOf(err))
+	assert.Equal(t, "", deleted)
+
+	prompts = withConfirmPrompt(t, "y\n", nil)
+	_, err = app.Parse([]string{"delete", "--yes", "prod"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", deleted)
+	assert.Empty(t, *prompts)
+
+	_, err = app.ParseArgs([]string{"delete", "prod"})
+	assert.EqualError(t, err, "aborted")
+}
+
+func TestConfirmWithoutTerminal(t *testing.T) {
+	withConfirmPrompt(t, "", errors.New("no tty"))
+	withStdin(t, "yes\n")
+	app := New("test", "")
+	app.Command("delete", "").Confirm("really?")
+	_, err := app.Parse([]string{"delete"})
+	assert.EqualError(t, err, "aborted")
+
+	// Piped input is left for the command.
+	data, err := ioutil.ReadAll(os.Stdin)
+	assert.NoError(t, err)
+	assert.Equal(t, "yes\n", string(data))
+}
+
+func TestConfirmBeforeSubCommand(t *testing.T) {
+	withConfirmPrompt(t, "n\n", nil)
+	dispatched := false
+	app := New("test", "")
+	db := app.Command("db", "").Confirm("really?")
+	db.Command("drop", "").Dispatch(func(*ParseContext) error {
+		dispatched = true
+		return nil
+	})
+	_, err := app.Parse([]string{"db", "drop"})
+	assert.EqualError(t, err, "aborted")
+	assert.False(t, dispatched)
+}
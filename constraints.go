@@ -0,0 +1,305 @@
+package kingpin
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Min requires the value of a numeric or duration flag to be at least min,
+// eg. Min(1) or Min(time.Second).
+func (f *FlagClause) Min(min interface{}) *FlagClause {
+	f.min = min
+	return f
+}
+
+// Max requires the value of a numeric or duration flag to be at most max.
+func (f *FlagClause) Max(max interface{}) *FlagClause {
+	f.max = max
+	return f
+}
+
+// Range requires the value of a numeric or duration flag to be between min
+// and max, inclusive.
+func (f *FlagClause) Range(min, max interface{}) *FlagClause {
+	return f.Min(min).Max(max)
+}
+
+// Min requires the value of a numeric or duration argument to be at least
+// min.
+func (a *ArgClause) Min(min interface{}) *ArgClause {
+	a.min = min
+	return a
+}
+
+// Max requires the value of a numeric or duration argument to be at most
+// max.
+func (a *ArgClause) Max(max interface{}) *ArgClause {
+	a.max = max
+	return a
+}
+
+// Range requires the value of a numeric or duration argument to be between
+// min and max, inclusive.
+func (a *ArgClause) Range(min, max interface{}) *ArgClause {
+	return a.Min(min).Max(max)
+}
+
+// MinLen requires the flag's value to be at least n characters long.
+func (f *FlagClause) MinLen(n int) *FlagClause {
+	f.minLen = n
+	return f
+}
+
+// MaxLen requires the flag's value to be at most n characters long.
+func (f *FlagClause) MaxLen(n int) *FlagClause {
+	f.maxLen = n
+	return f
+}
+
+// Match requires the flag's value to match re. Anchor the expression with ^
+// and $ to match the whole value.
+func (f *FlagClause) Match(re *regexp.Regexp) *FlagClause {
+	f.pattern = re
+	return f
+}
+
+// MinLen requires the argument to be at least n characters long.
+func (a *ArgClause) MinLen(n int) *ArgClause {
+	a.minLen = n
+	return a
+}
+
+// MaxLen requires the argument to be at most n characters long.
+func (a *ArgClause) MaxLen(n int) *ArgClause {
+	a.maxLen = n
+	return a
+}
+
+// Match requires the argument to match re. Anchor the expression with ^ and
+// $ to match the whole argument.
+func (a *ArgClause) Match(re *regexp.Regexp) *ArgClause {
+	a.pattern = re
+	return a
+}
+
+// OneOf restricts the flag to the given values, which are also shown in help
+// and offered for completion. Unlike Enum(), it applies to any type of value.
+func (f *FlagClause) OneOf(values ...string) *FlagClause {
+	f.oneOf = values
+	return f
+}
+
+// OneOf restricts the argument to the given values, which are also shown in
+// help and offered for completion.
+func (a *ArgClause) OneOf(values ...string) *ArgClause {
+	a.oneOf = values
+	return a
+}
+
+// clauseParser returns the parser of a flag or argument.
+func clauseParser(clause interface{}) *parserMixin {
+	switch c := clause.(type) {
+	case *FlagClause:
+		return &c.parserMixin
+	case *ArgClause:
+		return &c.parserMixin
+	}
+	return nil
+}
+
+// clauseName returns the name of a flag or argument as used in messages. It
+// allocates, so is only called when reporting an error.
+func clauseName(clause interface{}) string {
+	switch c := clause.(type) {
+	case *FlagClause:
+		return "--" + c.name
+	case *ArgClause:
+		return "<" + c.name + ">"
+	}
+	return ""
+}
+
+// checkConstraints checks that the constraints of a flag or argument can be
+// applied to its value.
+func (p *parserMixin) checkConstraints(name string) error {
+	if p.min == nil && p.max == nil {
+		return nil
+	}
+	for _, limit := range []interface{}{p.min, p.max} {
+		if _, ok := toFloat(reflect.ValueOf(limit)); limit != nil && !ok {
+			return fmt.Errorf("non-numeric limit %v for %s", limit, name)
+		}
+	}
+	g, ok := p.value.(Getter)
+	if !ok {
+		return fmt.Errorf("range limits on non-numeric %s", name)
+	}
+	typ := reflect.TypeOf(g.Get())
+	if typ == nil {
+		return fmt.Errorf("range limits on non-numeric %s", name)
+	}
+	if typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if _, ok := toFloat(reflect.Zero(typ)); !ok {
+		return fmt.Errorf("range limits on non-numeric %s", name)
+	}
+	return nil
+}
+
+// checkString returns an error if s, which is about to be parsed, does not
+// satisfy the limits set by MinLen(), MaxLen() and Match().
+func (p *parserMixin) checkString(context *ParseContext, clause interface{}, s string) error {
+	n := utf8.RuneCountInString(s)
+	switch {
+	case len(p.oneOf) > 0 && !p.allows(s):
+		return fmt.Errorf(context.msg().ValueNotAllowed, clauseName(clause), strings.Join(p.oneOf, ", "))
+	case p.minLen > 0 && n < p.minLen:
+		return fmt.Errorf(context.msg().ValueTooShort, clauseName(clause), p.minLen)
+	case p.maxLen > 0 && n > p.maxLen:
+		return fmt.Errorf(context.msg().ValueTooLong, clauseName(clause), p.maxLen)
+	case p.pattern != nil && !p.pattern.MatchString(s):
+		return fmt.Errorf(context.msg().ValueMismatch, clauseName(clause), p.pattern)
+	}
+	return nil
+}
+
+// allows returns true if s is one of the values given to OneOf().
+func (p *parserMixin) allows(s string) bool {
+	for _, v := range p.oneOf {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// helpText returns the help of a flag or argument, followed by the values it
+// is restricted to, if any.
+func (p *parserMixin) helpText(help string, msg *Messages) string {
+	if len(p.oneOf) == 0 {
+		return help
+	}
+	choices := fmt.Sprintf(msg.OneOfHelp, strings.Join(p.oneOf, ", "))
+	if help == "" {
+		return choices
+	}
+	return help + " " + choices
+}
+
+// checkRange returns an error if the value last parsed is outside the limits
+// set by Min() and Max().
+func (p *parserMixin) checkRange(context *ParseContext, clause interface{}) error {
+	if p.min == nil && p.max == nil {
+		return nil
+	}
+	g, ok := p.value.(Getter)
+	if !ok {
+		return nil
+	}
+	v := reflect.ValueOf(g.Get())
+	if v.Kind() == reflect.Slice {
+		if v.Len() == 0 {
+			return nil
+		}
+		v = v.Index(v.Len() - 1)
+	}
+	if _, ok := toFloat(v); !ok {
+		return nil
+	}
+	tooSmall := p.min != nil && compareNumbers(v, reflect.ValueOf(p.min)) < 0
+	tooLarge := p.max != nil && compareNumbers(v, reflect.ValueOf(p.max)) > 0
+	switch {
+	case p.min != nil && p.max != nil && (tooSmall || tooLarge):
+		return fmt.Errorf(context.msg().ValueOutOfRange, clauseName(clause), p.min, p.max)
+	case tooSmall:
+		return fmt.Errorf(context.msg().ValueTooSmall, clauseName(clause), p.min)
+	case tooLarge:
+		return fmt.Errorf(context.msg().ValueTooLarge, clauseName(clause), p.max)
+	}
+	return nil
+}
+
+// compareNumbers returns -1, 0 or 1 as a is less than, equal to or greater
+// than b. Integers are compared exactly, so that limits beyond 2^53 are not
+// rounded; anything involving a float is compared as float64.
+func compareNumbers(a, b reflect.Value) int {
+	switch {
+	case isInt(a) && isInt(b):
+		return compareInts(a.Int(), b.Int())
+	case isUint(a) && isUint(b):
+		return compareUints(a.Uint(), b.Uint())
+	case isInt(a) && isUint(b):
+		if a.Int() < 0 {
+			return -1
+		}
+		return compareUints(uint64(a.Int()), b.Uint())
+	case isUint(a) && isInt(b):
+		if b.Int() < 0 {
+			return 1
+		}
+		return compareUints(a.Uint(), uint64(b.Int()))
+	}
+	x, _ := toFloat(a)
+	y, _ := toFloat(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareUints(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// toFloat converts an integer or floating-point value, including named types
+// such as time.Duration, to a float64.
+func toFloat(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,129 @@
+package kingpin
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagRange(t *testing.T) {
+	app := New("test", "")
+	port := app.Flag("port", "").Range(1, 65535).Int()
+	timeout := app.Flag("timeout", "").Min(time.Second).Default("5s").Duration()
+	ratio := app.Flag("ratio", "").Max(1).Float()
+
+	_, err := app.Parse([]string{"--port=8080", "--ratio=0.5"})
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+	assert.Equal(t, 5*time.Second, *timeout)
+	assert.Equal(t, 0.5, *ratio)
+
+	_, err = app.Parse([]string{"--port=0"})
+	assert.EqualError(t, err, "--port must be between 1 and 65535")
+	_, err = app.Parse([]string{"--timeout=10ms"})
+	assert.EqualError(t, err, "--timeout must be at least 1s")
+	_, err = app.Parse([]string{"--ratio=1.5"})
+	assert.EqualError(t, err, "--ratio must be at most 1")
+}
+
+func TestArgRange(t *testing.T) {
+	app := New("test", "")
+	sizes := app.Arg("sizes", "").Min(1).Ints()
+	_, err := app.Parse([]string{"3", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 2}, *sizes)
+
+	_, err = app.Parse([]string{"3", "0"})
+	assert.EqualError(t, err, "<sizes> must be at least 1")
+}
+
+func TestRangeOnNonNumericValue(t *testing.T) {
+	app := New("test", "")
+	app.Flag("name", "").Min(1).String()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "range limits on non-numeric flag --name")
+
+	app = New("test", "")
+	app.Arg("count", "").Max("ten").Int()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "non-numeric limit ten for arg 'count'")
+}
+
+type nilGetter struct{}
+
+func (nilGetter) Set(string) error { return nil }
+func (nilGetter) String() string   { return "" }
+func (nilGetter) Get() interface{} { return nil }
+
+func TestRangeOnNilGetter(t *testing.T) {
+	app := New("test", "")
+	app.Flag("thing", "").Min(1).SetValue(nilGetter{})
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "range limits on non-numeric flag --thing")
+}
+
+func TestRangeOnLargeIntegers(t *testing.T) {
+	app := New("test", "")
+	n := app.Flag("n", "").Max(int64(1<<62 + 1)).Int64()
+	u := app.Flag("u", "").Min(uint64(1<<63 + 1)).Uint64()
+
+	_, err := app.Parse([]string{"--n=4611686018427387905", "--u=9223372036854775809"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1<<62+1), *n)
+	assert.Equal(t, uint64(1<<63+1), *u)
+
+	_, err = app.Parse([]string{"--n=4611686018427387906"})
+	assert.EqualError(t, err, "--n must be at most 4611686018427387905")
+	_, err = app.Parse([]string{"--u=9223372036854775808"})
+	assert.EqualError(t, err, "--u must be at least 9223372036854775809")
+}
+
+func TestStringConstraints(t *testing.T) {
+	app := New("test", "")
+	name := app.Flag("name", "").MinLen(3).MaxLen(8).Match(regexp.MustCompile(`^[a-z][a-z0-9-]*$`)).String()
+	id := app.Arg("id", "").MaxLen(4).String()
+
+	_, err := app.Parse([]string{"--name=web-01", "ab"})
+	assert.NoError(t, err)
+	assert.Equal(t, "web-01", *name)
+	assert.Equal(t, "ab", *id)
+
+	_, err = app.Parse([]string{"--name=ab"})
+	assert.EqualError(t, err, "--name must be at least 3 characters")
+	_, err = app.Parse([]string{"--name=much-too-long"})
+	assert.EqualError(t, err, "--name must be at most 8 characters")
+	_, err = app.Parse([]string{"--name=Web"})
+	assert.EqualError(t, err, "--name must match ^[a-z][a-z0-9-]*$")
+	_, err = app.Parse([]string{"héllo"})
+	assert.EqualError(t, err, "<id> must be at most 4 characters")
+	_, err = app.Parse([]string{"héll"})
+	assert.NoError(t, err)
+}
+
+func TestOneOf(t *testing.T) {
+	app := New("test", "")
+	workers := app.Flag("workers", "Number of workers.").OneOf("1", "2", "4", "8").Int()
+	region := app.Arg("region", "").OneOf("eu", "us").String()
+
+	_, err := app.Parse([]string{"--workers=4", "eu"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, *workers)
+	assert.Equal(t, "eu", *region)
+
+	_, err = app.Parse([]string{"--workers=3"})
+	assert.EqualError(t, err, "--workers must be one of 1, 2, 4, 8")
+	_, err = app.Parse([]string{"asia"})
+	assert.EqualError(t, err, "<region> must be one of eu, us")
+
+	assert.Equal(t, []string{"1", "2", "4", "8"}, app.Complete([]string{"--workers"}, 1))
+	assert.Equal(t, []string{"us"}, app.Complete([]string{"u"}, 0))
+	assert.Equal(t, []string{"1", "2", "4", "8"}, app.Model().Flags[1].Choices)
+
+	w := bytes.NewBuffer(nil)
+	app.Usage(w)
+	assert.Contains(t, w.String(), "Number of workers. (one of 1, 2, 4, 8)")
+	assert.Contains(t, w.String(), "(one of eu, us)")
+}
@@ -0,0 +1,25 @@
+package kingpin
+
+import "context"
+
+// ParseWithContext is like Parse() but makes ctx available to Dispatch()
+// actions via ParseContext.Context(), so long-running actions can respond to
+// cancellation and deadlines.
+func (a *Application) ParseWithContext(ctx context.Context, args []string) (command string, err error) {
+	parseContext := Tokenize(args)
+	parseContext.ctx = ctx
+	parseContext, err = a.parseContext(parseContext)
+	if err != nil {
+		return "", err
+	}
+	return parseContext.command, nil
+}
+
+// Context returns the context.Context passed to ParseWithContext(), or
+// context.Background() if there is none.
+func (p *ParseContext) Context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
@@ -0,0 +1,23 @@
+package kingpin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	app := New("app", "")
+	app.Command("run", "").Dispatch(func(pc *ParseContext) error {
+		return pc.Context().Err()
+	})
+	_, err := app.ParseWithContext(ctx, []string{"run"})
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = app.Parse([]string{"run"})
+	assert.NoError(t, err)
+}
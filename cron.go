@@ -0,0 +1,210 @@
+package kingpin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression, as returned by CronSpec().
+type CronSchedule struct {
+	spec   string
+	fields [5]uint64 // Minute, hour, day of month, month and day of week.
+	// A restricted day of month or week matches either, as in cron.
+	anyDay bool
+}
+
+// cronField describes one of the five fields of a cron expression.
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// cronDescriptors are the shorthands accepted in place of five fields.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCronSpec parses a standard five field cron expression, eg.
+// "*/15 9-17 * * mon-fri", or a descriptor such as "@daily".
+func ParseCronSpec(spec string) (*CronSchedule, error) {
+	expr := strings.TrimSpace(spec)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("expected 5 fields but got %d", len(parts))
+	}
+	c := &CronSchedule{spec: spec}
+	for i, part := range parts {
+		bits, err := cronFields[i].parse(part)
+		if err != nil {
+			return nil, err
+		}
+		c.fields[i] = bits
+	}
+	// Sunday may be given as 0 or 7.
+	if c.fields[4]&(1<<7) != 0 {
+		c.fields[4] |= 1
+	}
+	c.anyDay = strings.HasPrefix(parts[2], "*") || strings.HasPrefix(parts[4], "*")
+	return c, nil
+}
+
+// parse returns the set of values of a comma separated list of values,
+// ranges and steps, as a bit mask.
+func (f cronField) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		expr, step := item, 1
+		if i := strings.IndexByte(item, '/'); i != -1 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %s field '%s'", f.name, item)
+			}
+			expr, step = item[:i], n
+		}
+		low, high := f.min, f.max
+		if expr != "*" {
+			bounds := strings.SplitN(expr, "-", 2)
+			var err error
+			if low, err = f.value(bounds[0]); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = f.value(bounds[1]); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				high = f.max
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range in %s field '%s'", f.name, item)
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or name within the range of the field.
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return i + f.min, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s '%s'", f.name, s)
+	}
+	return v, nil
+}
+
+func (c *CronSchedule) has(field, v int) bool {
+	return c.fields[field]&(1<<uint(v)) != 0
+}
+
+// matchesDay returns true if the schedule runs on the day of t.
+func (c *CronSchedule) matchesDay(t time.Time) bool {
+	dom, dow := c.has(2, t.Day()), c.has(4, int(t.Weekday()))
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time after t at which the schedule runs, in the
+// location of t, or the zero time if it never runs, eg. for "0 0 30 2 *".
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		year, month, day := t.Date()
+		switch {
+		case !c.has(3, int(month)):
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchesDay(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case !c.has(1, t.Hour()):
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.has(0, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// String returns the expression the schedule was parsed from.
+func (c *CronSchedule) String() string {
+	return c.spec
+}
+
+// -- *CronSchedule Value
+type cronValue struct {
+	schedule **CronSchedule
+}
+
+func newCronValue(p **CronSchedule) *cronValue {
+	return &cronValue{p}
+}
+
+func (c *cronValue) Set(value string) error {
+	schedule, err := ParseCronSpec(value)
+	if err != nil {
+		return fmt.Errorf("invalid cron spec '%s': %s", value, err)
+	}
+	*c.schedule = schedule
+	return nil
+}
+
+func (c *cronValue) Get() interface{} { return *c.schedule }
+
+func (c *cronValue) String() string {
+	if *c.schedule == nil {
+		return ""
+	}
+	return (*c.schedule).String()
+}
+
+func (c *cronValue) reset() { *c.schedule = nil }
+
+func (c *cronValue) clone() Value {
+	schedule := *c.schedule
+	return &cronValue{&schedule}
+}
+
+// CronSpec parses a cron expression such as "*/15 9-17 * * mon-fri" or
+// "@daily", so that malformed schedules are reported as usage errors.
+func (p *parserMixin) CronSpec() (target **CronSchedule) {
+	target = new(*CronSchedule)
+	p.CronSpecVar(target)
+	return
+}
+
+// CronSpecVar parses a cron expression.
+func (p *parserMixin) CronSpecVar(target **CronSchedule) {
+	p.SetValue(newCronValue(target))
+}
@@ -0,0 +1,54 @@
+package kingpin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	start := time.Date(2024, 2, 28, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2024, 2, 28, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 2, 28, 10, 15, 0, 0, time.UTC)},
+		{"0 9-17 * * mon-fri", time.Date(2024, 2, 28, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * sat,7", time.Date(2024, 3, 2, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 1", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 2, 28, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCronSpec(test.spec)
+		assert.NoError(t, err, test.spec)
+		assert.Equal(t, test.next, schedule.Next(start), test.spec)
+	}
+}
+
+func TestCronSpecValue(t *testing.T) {
+	app := New("test", "")
+	schedule := app.Flag("schedule", "").Default("@daily").CronSpec()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "@daily", (*schedule).String())
+
+	_, err = app.Parse([]string{"--schedule=5 4 * * sun"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 3, 4, 5, 0, 0, time.UTC), (*schedule).Next(time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)))
+
+	for spec, message := range map[string]string{
+		"* * * *":     "expected 5 fields but got 4",
+		"60 * * * *":  "invalid minute '60'",
+		"* * * foo *": "invalid month 'foo'",
+		"*/0 * * * *": "invalid step in minute field '*/0'",
+		"* 5-1 * * *": "invalid range in hour field '5-1'",
+	} {
+		_, err = app.Parse([]string{"--schedule=" + spec})
+		assert.EqualError(t, err, "invalid cron spec '"+spec+"': "+message)
+	}
+}
@@ -0,0 +1,98 @@
+package kingpin
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LoadDotEnv reads environment variables from dotenv files each time the
+// application is parsed, for use by flags with OverrideDefaultFromEnvar().
+// Variables in the real environment take precedence, as do earlier files
+// over later ones. Missing files are ignored.
+//
+// Each line of a file is a NAME=value pair, optionally preceded by "export".
+// Blank lines and lines starting with # are skipped. Values may be quoted
+// with single quotes, taken literally, or double quotes, in which Go escape
+// sequences are interpreted.
+func (a *Application) LoadDotEnv(paths ...string) *Application {
+	a.dotEnv = append(a.dotEnv, paths...)
+	return a
+}
+
+// readDotEnv reads the variables in the given files.
+func readDotEnv(paths []string) (map[string]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	env := map[string]string{}
+	for _, path := range paths {
+		if err := readDotEnvFile(path, env); err != nil {
+			return nil, err
+		}
+	}
+	return env, nil
+}
+
+func readDotEnvFile(path string, env map[string]string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		eq := strings.Index(text, "=")
+		if eq < 1 {
+			return fmt.Errorf("%s:%d: expected NAME=value", path, line)
+		}
+		name := strings.TrimSpace(text[:eq])
+		value, err := parseDotEnvValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+		if _, ok := env[name]; !ok {
+			env[name] = value
+		}
+	}
+	return scanner.Err()
+}
+
+func parseDotEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", value)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid quoted value %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	}
+	// Unquoted values may be followed by a comment.
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
+
+// getenv returns the value of an environment variable, falling back to those
+// loaded with LoadDotEnv().
+func (p *ParseContext) getenv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return p.dotEnv[name]
+}
@@ -0,0 +1,68 @@
+package kingpin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDotEnv(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "kingpin")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, ".env")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadDotEnv(t *testing.T) {
+	path := writeDotEnv(t, `
+# Local development settings.
+export KINGPIN_TEST_HOST=localhost # comment
+KINGPIN_TEST_NAME="hello\tworld"
+KINGPIN_TEST_RAW='a\tb'
+`)
+	app := New("test", "").LoadDotEnv(path, path+".missing")
+	host := app.Flag("host", "").OverrideDefaultFromEnvar("KINGPIN_TEST_HOST").String()
+	name := app.Flag("name", "").OverrideDefaultFromEnvar("KINGPIN_TEST_NAME").String()
+	raw := app.Flag("raw", "").OverrideDefaultFromEnvar("KINGPIN_TEST_RAW").String()
+	context, err := app.ParseContext([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", *host)
+	assert.Equal(t, "hello\tworld", *name)
+	assert.Equal(t, `a\tb`, *raw)
+	assert.Equal(t, SourceEnvar, context.Source("host"))
+}
+
+func TestLoadDotEnvPrecedence(t *testing.T) {
+	first := writeDotEnv(t, "KINGPIN_TEST_HOST=first\n")
+	second := writeDotEnv(t, "KINGPIN_TEST_HOST=second\nKINGPIN_TEST_PORT=8080\n")
+	app := New("test", "").LoadDotEnv(first, second)
+	host := app.Flag("host", "").OverrideDefaultFromEnvar("KINGPIN_TEST_HOST").String()
+	port := app.Flag("port", "").OverrideDefaultFromEnvar("KINGPIN_TEST_PORT").Int()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "first", *host)
+	assert.Equal(t, 8080, *port)
+
+	os.Setenv("KINGPIN_TEST_HOST", "environment")
+	defer os.Unsetenv("KINGPIN_TEST_HOST")
+	_, err = app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "environment", *host)
+
+	_, err = app.Parse([]string{"--host=flag"})
+	assert.NoError(t, err)
+	assert.Equal(t, "flag", *host)
+}
+
+func TestLoadDotEnvInvalid(t *testing.T) {
+	path := writeDotEnv(t, "KINGPIN_TEST_HOST=ok\nnot a variable\n")
+	app := New("test", "").LoadDotEnv(path)
+	app.Flag("host", "").OverrideDefaultFromEnvar("KINGPIN_TEST_HOST").String()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "could not load environment: "+path+":2: expected NAME=value")
+}
@@ -0,0 +1,128 @@
+package kingpin
+
+import "errors"
+
+var (
+	// ErrHelp is returned by Parse() when help was displayed and NoExit() is
+	// enabled.
+	ErrHelp = errors.New("help requested")
+	// ErrVersion is returned by Parse() when the version was displayed and
+	// NoExit() is enabled.
+	ErrVersion = errors.New("version requested")
+)
+
+// ErrorCategory classifies the errors and exits of an application, for the
+// purpose of selecting an exit status.
+type ErrorCategory int
+
+// Error categories.
+const (
+	// UsageError is an error in the command line, such as an unknown flag.
+	UsageError ErrorCategory = iota
+	// RuntimeError is an error returned by a Dispatch() action, or reported
+	// with Fatalf() or FatalIfError().
+	RuntimeError
+	// ValidationError is an error returned by a Validate() function.
+	ValidationError
+	// HelpRequested is the successful exit after displaying help or version.
+	HelpRequested
+)
+
+// ExitCodes maps error categories to exit statuses.
+type ExitCodes map[ErrorCategory]int
+
+// DefaultExitCodes are the exit statuses used unless overridden with
+// Application.ExitCodes(). Categories not present exit with status 1.
+var DefaultExitCodes = ExitCodes{
+	UsageError:      1,
+	RuntimeError:    1,
+	ValidationError: 1,
+	HelpRequested:   0,
+}
+
+type categorizedError struct {
+	category ErrorCategory
+	err      error
+}
+
+func categorize(category ErrorCategory, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*categorizedError); ok {
+		return err
+	}
+	return &categorizedError{category, err}
+}
+
+func (c *categorizedError) Error() string { return c.err.Error() }
+
+func (c *categorizedError) Unwrap() error { return c.err }
+
+// ErrorCategoryOf returns the category of an error returned by Parse().
+// Errors not otherwise categorized are UsageErrors.
+func ErrorCategoryOf(err error) ErrorCategory {
+	var c *categorizedError
+	if errors.As(err, &c) {
+		return c.category
+	}
+	return UsageError
+}
+
+// ExitCodes overrides the exit status used for each category of error.
+func (a *Application) ExitCodes(codes ExitCodes) *Application {
+	for category, code := range codes {
+		a.exitCodes[category] = code
+	}
+	return a
+}
+
+// Terminate sets the function called to exit the application, which defaults
+// to os.Exit.
+func (a *Application) Terminate(terminate func(status int)) *Application {
+	a.terminate = terminate
+	return a
+}
+
+// OnTerminate adds a function to be called with the exit status just before
+// the application terminates, including after displaying help or the version
+// and from Fatalf() and friends. This allows applications to flush logs or
+// restore the state of the terminal. Functions are called in the order they
+// were added.
+func (a *Application) OnTerminate(hook func(status int)) *Application {
+	a.onTerminate = append(a.onTerminate, hook)
+	return a
+}
+
+// NoExit guarantees that the application never terminates the process.
+// Instead, Parse() returns ErrHelp or ErrVersion after displaying help or the
+// version, and Fatalf() and friends only print their message. This is useful
+// when embedding an application in a server, or in tests.
+func (a *Application) NoExit() *Application {
+	a.noExit = true
+	return a
+}
+
+// finish exits after help or version output, or returns err if NoExit() is
+// enabled.
+func (a *Application) finish(err error) error {
+	if a.noExit {
+		return categorize(HelpRequested, err)
+	}
+	a.exit(HelpRequested)
+	return nil
+}
+
+func (a *Application) exit(category ErrorCategory) {
+	if a.noExit {
+		return
+	}
+	code, ok := a.exitCodes[category]
+	if !ok {
+		code = 1
+	}
+	for _, hook := range a.onTerminate {
+		hook(code)
+	}
+	a.terminate(code)
+}
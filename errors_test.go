@@ -0,0 +1,113 @@
+package kingpin
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCategories(t *testing.T) {
+	app := New("test", "")
+	app.Command("run", "").Dispatch(func(*ParseContext) error { return errors.New("failed") })
+	app.Command("check", "").Validate(func(*CmdClause) error { return errors.New("invalid") })
+
+	_, err := app.Parse([]string{"--unknown"})
+	assert.Equal(t, UsageError, ErrorCategoryOf(err))
+	_, err = app.Parse([]string{"run"})
+	assert.EqualError(t, err, "failed")
+	assert.Equal(t, RuntimeError, ErrorCategoryOf(err))
+	_, err = app.Parse([]string{"check"})
+	assert.Equal(t, ValidationError, ErrorCategoryOf(err))
+}
+
+func TestExitCodes(t *testing.T) {
+	status := -1
+	app := New("test", "").ExitCodes(ExitCodes{UsageError: 2}).Terminate(func(s int) { status = s })
+	w := bytes.NewBuffer(nil)
+	app.UsageErrorf(w, "bad")
+	assert.Equal(t, 2, status)
+	app.Fatalf(w, "fatal")
+	assert.Equal(t, 1, status)
+}
+
+func TestFatalUsageContext(t *testing.T) {
+	status := -1
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").ErrorWriter(w).Terminate(func(s int) { status = s })
+	app.Flag("global", "").Bool()
+	cmd := app.Command("post", "Post a message.")
+	cmd.Flag("channel", "").String()
+	cmd.Dispatch(func(context *ParseContext) error {
+		app.FatalUsageContext(context, "missing message")
+		return nil
+	})
+	_, err := app.Parse([]string{"post"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, status)
+	assert.Contains(t, w.String(), "test: error: missing message\n")
+	assert.Contains(t, w.String(), "Post a message.")
+}
+
+func TestOnTerminate(t *testing.T) {
+	calls := []string{}
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").Writer(w).UsageWriter(w).ErrorWriter(w).
+		OnTerminate(func(status int) { calls = append(calls, fmt.Sprintf("flush %d", status)) }).
+		OnTerminate(func(status int) { calls = append(calls, fmt.Sprintf("restore %d", status)) }).
+		Terminate(func(status int) { calls = append(calls, fmt.Sprintf("exit %d", status)) })
+	app.ExitCodes(ExitCodes{HelpRequested: 0})
+
+	app.Parse([]string{"--help"})
+	assert.Equal(t, []string{"flush 0", "restore 0", "exit 0"}, calls)
+
+	calls = nil
+	app.Fatalf(w, "fatal")
+	assert.Equal(t, []string{"flush 1", "restore 1", "exit 1"}, calls)
+
+	calls = nil
+	app.NoExit().Fatalf(w, "fatal")
+	assert.Empty(t, calls)
+}
+
+func TestNoExit(t *testing.T) {
+	terminated := false
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").NoExit().Writer(w).UsageWriter(w).ErrorWriter(w).Terminate(func(int) { terminated = true })
+	app.Version("1.0")
+	app.Command("cmd", "")
+
+	_, err := app.Parse([]string{"--help"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	assert.Equal(t, HelpRequested, ErrorCategoryOf(err))
+	_, err = app.Parse([]string{"cmd", "--help"})
+	assert.True(t, errors.Is(err, ErrHelp))
+	_, err = app.Parse([]string{"--version"})
+	assert.True(t, errors.Is(err, ErrVersion))
+	app.Fatalf(w, "fatal")
+	assert.False(t, terminated)
+}
+
+func TestMustParse(t *testing.T) {
+	status := -1
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").ErrorWriter(w).Terminate(func(code int) { status = code })
+	app.ExitCodes(ExitCodes{UsageError: 2})
+	app.Command("cmd", "")
+
+	assert.Equal(t, "cmd", app.MustParse(app.Parse([]string{"cmd"})))
+	assert.Equal(t, -1, status)
+	app.MustParse(app.Parse([]string{"--bogus"}))
+	assert.Equal(t, 2, status)
+	assert.Equal(t, "test: error: unknown long flag '--bogus', try --help\n", w.String())
+}
+
+func TestCommandUsageUnknownWithNoExit(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").NoExit()
+	app.Command("cmd", "")
+	assert.NotPanics(t, func() { app.CommandUsage(w, "nope") })
+	assert.Contains(t, w.String(), "nope")
+}
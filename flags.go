@@ -2,14 +2,17 @@ package kingpin
 
 import (
 	"fmt"
-	"os"
+	"io/ioutil"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 type flagGroup struct {
-	short     map[string]*FlagClause
-	long      map[string]*FlagClause
-	flagOrder []*FlagClause
+	short      map[string]*FlagClause
+	long       map[string]*FlagClause
+	alternates map[string]*FlagClause
+	flagOrder  []*FlagClause
 }
 
 func newFlagGroup() *flagGroup {
@@ -21,35 +24,130 @@ func newFlagGroup() *flagGroup {
 
 // Flag defines a new flag with the given long name and help.
 func (f *flagGroup) Flag(name, help string) *FlagClause {
-	flag := newFlag(name, help)
-	f.long[name] = flag
-	f.flagOrder = append(f.flagOrder, flag)
-	return flag
+	return f.AddFlag(newFlag(name, help))
+}
+
+// GetFlag returns the flag with the given long name, or nil, so that it may
+// be configured further.
+func (f *flagGroup) GetFlag(name string) *FlagClause {
+	for _, flag := range f.flagOrder {
+		if flag.name == name {
+			return flag
+		}
+	}
+	return nil
+}
+
+// RemoveFlag removes the flag with the given long name, returning it, or nil
+// if there is no such flag. This allows wrappers to drop flags they don't
+// want, including built-in flags such as --help.
+func (f *flagGroup) RemoveFlag(name string) *FlagClause {
+	for i, flag := range f.flagOrder {
+		if flag.name != name {
+			continue
+		}
+		f.flagOrder = append(f.flagOrder[:i:i], f.flagOrder[i+1:]...)
+		if f.long[name] == flag {
+			delete(f.long, name)
+		}
+		for alternate, owner := range f.alternates {
+			if owner == flag {
+				delete(f.alternates, alternate)
+			}
+		}
+		if f.short[string(flag.shorthand)] == flag {
+			delete(f.short, string(flag.shorthand))
+		}
+		return flag
+	}
+	return nil
+}
+
+// replaceRedefined replaces each flag with the last flag defined with the
+// same long name, keeping the position of the first.
+func (f *flagGroup) replaceRedefined() {
+	index := map[string]int{}
+	out := f.flagOrder[:0]
+	for _, flag := range f.flagOrder {
+		if i, ok := index[flag.name]; ok {
+			out[i] = flag
+			continue
+		}
+		index[flag.name] = len(out)
+		out = append(out, flag)
+	}
+	f.flagOrder = out
+	for _, flag := range out {
+		f.long[flag.name] = flag
+	}
 }
 
 func (f *flagGroup) init() error {
-	for _, flag := range f.long {
-		if err := flag.init(); err != nil {
-			return err
+	if errs := f.check(); len(errs) > 0 {
+		return errs[0]
+	}
+	f.index()
+	return nil
+}
+
+// index maps the long, alternate and short names of the flags to the flags,
+// which may have been renamed since they were added.
+func (f *flagGroup) index() {
+	f.long = make(map[string]*FlagClause)
+	f.alternates = make(map[string]*FlagClause)
+	f.short = make(map[string]*FlagClause)
+	for _, flag := range f.flagOrder {
+		f.long[flag.name] = flag
+		for name := range flag.alternates {
+			f.alternates[name] = flag
 		}
 		if flag.shorthand != 0 {
 			f.short[string(flag.shorthand)] = flag
 		}
 	}
-	return nil
 }
 
-func (f *flagGroup) parse(context *ParseContext, ignoreRequired bool) error {
-	// Track how many required flags we've seen.
-	required := make(map[string]bool)
-	// Keep track of any flags that we need to initialise with defaults.
-	defaults := make(map[string]bool)
-	for k, flag := range f.long {
-		defaults[k] = true
-		if !ignoreRequired && flag.needsValue() {
-			required[k] = true
+// check returns all problems with the definitions of the flags.
+func (f *flagGroup) check() (errs []error) {
+	long := map[string]bool{}
+	var short [256]bool
+	for _, flag := range f.flagOrder {
+		if long[flag.name] {
+			errs = append(errs, fmt.Errorf("duplicate long flag --%s", flag.name))
+			continue
 		}
+		long[flag.name] = true
+		if err := flag.init(); err != nil {
+			errs = append(errs, err)
+		}
+		if flag.shorthand != 0 {
+			if short[flag.shorthand] {
+				errs = append(errs, fmt.Errorf("duplicate short flag -%c", flag.shorthand))
+			}
+			short[flag.shorthand] = true
+		}
+	}
+	alternates := map[string]bool{}
+	for _, flag := range f.flagOrder {
+		for _, name := range flag.sortedAlternateNames() {
+			if long[name] || alternates[name] {
+				errs = append(errs, fmt.Errorf("duplicate long flag --%s", name))
+			}
+			alternates[name] = true
+		}
+	}
+	return
+}
+
+// lookup returns the flag with the given long name or alternate name, or nil.
+func (f *flagGroup) lookup(name string) *FlagClause {
+	if flag, ok := f.long[name]; ok {
+		return flag
 	}
+	return f.alternates[name]
+}
+
+func (f *flagGroup) parse(context *ParseContext, ignoreRequired bool) error {
 
 	var token *Token
 
@@ -73,48 +171,100 @@ loop:
 					name = name[3:]
 					invert = true
 				}
-				flag, ok = f.long[name]
+				flag = f.lookup(name)
+				ok = flag != nil
+				if !ok {
+					flag, ok = context.inheritedFlag(name, false)
+				}
 				if !ok {
-					return fmt.Errorf("unknown long flag '%s'", flagToken)
+					if context.stopPartial() {
+						break loop
+					}
+					if context.deferFlag(flagToken, name) {
+						continue
+					}
+					if err := context.fail(fmt.Errorf(context.msg().UnknownLongFlag, flagToken)); err != nil {
+						return err
+					}
+					context.skipUnknown(flagToken)
+					continue
 				}
 			} else {
 				flag, ok = f.short[name]
 				if !ok {
-					return fmt.Errorf("unknown short flag '%s'", flagToken)
+					flag, ok = context.inheritedFlag(name, true)
+				}
+				if !ok {
+					if context.stopPartial() {
+						break loop
+					}
+					if context.deferFlag(flagToken, name) {
+						continue
+					}
+					if err := context.fail(fmt.Errorf(context.msg().UnknownShortFlag, flagToken)); err != nil {
+						return err
+					}
+					context.skipUnknown(flagToken)
+					continue
 				}
 			}
 
-			delete(required, flag.name)
-			delete(defaults, flag.name)
+			if context.flagsSeen == nil {
+				context.flagsSeen = make([]*FlagClause, 0, len(context.Tokens))
+			}
+			context.flagsSeen = append(context.flagsSeen, flag)
+
+			if flag.deprecated != "" {
+				context.Warn(context.msg().DeprecatedFlag, flag.name, flag.deprecated)
+			}
+			if flagToken.Type == TokenLong && name != flag.name && flag.alternates[name] {
+				context.Warn(context.msg().RenamedFlag, name, flag.name)
+			}
 
 			context.Next()
 
 			fb, ok := flag.value.(boolFlag)
 			if ok && fb.IsBoolFlag() {
-				if invert {
-					defaultValue = "false"
-				} else {
-					defaultValue = "true"
+				v := true
+				// An explicit value may be attached, as in "--name=false", or
+				// follow with SeparateBoolValues().
+				if token = context.Peek(); context.attached(flagToken, token) || context.boolValue(flagToken, token) {
+					context.Next()
+					var err error
+					if v, err = strconv.ParseBool(token.Value); err != nil {
+						return fmt.Errorf(context.msg().InvalidBoolFlag, flagToken, token.Value)
+					}
 				}
+				defaultValue = strconv.FormatBool(v != invert)
 			} else {
 				if invert {
-					return fmt.Errorf("unknown long flag '%s'", flagToken)
+					return fmt.Errorf(context.msg().UnknownLongFlag, flagToken)
 				}
 				token = context.Peek()
-				if token.Type != TokenArg {
-					return fmt.Errorf("expected argument for flag '%s'", flagToken)
+				if flag.optionalValue && !context.attached(flagToken, token) {
+					defaultValue = flag.noOptDefault
+				} else if token.Type != TokenArg {
+					return fmt.Errorf(context.msg().ExpectedFlagArgument, flagToken)
+				} else {
+					context.Next()
+					defaultValue = token.Value
+					if flag.fileRef && strings.HasPrefix(defaultValue, "@") {
+						value, err := readFileRef(defaultValue[1:])
+						if err != nil {
+							return fmt.Errorf(context.msg().FileRefFailed, flag.name, err)
+						}
+						defaultValue = value
+					}
 				}
-				context.Next()
-				defaultValue = token.Value
 			}
 
-			if err := flag.value.Set(defaultValue); err != nil {
-				return err
+			if err := context.set(flag, flag.value, defaultValue, SourceCommandLine); err != nil {
+				return flag.secretError(context, err)
 			}
 
 			if flag.dispatch != nil {
-				if err := flag.dispatch(context); err != nil {
-					return err
+				if err := context.dispatch(flag.dispatch); err != nil {
+					return categorize(RuntimeError, err)
 				}
 			}
 
@@ -123,25 +273,80 @@ loop:
 		}
 	}
 
+	// Persistent flags may still be provided after a subcommand.
+	return f.finish(context, ignoreRequired, false)
+}
+
+// finish checks that the required flags were provided and applies defaults,
+// in declaration order, for either the persistent or the other flags.
+func (f *flagGroup) finish(context *ParseContext, ignoreRequired, persistent bool) error {
+	seen := func(flag *FlagClause) bool {
+		return len(context.Elements(flag)) > 0
+	}
+
 	// Check that required flags were provided.
+	required := []string{}
+	if !ignoreRequired {
+		for _, flag := range f.flagOrder {
+			if flag.persistent == persistent && !seen(flag) && flag.needsValue(context) {
+				if ok, err := flag.prompt(context); err != nil {
+					return err
+				} else if ok {
+					continue
+				}
+				required = append(required, flag.name)
+			}
+		}
+	}
 	if len(required) == 1 {
-		for k := range required {
-			return fmt.Errorf("required flag --%s not provided", k)
+		if err := context.fail(fmt.Errorf(context.msg().RequiredFlag, required[0])); err != nil {
+			return err
 		}
 	} else if len(required) > 1 {
 		flags := make([]string, 0, len(required))
-		for k := range required {
-			flags = append(flags, "--"+k)
+		for _, name := range required {
+			flags = append(flags, "--"+name)
+		}
+		if err := context.fail(fmt.Errorf(context.msg().RequiredFlags, strings.Join(flags, ", "))); err != nil {
+			return err
 		}
-		return fmt.Errorf("required flags %s not provided", strings.Join(flags, ", "))
 	}
 
 	// Apply defaults to all unprocessed flags.
-	for k := range defaults {
-		flag := f.long[k]
-		if flag.defaultValue != "" {
-			if err := flag.value.Set(flag.defaultValue); err != nil {
-				return fmt.Errorf("default value for --%s is invalid: %s", flag.name, err)
+	for _, flag := range f.flagOrder {
+		if flag.persistent != persistent || seen(flag) {
+			continue
+		}
+		values, source, err := flag.defaultFor(context)
+		if err != nil {
+			return err
+		}
+		if source == SourceEnvar && flag.deprecated != "" {
+			// Falling back to the environment is as much a use of the flag
+			// as giving it on the command line.
+			context.Warn(context.msg().DeprecatedEnvar, flag.envar, flag.name, flag.deprecated)
+		}
+		for _, value := range values {
+			if err := context.set(flag, flag.value, value, source); err != nil {
+				return fmt.Errorf(context.msg().InvalidFlagDefault, flag.name, flag.secretError(context, err))
+			}
+		}
+	}
+
+	if !ignoreRequired {
+		for _, flag := range f.flagOrder {
+			if flag.persistent != persistent {
+				continue
+			}
+			if flag.requiredBy(context, flag) {
+				if err := context.fail(fmt.Errorf(context.msg().RequiredFlag, flag.name)); err != nil {
+					return err
+				}
+			}
+			if err := flag.checkOccurrences(context, flag); err != nil {
+				if err := context.fail(err); err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -150,7 +355,7 @@ loop:
 
 func (f *flagGroup) visibleFlags() int {
 	count := 0
-	for _, flag := range f.long {
+	for _, flag := range f.flagOrder {
 		if !flag.hidden {
 			count++
 		}
@@ -161,14 +366,24 @@ func (f *flagGroup) visibleFlags() int {
 // FlagClause is a fluid interface used to build flags.
 type FlagClause struct {
 	parserMixin
-	name         string
-	shorthand    byte
-	help         string
-	envar        string
-	defaultValue string
-	placeholder  string
-	dispatch     Dispatch
-	hidden       bool
+	name          string
+	shorthand     byte
+	help          string
+	envar         string
+	defaultValues []string
+	defaultFunc   func() (string, error)
+	noOptDefault  string
+	optionalValue bool
+	placeholder   string
+	dispatch      Dispatch
+	hidden        bool
+	deprecated    string
+	alternates    map[string]bool
+	section       string
+	persistent    bool
+	override      bool
+	fileRef       bool
+	password      bool
 }
 
 func newFlag(name, help string) *FlagClause {
@@ -179,36 +394,76 @@ func newFlag(name, help string) *FlagClause {
 	return f
 }
 
-func (f *FlagClause) needsValue() bool {
-	return f.required && f.defaultValue == ""
+func (f *FlagClause) needsValue(context *ParseContext) bool {
+	if !f.required {
+		return false
+	}
+	_, source, _ := f.defaultFor(context)
+	return source == SourceNone
+}
+
+// defaultFor returns the values of the flag when it is not present on the
+// command line, and where they came from. The environment variable, if any,
+// overrides the default unless the parse has no side effects.
+func (f *FlagClause) defaultFor(context *ParseContext) ([]string, ValueSource, error) {
+	if f.envar != "" && !context.pure {
+		if v := context.getenv(f.envar); v != "" {
+			return []string{v}, SourceEnvar, nil
+		}
+	}
+	if len(f.defaultValues) > 0 {
+		return f.defaultValues, SourceDefault, nil
+	}
+	if f.defaultFunc != nil {
+		v, err := f.defaultFunc()
+		if err != nil {
+			return nil, SourceNone, fmt.Errorf(context.msg().DefaultFuncFailed, "--"+f.name, err)
+		}
+		if v != "" {
+			return []string{v}, SourceDefault, nil
+		}
+	}
+	return nil, SourceNone, nil
 }
 
 func (f *FlagClause) formatPlaceHolder() string {
 	if f.placeholder != "" {
 		return f.placeholder
 	}
-	if f.defaultValue != "" {
+	if len(f.defaultValues) > 0 && !f.password {
 		if _, ok := f.value.(*stringValue); ok {
-			return fmt.Sprintf("%q", f.defaultValue)
+			return fmt.Sprintf("%q", f.defaultValues[0])
 		}
-		return f.defaultValue
+		return strings.Join(f.defaultValues, ",")
 	}
 	return strings.ToUpper(f.name)
 }
 
 func (f *FlagClause) init() error {
-	if f.required && f.defaultValue != "" {
+	if f.required && (len(f.defaultValues) > 0 || f.defaultFunc != nil || f.defaultFrom != nil) {
 		return fmt.Errorf("required flag '--%s' with default value that will never be used", f.name)
 	}
 	if f.value == nil {
 		return fmt.Errorf("no type defined for --%s (eg. .String())", f.name)
 	}
-	if f.envar != "" {
-		if v := os.Getenv(f.envar); v != "" {
-			f.defaultValue = v
-		}
+	if len(f.defaultValues) > 1 && !isCumulative(f.value) {
+		return fmt.Errorf("multiple default values for non-cumulative flag --%s", f.name)
 	}
-	return nil
+	if fb, ok := f.value.(boolFlag); ok && fb.IsBoolFlag() && f.optionalValue {
+		return fmt.Errorf("NoOptDefault() on boolean flag --%s", f.name)
+	}
+	if (f.minOccurrences > 0 || f.maxOccurrences > 0) && !isCumulative(f.value) {
+		return fmt.Errorf("occurrence limits on non-cumulative flag --%s", f.name)
+	}
+	return f.checkConstraints("flag --" + f.name)
+}
+
+// IsSetByUser returns true if the flag was given a value by the user in the
+// parse described by context, on the command line, in the environment or at
+// a prompt, rather than defaulted. This allows, eg., a flag to override a
+// configuration file only when it is given.
+func (f *FlagClause) IsSetByUser(context *ParseContext) bool {
+	return context.setByUser(f)
 }
 
 // Dispatch to the given function when the flag is parsed.
@@ -218,8 +473,36 @@ func (f *FlagClause) Dispatch(dispatch Dispatch) *FlagClause {
 }
 
 // Default value for this flag. It *must* be parseable by the value of the flag.
-func (f *FlagClause) Default(value string) *FlagClause {
-	f.defaultValue = value
+// Cumulative flags may be given several values, eg. Default("a", "b").
+func (f *FlagClause) Default(values ...string) *FlagClause {
+	f.defaultValues = defaultValues(values)
+	return f
+}
+
+// defaultValues returns the given default values, or nil for Default("").
+func defaultValues(values []string) []string {
+	if len(values) == 1 && values[0] == "" {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+// DefaultFunc sets a function that computes the default value for this flag.
+// It is only called if the flag is not provided by the command line or
+// environment, so may be expensive, and is ignored if Default() is also set.
+func (f *FlagClause) DefaultFunc(fn func() (string, error)) *FlagClause {
+	f.defaultFunc = fn
+	return f
+}
+
+// DefaultFrom sets a function that computes the default value for this flag
+// from other values, eg. with ParseContext.StringValue(). It is called after
+// all other flags and arguments have been set, so defaults can refer to
+// values of commands selected later on the command line. Flags and arguments
+// with a DefaultFrom() default are resolved in declaration order, application
+// first, so may refer to those resolved before them.
+func (f *FlagClause) DefaultFrom(fn func(*ParseContext) (string, error)) *FlagClause {
+	f.defaultFrom = fn
 	return f
 }
 
@@ -230,6 +513,16 @@ func (f *FlagClause) OverrideDefaultFromEnvar(envar string) *FlagClause {
 	return f
 }
 
+// NoOptDefault makes the value of the flag optional, as in "--color[=WHEN]".
+// If the flag is given without a value the given value is used instead. An
+// optional value must be attached to the flag, as in "--color=never", as
+// "--color never" is the flag followed by a positional argument.
+func (f *FlagClause) NoOptDefault(value string) *FlagClause {
+	f.noOptDefault = value
+	f.optionalValue = true
+	return f
+}
+
 // PlaceHolder sets the place-holder string used for flag values in the help. The
 // default behaviour is to use the value provided by Default() if provided,
 // then fall back on the capitalized flag name.
@@ -244,12 +537,143 @@ func (f *FlagClause) Hidden() *FlagClause {
 	return f
 }
 
+// AllowFileRef allows the value of the flag to be read from a file, by giving
+// the file name prefixed with "@", eg. --token=@/run/secrets/token. This keeps
+// secrets out of shell history and process listings. A single trailing
+// newline is removed from the contents of the file.
+func (f *FlagClause) AllowFileRef() *FlagClause {
+	f.fileRef = true
+	return f
+}
+
+// readFileRef reads a flag value from a file.
+func readFileRef(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
+
+// Override allows the flag to have the same name or short name as a
+// persistent flag of a parent command, which it then replaces within this
+// command.
+func (f *FlagClause) Override() *FlagClause {
+	f.override = true
+	return f
+}
+
+// Section places the flag under a heading of its own in help. Flags without a
+// section are displayed first, followed by each section in the order it was
+// first used.
+func (f *FlagClause) Section(title string) *FlagClause {
+	f.section = title
+	return f
+}
+
+// Deprecated marks the flag as deprecated. A warning including message is
+// produced whenever the flag is used, on the command line or through its
+// environment variable.
+func (f *FlagClause) Deprecated(message string) *FlagClause {
+	f.deprecated = message
+	return f
+}
+
+// HintAction registers a function that provides completion candidates for
+// the value of the flag.
+func (f *FlagClause) HintAction(action HintAction) *FlagClause {
+	f.hintActions = append(f.hintActions, action)
+	return f
+}
+
+// HintOptions registers fixed completion candidates for the value of the flag.
+func (f *FlagClause) HintOptions(options ...string) *FlagClause {
+	return f.HintAction(func() []string { return options })
+}
+
 // Required makes the flag required. You can not provide a Default() value to a Required() flag.
 func (f *FlagClause) Required() *FlagClause {
 	f.required = true
 	return f
 }
 
+// RequiredIf makes the flag required when predicate returns true. The
+// predicate is called once the flags of the application or command declaring
+// the flag have been parsed and their defaults applied, so it can inspect them.
+func (f *FlagClause) RequiredIf(predicate func(*ParseContext) bool) *FlagClause {
+	f.requiredIf = predicate
+	return f
+}
+
+// Cumulative marks the flag's value as cumulative, so that each occurrence of
+// the flag is passed to the value's Set() method. Combine with
+// NewAccumulator() to collect each occurrence of a custom Value into a slice.
+func (f *FlagClause) Cumulative() *FlagClause {
+	f.setCumulative()
+	return f
+}
+
+// MinOccurrences requires a cumulative flag to be provided at least n times.
+// A default value counts as one occurrence.
+func (f *FlagClause) MinOccurrences(n int) *FlagClause {
+	f.minOccurrences = n
+	return f
+}
+
+// MaxOccurrences allows a cumulative flag to be provided at most n times.
+func (f *FlagClause) MaxOccurrences(n int) *FlagClause {
+	f.maxOccurrences = n
+	return f
+}
+
+// Rename changes the long name of the flag, eg. to rename the built-in
+// Application.HelpFlag.
+func (f *FlagClause) Rename(name string) *FlagClause {
+	f.name = name
+	return f
+}
+
+// AlternateNames adds long names by which the flag is also accepted, eg. so
+// that names it was known by before being renamed keep working. Alternate
+// names are not shown in help.
+func (f *FlagClause) AlternateNames(names ...string) *FlagClause {
+	return f.addAlternateNames(names, false)
+}
+
+// DeprecatedAlternateNames is like AlternateNames(), but a warning that the
+// flag has been renamed is produced whenever one of the names is used.
+func (f *FlagClause) DeprecatedAlternateNames(names ...string) *FlagClause {
+	return f.addAlternateNames(names, true)
+}
+
+func (f *FlagClause) addAlternateNames(names []string, deprecated bool) *FlagClause {
+	if f.alternates == nil {
+		f.alternates = map[string]bool{}
+	}
+	for _, name := range names {
+		f.alternates[name] = deprecated
+	}
+	return f
+}
+
+// sortedAlternateNames returns the alternate names of the flag in order.
+func (f *FlagClause) sortedAlternateNames() []string {
+	names := make([]string, 0, len(f.alternates))
+	for name := range f.alternates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasName returns true if name is the long name or an alternate name of the
+// flag.
+func (f *FlagClause) hasName(name string) bool {
+	_, ok := f.alternates[name]
+	return f.name == name || ok
+}
+
 // Short sets the short flag name.
 func (f *FlagClause) Short(name byte) *FlagClause {
 	f.shorthand = name
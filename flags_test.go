@@ -1,6 +1,11 @@
 package kingpin
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -27,6 +32,62 @@ func TestNoBool(t *testing.T) {
 	assert.False(t, *b)
 }
 
+func TestExplicitBool(t *testing.T) {
+	app := New("test", "")
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	files := app.Arg("files", "").Strings()
+	for _, test := range []struct {
+		args     []string
+		expected bool
+	}{
+		{[]string{"--verbose=true"}, true},
+		{[]string{"--verbose=false"}, false},
+		{[]string{"--verbose=1"}, true},
+		{[]string{"--verbose=0"}, false},
+		{[]string{"--no-verbose=false"}, true},
+		{[]string{"--no-verbose=true"}, false},
+	} {
+		_, err := app.Parse(test.args)
+		assert.NoError(t, err, test.args)
+		assert.Equal(t, test.expected, *verbose, test.args)
+		assert.Empty(t, *files)
+	}
+
+	// A separate argument is positional, so existing command lines keep
+	// their meaning.
+	_, err := app.Parse([]string{"--verbose", "false"})
+	assert.NoError(t, err)
+	assert.True(t, *verbose)
+	assert.Equal(t, []string{"false"}, *files)
+
+	_, err = app.Parse([]string{"--verbose=maybe"})
+	assert.EqualError(t, err, "expected true or false for flag '--verbose' but got 'maybe'")
+}
+
+func TestSeparateBoolValues(t *testing.T) {
+	app := New("test", "").SeparateBoolValues()
+	verbose := app.Flag("verbose", "").Short('v').Bool()
+	files := app.Arg("files", "").Strings()
+	for _, test := range []struct {
+		args     []string
+		expected bool
+		files    []string
+	}{
+		{[]string{"--verbose", "0"}, false, nil},
+		{[]string{"--verbose", "1", "a"}, true, []string{"a"}},
+		{[]string{"-v", "false"}, false, nil},
+		{[]string{"--no-verbose", "true"}, false, nil},
+		{[]string{"--verbose=false", "true"}, false, []string{"true"}},
+		{[]string{"--verbose", "yes"}, true, []string{"yes"}},
+		{[]string{"--verbose", "--", "0"}, true, []string{"0"}},
+	} {
+		_, err := app.Parse(test.args)
+		assert.NoError(t, err, test.args)
+		assert.Equal(t, test.expected, *verbose, test.args)
+		assert.Equal(t, test.files, *files, test.args)
+	}
+}
+
 func TestNegateNonBool(t *testing.T) {
 	fg := newFlagGroup()
 	f := fg.Flag("b", "")
@@ -58,3 +119,265 @@ func TestRequiredFlag(t *testing.T) {
 	err = fg.parse(tokens, false)
 	assert.Error(t, err)
 }
+
+func TestDeprecatedFlagWarns(t *testing.T) {
+	warnings := []string{}
+	app := New("test", "").OnWarning(func(w string) { warnings = append(warnings, w) })
+	app.Flag("old", "").Deprecated("use --new").Bool()
+	context, err := app.ParseContext([]string{"--old"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flag --old is deprecated: use --new"}, warnings)
+	assert.Equal(t, warnings, context.Warnings())
+}
+
+func TestDeprecatedFlagWarnsFromEnvar(t *testing.T) {
+	os.Setenv("KINGPIN_TEST_OLD", "x")
+	defer os.Unsetenv("KINGPIN_TEST_OLD")
+	warnings := []string{}
+	app := New("test", "").OnWarning(func(w string) { warnings = append(warnings, w) })
+	app.Flag("old", "").Deprecated("use --new").OverrideDefaultFromEnvar("KINGPIN_TEST_OLD").String()
+	app.Flag("current", "").OverrideDefaultFromEnvar("KINGPIN_TEST_OLD").String()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"$KINGPIN_TEST_OLD sets deprecated flag --old: use --new"}, warnings)
+
+	warnings = nil
+	_, err = app.Parse([]string{"--old=y"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"flag --old is deprecated: use --new"}, warnings)
+}
+
+func TestFlagAlternateNames(t *testing.T) {
+	warnings := []string{}
+	app := New("test", "").OnWarning(func(w string) { warnings = append(warnings, w) })
+	output := app.Flag("output", "").AlternateNames("out").DeprecatedAlternateNames("output-file").String()
+	color := app.Flag("color", "").AlternateNames("colour").Bool()
+	_, err := app.Parse([]string{"--out=a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a", *output)
+	assert.Empty(t, warnings)
+
+	context, err := app.ParseContext([]string{"--output-file", "b", "--colour"})
+	assert.NoError(t, err)
+	assert.Equal(t, "b", *output)
+	assert.True(t, *color)
+	assert.Equal(t, "b", context.StringValue("output"))
+	assert.Equal(t, []string{"flag --output-file is deprecated: use --output instead"}, warnings)
+
+	_, err = app.Parse([]string{"--colour", "--no-colour"})
+	assert.NoError(t, err)
+	assert.False(t, *color)
+
+	w := bytes.NewBuffer(nil)
+	app.Usage(w)
+	assert.Contains(t, w.String(), "--output=OUTPUT")
+	assert.NotContains(t, w.String(), "output-file")
+	assert.NotContains(t, w.String(), "colour")
+}
+
+func TestFlagAlternateNameConflicts(t *testing.T) {
+	app := New("test", "")
+	app.Flag("output", "").AlternateNames("out").String()
+	app.Flag("out", "").String()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "duplicate long flag --out")
+}
+
+func TestFlagAlternateNamesAfterCheck(t *testing.T) {
+	app := New("test", "")
+	output := app.Flag("output", "").String()
+	assert.Empty(t, app.Check())
+	app.GetFlag("output").AlternateNames("out")
+	_, err := app.Parse([]string{"--out=x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "x", *output)
+}
+
+func TestRequiredFlagsReportedInDeclarationOrder(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		app := New("test", "")
+		app.Flag("c", "").Required().String()
+		app.Flag("a", "").Required().String()
+		app.Flag("b", "").Required().String()
+		_, err := app.Parse([]string{})
+		assert.EqualError(t, err, "required flags --c, --a, --b not provided")
+	}
+}
+
+func TestDuplicateFlags(t *testing.T) {
+	app := New("test", "")
+	app.Flag("a", "").Short('x').Bool()
+	app.Flag("b", "").Short('x').Bool()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "duplicate short flag -x")
+
+	app = New("test", "")
+	app.Flag("a", "").Bool()
+	app.Flag("a", "").String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "duplicate long flag --a")
+}
+
+func TestFlagOccurrences(t *testing.T) {
+	app := New("test", "")
+	app.Flag("target", "").MinOccurrences(2).MaxOccurrences(3).Strings()
+	_, err := app.Parse([]string{"--target=a", "--target=b"})
+	assert.NoError(t, err)
+	_, err = app.Parse([]string{"--target=a"})
+	assert.EqualError(t, err, "--target requires at least 2 value(s)")
+	_, err = app.Parse([]string{"--target=a", "--target=b", "--target=c", "--target=d"})
+	assert.EqualError(t, err, "--target accepts at most 3 value(s)")
+
+	app = New("test", "")
+	app.Flag("target", "").Default("a").MinOccurrences(1).Strings()
+	_, err = app.Parse([]string{})
+	assert.NoError(t, err)
+
+	app = New("test", "")
+	app.Flag("target", "").MinOccurrences(1).String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "occurrence limits on non-cumulative flag --target")
+}
+
+func TestFlagRequiredIf(t *testing.T) {
+	app := New("test", "")
+	tls := app.Flag("tls", "").Bool()
+	app.Flag("key", "").RequiredIf(func(*ParseContext) bool { return *tls }).String()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	_, err = app.Parse([]string{"--tls"})
+	assert.EqualError(t, err, "required flag --key not provided")
+	_, err = app.Parse([]string{"--tls", "--key=k"})
+	assert.NoError(t, err)
+}
+
+func TestFlagDefaultFunc(t *testing.T) {
+	calls := 0
+	app := New("test", "")
+	host := app.Flag("host", "").DefaultFunc(func() (string, error) {
+		calls++
+		return "localhost", nil
+	}).String()
+	_, err := app.Parse([]string{"--host=example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", *host)
+	assert.Equal(t, 0, calls)
+	_, err = app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", *host)
+	assert.Equal(t, 1, calls)
+
+	app = New("test", "")
+	app.Flag("token", "").DefaultFunc(func() (string, error) {
+		return "", fmt.Errorf("no token file")
+	}).String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "could not determine default for --token: no token file")
+}
+
+func TestFlagMultipleDefaults(t *testing.T) {
+	app := New("test", "")
+	tags := app.Flag("tag", "").Default("a", "b", "c").Strings()
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, *tags)
+	_, err = app.Parse([]string{"--tag=d"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"d"}, *tags)
+	assert.Equal(t, []string{"a", "b", "c"}, app.Model().Flags[1].Defaults)
+
+	app = New("test", "")
+	app.Flag("name", "").Default("a", "b").String()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "multiple default values for non-cumulative flag --name")
+
+	app = New("test", "")
+	name := app.Flag("name", "").Default("a").Default("").String()
+	_, err = app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", *name)
+}
+
+func TestFlagNoOptDefault(t *testing.T) {
+	app := New("test", "")
+	color := app.Flag("color", "").Short('c').Default("auto").NoOptDefault("always").PlaceHolder("WHEN").String()
+	files := app.Arg("files", "").Strings()
+
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "auto", *color)
+	_, err = app.Parse([]string{"--color=never", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "never", *color)
+	assert.Equal(t, []string{"a"}, *files)
+	_, err = app.Parse([]string{"--color", "a"})
+	assert.NoError(t, err)
+	assert.Equal(t, "always", *color)
+	assert.Equal(t, []string{"a"}, *files)
+	_, err = app.Parse([]string{"-c"})
+	assert.NoError(t, err)
+	assert.Equal(t, "always", *color)
+	_, err = app.Parse([]string{"--color="})
+	assert.NoError(t, err)
+	assert.Equal(t, "", *color)
+
+	w := &bytes.Buffer{}
+	app.Usage(w)
+	assert.Contains(t, w.String(), "-c, --color[=WHEN]")
+
+	app = New("test", "")
+	app.Flag("verbose", "").NoOptDefault("true").Bool()
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "NoOptDefault() on boolean flag --verbose")
+}
+
+func TestFlagDefaultFrom(t *testing.T) {
+	app := New("test", "")
+	output := app.Flag("output", "").DefaultFrom(func(context *ParseContext) (string, error) {
+		return context.StringValue("build.input") + ".out", nil
+	}).String()
+	log := app.Flag("log", "").DefaultFrom(func(context *ParseContext) (string, error) {
+		return context.StringValue("output") + ".log", nil
+	}).String()
+	var seen string
+	build := app.Command("build", "").Dispatch(func(*ParseContext) error {
+		seen = *output
+		return nil
+	})
+	build.Arg("input", "").Required().String()
+
+	_, err := app.Parse([]string{"build", "main.c"})
+	assert.NoError(t, err)
+	assert.Equal(t, "main.c.out", *output)
+	assert.Equal(t, "main.c.out.log", *log)
+	assert.Equal(t, "main.c.out", seen)
+
+	_, err = app.Parse([]string{"--output=a.out", "build", "main.c"})
+	assert.NoError(t, err)
+	assert.Equal(t, "a.out", *output)
+	assert.Equal(t, "a.out.log", *log)
+}
+
+func TestFlagAllowFileRef(t *testing.T) {
+	f, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("s3cret\n")
+	f.Close()
+
+	app := New("test", "")
+	token := app.Flag("token", "").AllowFileRef().String()
+	name := app.Flag("name", "").String()
+	_, err = app.Parse([]string{"--token=@" + f.Name(), "--name=@" + f.Name()})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", *token)
+	assert.Equal(t, "@"+f.Name(), *name)
+
+	_, err = app.Parse([]string{"--token", "plain"})
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", *token)
+
+	_, err = app.Parse([]string{"--token=@" + f.Name() + ".missing"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "could not read value of --token: ")
+}
@@ -0,0 +1,38 @@
+package kingpin
+
+import "flag"
+
+// ImportFlagSet defines a flag for each flag in the standard library
+// flag.FlagSet fs. The new flags share the backing values of the originals, so
+// libraries that read their configuration from fs see the parsed values.
+//
+// Single character flag names are also registered as short flags.
+func (f *flagGroup) ImportFlagSet(fs *flag.FlagSet) {
+	fs.VisitAll(func(fl *flag.Flag) {
+		clause := f.Flag(fl.Name, fl.Usage)
+		if len(fl.Name) == 1 {
+			clause.Short(fl.Name[0])
+		}
+		clause.SetValue(fl.Value)
+	})
+}
+
+// getterValue adapts a Value that does not implement Getter.
+type getterValue struct {
+	Value
+}
+
+func (g *getterValue) Get() interface{} { return g.Value.String() }
+
+// GetValue returns the flag's value as a standard library flag.Getter. All
+// Value types provided by this package implement flag.Getter directly; other
+// values are adapted to return their string representation from Get().
+func (f *FlagClause) GetValue() flag.Getter {
+	if getter, ok := f.value.(flag.Getter); ok {
+		return getter
+	}
+	if f.value == nil {
+		return nil
+	}
+	return &getterValue{f.value}
+}
@@ -0,0 +1,60 @@
+package kingpin
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("lib", flag.ContinueOnError)
+	verbosity := fs.Int("v", 0, "Log verbosity.")
+	logtostderr := fs.Bool("logtostderr", false, "Log to stderr.")
+	timeout := fs.Duration("timeout", time.Second, "Timeout.")
+
+	app := New("test", "")
+	app.ImportFlagSet(fs)
+	_, err := app.Parse([]string{"-v", "2", "--logtostderr"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *verbosity)
+	assert.True(t, *logtostderr)
+	assert.Equal(t, time.Second, *timeout)
+	assert.Equal(t, "Log to stderr.", app.long["logtostderr"].help)
+}
+
+func TestBuiltinValuesImplementGetter(t *testing.T) {
+	p := &parserMixin{}
+	setters := []func(){
+		func() { p.String() }, func() { p.Strings() }, func() { p.StringMap() },
+		func() { p.Bool() }, func() { p.Int() }, func() { p.Int64() },
+		func() { p.Uint64() }, func() { p.Float() }, func() { p.Duration() },
+		func() { p.Bytes() }, func() { p.IP() }, func() { p.TCP() },
+		func() { p.TCPList() }, func() { p.ExistingFile() }, func() { p.ExistingDir() },
+		func() { p.File() }, func() { p.URL() }, func() { p.URLList() },
+		func() { p.ReaderArg() }, func() { p.Time(time.Kitchen) }, func() { p.TimeOfDay() },
+		func() { p.Location() }, func() { p.Percent() },
+		func() { p.BigInt() }, func() { p.BigFloat() }, func() { p.Decimal() },
+		func() { p.UDPAddr() }, func() { p.UDPAddrList() }, func() { p.GlobFiles() },
+		func() { p.ExistingDirTree("") }, func() { p.Template() },
+		func() { p.JSON(&struct{}{}) }, func() { p.StrictJSON(&struct{}{}) },
+		func() { p.YAML(&struct{}{}) }, func() { p.StrictYAML(&struct{}{}) },
+		func() { p.Base64Bytes() }, func() { p.HexBytes() }, func() { p.CronSpec() },
+		func() { p.Enum("a") }, func() { p.Enums("a") }, func() { p.LogLevel() },
+	}
+	for _, set := range setters {
+		set()
+		_, ok := p.value.(flag.Getter)
+		assert.True(t, ok, "%T does not implement flag.Getter", p.value)
+	}
+}
+
+func TestFlagGetValue(t *testing.T) {
+	app := New("test", "")
+	f := app.Flag("n", "")
+	f.Int()
+	_, err := app.Parse([]string{"--n=3"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, f.GetValue().Get())
+}
@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package kingpin
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzParseArgs(f *testing.F) {
+	f.Add("--debug cmd --name=alec -v arg1 arg2")
+	f.Add("-n alec --no-debug cmd -- -x")
+	f.Add("help cmd")
+	f.Fuzz(func(t *testing.T, line string) {
+		app := New("test", "").Version("1.0")
+		app.Flag("debug", "").Bool()
+		cmd := app.Command("cmd", "")
+		cmd.Flag("name", "").Short('n').Default("x").String()
+		cmd.Flag("verbose", "").Short('v').Bool()
+		cmd.Arg("args", "").Strings()
+		app.ParseArgs(strings.Fields(line))
+	})
+}
@@ -0,0 +1,97 @@
+//go:build go1.18
+// +build go1.18
+
+package kingpin
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/alecthomas/units"
+)
+
+// Parseable is the set of types supported by FlagOf() and ArgOf().
+type Parseable interface {
+	string | []string | bool | int | int64 | uint64 | float64 |
+		time.Duration | units.Base2Bytes | net.IP
+}
+
+type flagDefiner interface {
+	Flag(name, help string) *FlagClause
+}
+
+type argDefiner interface {
+	Arg(name, help string) *ArgClause
+}
+
+// -- generic Value backed by a parse function
+type funcValue[T any] struct {
+	target *T
+	parse  func(string) (T, error)
+}
+
+func newFuncValue[T any](target *T, parse func(string) (T, error)) *funcValue[T] {
+	return &funcValue[T]{target, parse}
+}
+
+func (f *funcValue[T]) Set(s string) error {
+	v, err := f.parse(s)
+	if err != nil {
+		return err
+	}
+	*f.target = v
+	return nil
+}
+
+func (f *funcValue[T]) Get() interface{} { return *f.target }
+
+func (f *funcValue[T]) String() string { return fmt.Sprintf("%v", *f.target) }
+
+func (f *funcValue[T]) clone() Value {
+	target := *f.target
+	return &funcValue[T]{&target, f.parse}
+}
+
+func (f *funcValue[T]) reset() {
+	var zero T
+	*f.target = zero
+}
+
+// FlagValue sets the value of a flag to one parsed by parse. eg.
+//
+//	level := kingpin.FlagValue(app.Flag("level", "Log level."), logrus.ParseLevel)
+func FlagValue[T any](f *FlagClause, parse func(string) (T, error)) (target *T) {
+	target = new(T)
+	f.SetValue(newFuncValue(target, parse))
+	return
+}
+
+// ArgValue sets the value of an argument to one parsed by parse.
+func ArgValue[T any](a *ArgClause, parse func(string) (T, error)) (target *T) {
+	target = new(T)
+	a.SetValue(newFuncValue(target, parse))
+	return
+}
+
+// FlagOf defines a new flag on an Application or CmdClause, returning a
+// pointer to its typed value. eg.
+//
+//	count := kingpin.FlagOf[int](app, "count", "Number of packets.")
+func FlagOf[T Parseable](group flagDefiner, name, help string) (target *T) {
+	target = new(T)
+	if err := bindField(&group.Flag(name, help).parserMixin, target); err != nil {
+		panic(err)
+	}
+	return
+}
+
+// ArgOf defines a new positional argument on an Application or CmdClause,
+// returning a pointer to its typed value.
+func ArgOf[T Parseable](group argDefiner, name, help string) (target *T) {
+	target = new(T)
+	if err := bindField(&group.Arg(name, help).parserMixin, target); err != nil {
+		panic(err)
+	}
+	return
+}
@@ -0,0 +1,41 @@
+//go:build go1.18
+// +build go1.18
+
+package kingpin
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagValue(t *testing.T) {
+	app := New("test", "")
+	v := FlagValue(app.Flag("n", ""), strconv.Atoi)
+	_, err := app.Parse([]string{"--n=12"})
+	assert.NoError(t, err)
+	assert.Equal(t, 12, *v)
+	_, err = app.Parse([]string{"--n=x"})
+	assert.Error(t, err)
+}
+
+func TestArgValue(t *testing.T) {
+	app := New("test", "")
+	v := ArgValue(app.Arg("b", ""), strconv.ParseBool)
+	_, err := app.Parse([]string{"true"})
+	assert.NoError(t, err)
+	assert.True(t, *v)
+}
+
+func TestFlagOf(t *testing.T) {
+	app := New("test", "")
+	timeout := FlagOf[time.Duration](app, "timeout", "")
+	cmd := app.Command("cmd", "")
+	names := ArgOf[[]string](cmd, "names", "")
+	_, err := app.Parse([]string{"--timeout=5s", "cmd", "a", "b"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, *timeout)
+	assert.Equal(t, []string{"a", "b"}, *names)
+}
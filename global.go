@@ -29,9 +29,9 @@ func Arg(name, help string) *ArgClause {
 // an error was encountered.
 func Parse() string {
 	selected := MustParse(CommandLine.Parse(os.Args[1:]))
-	if selected == "" && CommandLine.cmdGroup.have() {
+	if selected == "" && CommandLine.cmdGroup.have() && !CommandLine.argGroup.have() {
 		Usage()
-		os.Exit(0)
+		CommandLine.exit(HelpRequested)
 	}
 	return selected
 }
@@ -44,42 +44,56 @@ func ParseWithFileExpansion() string {
 		Fatalf("failed to expand flags: %s", err)
 	}
 	selected := MustParse(CommandLine.Parse(args))
-	if selected == "" && CommandLine.cmdGroup.have() {
+	if selected == "" && CommandLine.cmdGroup.have() && !CommandLine.argGroup.have() {
 		Usage()
-		os.Exit(0)
+		CommandLine.exit(HelpRequested)
 	}
 	return selected
 
 }
 
-// Fatalf prints an error message to stderr and exits.
+// Fatalf prints an error message to the error writer (stderr by default) and exits.
 func Fatalf(format string, args ...interface{}) {
-	CommandLine.Fatalf(os.Stderr, format, args...)
+	CommandLine.Fatalf(CommandLine.errorWriter, format, args...)
 }
 
 // FatalIfError prints an error and exits if err is not nil. The error is printed
 // with the given prefix.
 func FatalIfError(err error, prefix string) {
-	CommandLine.FatalIfError(os.Stderr, err, prefix)
+	CommandLine.FatalIfError(CommandLine.errorWriter, err, prefix)
 }
 
 // UsageErrorf prints an error message followed by usage information, then
 // exits with a non-zero status.
 func UsageErrorf(format string, args ...interface{}) {
-	CommandLine.UsageErrorf(os.Stderr, format, args...)
+	CommandLine.UsageErrorf(CommandLine.errorWriter, format, args...)
 }
 
-// Usage prints usage to stderr.
+// FatalUsage prints an error message followed by usage information, then
+// exits with a non-zero status.
+func FatalUsage(format string, args ...interface{}) {
+	CommandLine.FatalUsage(format, args...)
+}
+
+// FatalUsageContext prints an error message followed by usage information
+// for the command selected in context, then exits with a non-zero status.
+func FatalUsageContext(context *ParseContext, format string, args ...interface{}) {
+	CommandLine.FatalUsageContext(context, format, args...)
+}
+
+// Usage prints usage to the usage writer (stderr by default).
 func Usage() {
-	CommandLine.Usage(os.Stderr)
+	CommandLine.Usage(CommandLine.usageWriter)
 }
 
-// MustParse can be used with app.Parse(args) to exit with an error if parsing fails.
+// MustParse can be used with CommandLine.Parse(args) to exit with an error if
+// parsing fails. The error is reported with the settings of CommandLine, such
+// as its exit codes and error writer; use Application.MustParse() with other
+// applications.
+//
+//	command := kingpin.MustParse(kingpin.CommandLine.Parse(os.Args[1:]))
 func MustParse(command string, err error) string {
-	if err != nil {
-		Fatalf("%s, try --help", err)
-	}
-	return command
+	return CommandLine.MustParse(command, err)
 }
 
 // Version adds a flag for displaying the application version number.
@@ -4,6 +4,10 @@ package kingpin
 
 import "io"
 
+func guessHeight(w io.Writer) int {
+	return 0
+}
+
 func guessWidth(w io.Writer) int {
 	return 80
 }
@@ -10,6 +10,33 @@ import (
 	"unsafe"
 )
 
+// terminalSize returns the height and width of the terminal w is connected
+// to, if any.
+func terminalSize(w io.Writer) (height, width int, ok bool) {
+	t, ok := w.(*os.File)
+	if !ok {
+		return 0, 0, false
+	}
+	var dimensions [4]uint16
+	if _, _, err := syscall.Syscall6(
+		syscall.SYS_IOCTL,
+		t.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&dimensions)),
+		0, 0, 0,
+	); err != 0 {
+		return 0, 0, false
+	}
+	return int(dimensions[0]), int(dimensions[1]), true
+}
+
+// guessHeight returns the height of the terminal w is connected to, or 0 if
+// it is not a terminal.
+func guessHeight(w io.Writer) int {
+	height, _, _ := terminalSize(w)
+	return height
+}
+
 func guessWidth(w io.Writer) int {
 	// check if COLUMNS env is set to comply with
 	// http://pubs.opengroup.org/onlinepubs/009604499/basedefs/xbd_chap08.html
@@ -20,19 +47,8 @@ func guessWidth(w io.Writer) int {
 		}
 	}
 
-	if t, ok := w.(*os.File); ok {
-		fd := t.Fd()
-		var dimensions [4]uint16
-
-		if _, _, err := syscall.Syscall6(
-			syscall.SYS_IOCTL,
-			uintptr(fd),
-			uintptr(syscall.TIOCGWINSZ),
-			uintptr(unsafe.Pointer(&dimensions)),
-			0, 0, 0,
-		); err == 0 {
-			return int(dimensions[1])
-		}
+	if _, width, ok := terminalSize(w); ok {
+		return width
 	}
 	return 80
 }
@@ -0,0 +1,143 @@
+// Package kingpintest provides helpers for testing Kingpin applications.
+//
+// Run parses a command line with an application, injecting the environment
+// and standard input, and captures everything the application writes along
+// with its exit status, without ever terminating the test process:
+//
+//	result := kingpintest.Run(app, kingpintest.Input{
+//		Args: []string{"post", "--image", "owls.jpg", "pics"},
+//		Env:  map[string]string{"CHAT_SERVER": "10.0.0.1"},
+//	})
+//	if result.Err != nil {
+//		t.Fatal(result.Err)
+//	}
+//
+// Run modifies process-wide state (the environment and os.Stdin) while the
+// application is parsing, so tests using it must not run in parallel.
+package kingpintest
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/alecthomas/kingpin"
+)
+
+// Input to an application.
+type Input struct {
+	// Args are the command-line arguments, excluding the program name.
+	Args []string
+	// Env is set in the environment for the duration of the parse.
+	Env map[string]string
+	// Stdin is made available as os.Stdin for the duration of the parse, if
+	// not nil.
+	Stdin io.Reader
+}
+
+// Result of running an application.
+type Result struct {
+	// Command is the selected command, as returned by Parse().
+	Command string
+	// Context is the ParseContext, or nil if parsing failed.
+	Context *kingpin.ParseContext
+	// Err is the error returned by Parse(), if any.
+	Err error
+	// Stdout is everything written to the application's writer.
+	Stdout string
+	// Stderr is everything written to the application's error and usage
+	// writers.
+	Stderr string
+	// Exited is true if the application attempted to terminate.
+	Exited bool
+	// ExitCode is the status the application attempted to exit with.
+	ExitCode int
+}
+
+type exitSignal struct {
+	code int
+}
+
+// Run parses in.Args with app and returns the result.
+//
+// The application's writers and Terminate() function are replaced with ones
+// capturing output and the exit status until Run returns. An attempt to
+// terminate stops parsing immediately, as os.Exit() would.
+func Run(app *kingpin.Application, in Input) (result *Result) {
+	result = &Result{}
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	defer app.Capture(stdout, stderr, func(code int) { panic(exitSignal{code}) })()
+
+	defer setEnv(in.Env)()
+	if in.Stdin != nil {
+		restore, err := setStdin(in.Stdin)
+		if err != nil {
+			result.Err = err
+			return
+		}
+		defer restore()
+	}
+
+	defer func() {
+		result.Stdout = stdout.String()
+		result.Stderr = stderr.String()
+		if r := recover(); r != nil {
+			exit, ok := r.(exitSignal)
+			if !ok {
+				panic(r)
+			}
+			result.Exited = true
+			result.ExitCode = exit.code
+		}
+	}()
+
+	result.Context, result.Err = app.ParseContext(in.Args)
+	if result.Context != nil && result.Context.SelectedCommand != nil {
+		result.Command = result.Context.SelectedCommand.FullCommand()
+	}
+	return
+}
+
+// setEnv sets each variable in env, returning a function that restores the
+// previous environment.
+func setEnv(env map[string]string) func() {
+	type previous struct {
+		value string
+		ok    bool
+	}
+	saved := map[string]previous{}
+	for k, v := range env {
+		old, ok := os.LookupEnv(k)
+		saved[k] = previous{old, ok}
+		os.Setenv(k, v)
+	}
+	return func() {
+		for k, p := range saved {
+			if p.ok {
+				os.Setenv(k, p.value)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
+
+// setStdin replaces os.Stdin with a pipe fed from r, returning a function
+// that restores the original.
+func setStdin(r io.Reader) (func(), error) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		io.Copy(pw, r)
+		pw.Close()
+	}()
+	original := os.Stdin
+	os.Stdin = pr
+	return func() {
+		os.Stdin = original
+		pr.Close()
+	}, nil
+}
@@ -0,0 +1,77 @@
+package kingpintest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kingpin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommand(t *testing.T) {
+	app := kingpin.New("test", "")
+	cmd := app.Command("cmd", "")
+	name := cmd.Flag("name", "").OverrideDefaultFromEnvar("KINGPINTEST_NAME").String()
+	result := Run(app, Input{
+		Args: []string{"cmd"},
+		Env:  map[string]string{"KINGPINTEST_NAME": "alec"},
+	})
+	assert.NoError(t, result.Err)
+	assert.False(t, result.Exited)
+	assert.Equal(t, "cmd", result.Command)
+	assert.Equal(t, "alec", *name)
+	_, ok := os.LookupEnv("KINGPINTEST_NAME")
+	assert.False(t, ok)
+}
+
+func TestRunCapturesExit(t *testing.T) {
+	app := kingpin.New("test", "").Version("1.0.0")
+	result := Run(app, Input{Args: []string{"--version"}})
+	assert.True(t, result.Exited)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "1.0.0\n", result.Stdout)
+
+	app = kingpin.New("test", "")
+	result = Run(app, Input{Args: []string{"--help"}})
+	assert.True(t, result.Exited)
+	assert.Contains(t, result.Stderr, "usage: test")
+}
+
+func TestRunError(t *testing.T) {
+	app := kingpin.New("test", "")
+	result := Run(app, Input{Args: []string{"--unknown"}})
+	assert.Error(t, result.Err)
+	assert.Nil(t, result.Context)
+	assert.False(t, result.Exited)
+}
+
+func TestRunStdin(t *testing.T) {
+	app := kingpin.New("test", "")
+	var read string
+	app.Flag("read", "").Dispatch(func(*kingpin.ParseContext) error {
+		b, err := ioutil.ReadAll(os.Stdin)
+		read = string(b)
+		return err
+	}).Bool()
+	stdin := os.Stdin
+	result := Run(app, Input{Args: []string{"--read"}, Stdin: strings.NewReader("hello")})
+	assert.NoError(t, result.Err)
+	assert.Equal(t, "hello", read)
+	assert.Equal(t, stdin, os.Stdin)
+}
+
+func TestRunRestoresApplication(t *testing.T) {
+	out := &bytes.Buffer{}
+	status := -1
+	app := kingpin.New("test", "").Version("1.0.0").Writer(out).Terminate(func(code int) { status = code })
+	result := Run(app, Input{Args: []string{"--version"}})
+	assert.True(t, result.Exited)
+	assert.Equal(t, "", out.String())
+
+	app.Parse([]string{"--version"})
+	assert.Equal(t, "1.0.0\n", out.String())
+	assert.Equal(t, 0, status)
+}
@@ -2,18 +2,28 @@ package kingpin
 
 import (
 	"bufio"
+	"errors"
 	"os"
-
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// TokenType is the type of a Token.
 type TokenType int
 
 // Token types.
 const (
+	// TokenShort is a single short flag character, without the leading "-".
+	// Combined short flags such as "-abc" produce one token per character.
 	TokenShort TokenType = iota
+	// TokenLong is a long flag name, without the leading "--" or any
+	// "=<value>" suffix.
 	TokenLong
+	// TokenArg is a positional argument, command name, flag value or any
+	// argument following "--".
 	TokenArg
+	// TokenEOL marks the end of the input.
 	TokenEOL
 )
 
@@ -21,11 +31,22 @@ var (
 	TokenEOLMarker = Token{TokenEOL, ""}
 )
 
+// A Token is a single lexical element of the command line. "--" separators
+// do not produce tokens.
 type Token struct {
 	Type  TokenType
 	Value string
 }
 
+// A TokenPosition locates a Token within the original command-line
+// arguments.
+type TokenPosition struct {
+	// Arg is the index of the argument the token was read from.
+	Arg int
+	// Offset is the byte offset of the token's value within the argument.
+	Offset int
+}
+
 func (t *Token) IsFlag() bool {
 	return t.Type == TokenShort || t.Type == TokenLong
 }
@@ -59,6 +80,15 @@ func (t Tokens) String() string {
 	return strings.Join(out, " ")
 }
 
+// Strings returns the string form of each token.
+func (t Tokens) Strings() []string {
+	out := []string{}
+	for _, tok := range t {
+		out = append(out, tok.String())
+	}
+	return out
+}
+
 func (t Tokens) Next() Tokens {
 	if len(t) == 0 {
 		return nil
@@ -80,33 +110,166 @@ func (t Tokens) Peek() *Token {
 	return t[0]
 }
 
+// Tokenize splits command-line arguments into tokens, returning a
+// ParseContext ready for parsing.
 func Tokenize(args []string) *ParseContext {
-	tokens := make(Tokens, 0, len(args))
-	allowFlags := true
-	for _, arg := range args {
-		if allowFlags {
-			if arg == "--" {
-				allowFlags = false
-				continue
+	tokens, positions, indexes := tokenize(args, nil)
+	return &ParseContext{
+		Tokens:    tokens,
+		args:      args,
+		allTokens: tokens,
+		positions: positions,
+		indexes:   indexes,
+	}
+}
+
+// A slashFlagFunc resolves the name of a flag given as "/name", returning
+// the type and value of the equivalent token, or false if name is not a
+// flag.
+type slashFlagFunc func(name string) (TokenType, string, bool)
+
+// tokenize returns the tokens of args, their positions and a map from each
+// token to its index in the tokens, so that positions can be found quickly.
+func tokenize(args []string, slashFlag slashFlagFunc) (Tokens, []TokenPosition, map[*Token]int) {
+	t := &tokenizer{
+		store:      make([]Token, 0, len(args)),
+		tokens:     make(Tokens, 0, len(args)),
+		positions:  make([]TokenPosition, 0, len(args)),
+		indexes:    make(map[*Token]int, len(args)),
+		allowFlags: true,
+		slashFlag:  slashFlag,
+	}
+	for i, arg := range args {
+		t.tokenizeArg(i, arg)
+	}
+	return t.tokens, t.positions, t.indexes
+}
+
+// tokenizer accumulates tokens and their positions. Tokens are allocated in
+// blocks from store rather than individually. When store grows, tokens
+// already handed out continue to refer to the previous block, which is fine
+// as tokens are never modified.
+type tokenizer struct {
+	store      []Token
+	tokens     Tokens
+	positions  []TokenPosition
+	indexes    map[*Token]int
+	allowFlags bool
+	slashFlag  slashFlagFunc
+}
+
+func (t *tokenizer) add(index, offset int, typ TokenType, value string) {
+	t.store = append(t.store, Token{typ, value})
+	t.tokens = append(t.tokens, &t.store[len(t.store)-1])
+	t.indexes[&t.store[len(t.store)-1]] = len(t.tokens) - 1
+	t.positions = append(t.positions, TokenPosition{Arg: index, Offset: offset})
+}
+
+func (t *tokenizer) tokenizeArg(index int, arg string) {
+	if t.allowFlags {
+		if arg == "--" {
+			t.allowFlags = false
+			return
+		}
+		if strings.HasPrefix(arg, "--") {
+			name := arg[2:]
+			if i := strings.IndexByte(name, '='); i != -1 {
+				t.add(index, 2, TokenLong, name[:i])
+				t.add(index, 3+i, TokenArg, name[i+1:])
+			} else {
+				t.add(index, 2, TokenLong, name)
 			}
-			if strings.HasPrefix(arg, "--") {
-				parts := strings.SplitN(arg[2:], "=", 2)
-				tokens = append(tokens, &Token{TokenLong, parts[0]})
-				if len(parts) == 2 {
-					tokens = append(tokens, &Token{TokenArg, parts[1]})
+			return
+		}
+		// A lone "-" is an argument, conventionally meaning stdin.
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			for offset, a := range arg[1:] {
+				start := 1 + offset
+				if a == utf8.RuneError {
+					t.add(index, start, TokenShort, string(a))
+				} else {
+					// Slice rather than convert, to avoid an allocation.
+					t.add(index, start, TokenShort, arg[start:start+utf8.RuneLen(a)])
 				}
-				continue
 			}
-			if strings.HasPrefix(arg, "-") {
-				for _, a := range arg[1:] {
-					tokens = append(tokens, &Token{TokenShort, string(a)})
-				}
-				continue
+			return
+		}
+		if t.slashFlag != nil && t.tokenizeSlashFlag(index, arg) {
+			return
+		}
+	}
+	t.add(index, 0, TokenArg, arg)
+}
+
+// tokenizeSlashFlag tokenizes "/name" and "/name:value" forms, returning
+// false if arg does not name a flag, eg. if it is an absolute path.
+func (t *tokenizer) tokenizeSlashFlag(index int, arg string) bool {
+	if !strings.HasPrefix(arg, "/") {
+		return false
+	}
+	name, value := arg[1:], ""
+	i := strings.IndexByte(name, ':')
+	if i != -1 {
+		name, value = name[:i], name[i+1:]
+	}
+	typ, name, ok := t.slashFlag(name)
+	if !ok {
+		return false
+	}
+	t.add(index, 1, typ, name)
+	if i != -1 {
+		t.add(index, 2+i, TokenArg, value)
+	}
+	return true
+}
+
+// splitWords splits s into words as a POSIX shell would, without expansions.
+// Words are separated by white space, which may be included in a word by
+// quoting. Within single quotes every character is literal; within double
+// quotes a backslash escapes '"', '\\', '$' and '`'; elsewhere a backslash
+// escapes any character.
+func splitWords(s string) ([]string, error) {
+	words := []string{}
+	word := []rune{}
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				word = append(word, '\\')
+			}
+			word = append(word, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word = append(word, r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, string(word))
+				word = word[:0]
+				inWord = false
 			}
+		default:
+			word = append(word, r)
+			inWord = true
 		}
-		tokens = append(tokens, &Token{TokenArg, arg})
 	}
-	return &ParseContext{Tokens: tokens}
+	if escaped || quote != 0 {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return words, nil
 }
 
 // ExpandArgsFromFiles expands arguments in the form @<file> into one-arg-per-
@@ -37,3 +37,54 @@ func TestLexer(t *testing.T) {
 	assert.Equal(t, &Token{TokenArg, "-123"}, tok)
 	tokens = tokens.Next()
 }
+
+func TestTokenPositions(t *testing.T) {
+	context := Tokenize([]string{"-ab", "--foo=bar", "--", "--baz"})
+	tokens := context.AllTokens()
+	assert.Equal(t, []Token{
+		{TokenShort, "a"}, {TokenShort, "b"}, {TokenLong, "foo"}, {TokenArg, "bar"}, {TokenArg, "--baz"},
+	}, tokens)
+	assert.Equal(t, TokenPosition{0, 2}, context.Position(1))
+	assert.Equal(t, TokenPosition{1, 6}, context.Position(3))
+	assert.Equal(t, TokenPosition{3, 0}, context.Position(4))
+}
+
+func TestTokenizeSlashFlags(t *testing.T) {
+	slashFlag := func(name string) (TokenType, string, bool) {
+		return TokenLong, name, name == "out"
+	}
+	tokens, positions, _ := tokenize([]string{"/out:C:\\x", "/tmp", "/out", "--", "/out"}, slashFlag)
+	assert.Equal(t, "--out C:\\x /tmp --out /out", tokens.String())
+	assert.Equal(t, []TokenPosition{{0, 1}, {0, 5}, {1, 0}, {2, 1}, {4, 0}}, positions)
+}
+
+var benchmarkArgs = []string{
+	"--debug", "--server=10.0.0.1", "-vvx", "post", "--image", "owls.jpg",
+	"--channel=pics", "-t", "5s", "--no-notify", "--", "-not-a-flag", "text",
+}
+
+func BenchmarkTokenize(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Tokenize(benchmarkArgs)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected []string
+	}{
+		{"", []string{}},
+		{"  -v   --level=2 ", []string{"-v", "--level=2"}},
+		{`--name='a b' "c d"`, []string{"--name=a b", "c d"}},
+		{`a\ b '\n' "\"\n"`, []string{"a b", `\n`, `"\n`}},
+		{`'' ""`, []string{"", ""}},
+	} {
+		words, err := splitWords(test.input)
+		assert.NoError(t, err, test.input)
+		assert.Equal(t, test.expected, words, test.input)
+	}
+	_, err := splitWords(`--name="a b`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,198 @@
+package kingpin
+
+import "fmt"
+
+// Messages is a catalog of the user-facing messages produced while parsing
+// and displaying usage. Each message is a fmt format string, and receives the
+// same arguments as the corresponding entry in DefaultMessages. Messages that
+// receive no arguments, such as section titles, are used verbatim. This
+// includes the errors of the built-in values, such as "expected integer".
+//
+// To rephrase or translate messages, copy DefaultMessages, modify the copy
+// and pass it to Application.Messages().
+type Messages struct {
+	UnknownLongFlag      string // Flag token.
+	UnknownShortFlag     string // Flag token.
+	ExpectedFlagArgument string // Flag token.
+	InvalidBoolFlag      string // Flag token, value.
+	RequiredFlag         string // Flag name.
+	RequiredFlags        string // Comma separated list of flags.
+	InvalidFlagDefault   string // Flag name, error.
+	FileRefFailed        string // Flag name, error.
+	DotEnvFailed         string // Error.
+	InvalidOptions       string // Environment variable, error.
+	InvalidSecret        string // Flag name.
+	PasswordPrompt       string // Flag name.
+	RequiredArg          string // Argument name.
+	ExpectedPositional   string // Argument name, token.
+	InvalidArgDefault    string // Default value, argument name, error.
+	InvalidArg           string // Argument name, error.
+	DefaultFuncFailed    string // Flag or argument, error.
+	TooFewOccurrences    string // Flag or argument, minimum.
+	TooManyOccurrences   string // Flag or argument, maximum.
+	ValueTooSmall        string // Flag or argument, minimum.
+	ValueTooLarge        string // Flag or argument, maximum.
+	ValueOutOfRange      string // Flag or argument, minimum, maximum.
+	ValueTooShort        string // Flag or argument, minimum length.
+	ValueTooLong         string // Flag or argument, maximum length.
+	ValueMismatch        string // Flag or argument, regular expression.
+	ValueNotAllowed      string // Flag or argument, comma separated list of values.
+	ExpectedInteger      string // Value.
+	ExpectedNumber       string // Value.
+	ExpectedDecimal      string // Value.
+	ExpectedPercent      string // Value.
+	PercentOutOfRange    string // Value.
+	ExpectedBase64       string // Value.
+	ExpectedHex          string // Value.
+	ExpectedTime         string // Layout, value.
+	UnknownTimeZone      string // Value.
+	ExpectedTimeOfDay    string // Value.
+	ExpectedKeyValue     string // Value.
+	InvalidIP            string // Value.
+	InvalidTCPAddress    string // Value, error.
+	InvalidUDPAddress    string // Value, error.
+	PathNotFound         string // Path.
+	PathIsDirectory      string // File name.
+	PathIsFile           string // File name.
+	InvalidPattern       string // Pattern, error.
+	NoMatchingFiles      string // Pattern.
+	InvalidTemplate      string // Error.
+	InvalidJSON          string // Error.
+	InvalidYAML          string // Error.
+	InvalidURL           string // Error.
+	InvalidEnum          string // Comma separated list of values, value.
+	InvalidLogLevel      string // Comma separated list of levels, value.
+	ExpectedCommand      string // Token.
+	NoSuchCommand        string // Token.
+	UnknownCommand       string // Command.
+	UnexpectedArgument   string // Token.
+	UnexpectedArguments  string // Tokens.
+	ErrorPrefix          string // Application name.
+	WarningPrefix        string // Application name.
+	DeprecatedFlag       string // Flag name, deprecation message.
+	RenamedFlag          string // Alternate flag name, flag name.
+	DeprecatedEnvar      string // Environment variable, flag name, deprecation message.
+	TryHelp              string // Error.
+	TryCommandHelp       string // Error, application name and command.
+	TemplateError        string // Error.
+	OneOfHelp            string // Comma separated list of values.
+	ConfirmPrompt        string // Question.
+	NotConfirmed         string // None.
+	UsagePrefix          string // None.
+	FlagsTitle           string // None.
+	GlobalFlagsTitle     string // None.
+	ArgsTitle            string // None.
+	CommandsTitle        string // None.
+	AuthorTitle          string // None.
+	HomepageTitle        string // None.
+	BugReportsTitle      string // None.
+}
+
+// DefaultMessages are the messages used unless overridden with
+// Application.Messages().
+var DefaultMessages = Messages{
+	UnknownLongFlag:      "unknown long flag '%s'",
+	UnknownShortFlag:     "unknown short flag '%s'",
+	ExpectedFlagArgument: "expected argument for flag '%s'",
+	InvalidBoolFlag:      "expected true or false for flag '%s' but got '%s'",
+	RequiredFlag:         "required flag --%s not provided",
+	RequiredFlags:        "required flags %s not provided",
+	InvalidFlagDefault:   "default value for --%s is invalid: %s",
+	FileRefFailed:        "could not read value of --%s: %s",
+	DotEnvFailed:         "could not load environment: %s",
+	InvalidOptions:       "invalid $%s: %s",
+	InvalidSecret:        "invalid value for --%s",
+	PasswordPrompt:       "Value for --%s: ",
+	RequiredArg:          "'%s' is required",
+	ExpectedPositional:   "expected positional arguments <%s> but got '%s'",
+	InvalidArgDefault:    "invalid default value '%s' for argument '%s': %s",
+	InvalidArg:           "invalid argument <%s>: %s",
+	DefaultFuncFailed:    "could not determine default for %s: %s",
+	TooFewOccurrences:    "%s requires at least %d value(s)",
+	TooManyOccurrences:   "%s accepts at most %d value(s)",
+	ValueTooSmall:        "%s must be at least %v",
+	ValueTooLarge:        "%s must be at most %v",
+	ValueOutOfRange:      "%s must be between %v and %v",
+	ValueTooShort:        "%s must be at least %d characters",
+	ValueTooLong:         "%s must be at most %d characters",
+	ValueMismatch:        "%s must match %s",
+	ValueNotAllowed:      "%s must be one of %s",
+	ExpectedInteger:      "expected integer but got '%s'",
+	ExpectedNumber:       "expected number but got '%s'",
+	ExpectedDecimal:      "expected decimal number but got '%s'",
+	ExpectedPercent:      "expected percentage such as 75%% but got '%s'",
+	PercentOutOfRange:    "percentage must be between 0%% and 100%% but got '%s'",
+	ExpectedBase64:       "expected base64 but got '%s'",
+	ExpectedHex:          "expected hex but got '%s'",
+	ExpectedTime:         "expected time in the form %s but got '%s'",
+	UnknownTimeZone:      "unknown time zone '%s'",
+	ExpectedTimeOfDay:    "expected time of day such as 14:30 but got '%s'",
+	ExpectedKeyValue:     "expected KEY=VALUE got '%s'",
+	InvalidIP:            "'%s' is not an IP address",
+	InvalidTCPAddress:    "'%s' is not a valid TCP address: %s",
+	InvalidUDPAddress:    "'%s' is not a valid UDP address: %s",
+	PathNotFound:         "path '%s' does not exist",
+	PathIsDirectory:      "'%s' is a directory",
+	PathIsFile:           "'%s' is a file",
+	InvalidPattern:       "invalid pattern '%s': %s",
+	NoMatchingFiles:      "no files match '%s'",
+	InvalidTemplate:      "invalid template: %s",
+	InvalidJSON:          "invalid JSON: %s",
+	InvalidYAML:          "invalid YAML: %s",
+	InvalidURL:           "invalid URL: %s",
+	InvalidEnum:          "enum value must be one of %s, got '%s'",
+	InvalidLogLevel:      "log level must be one of %s, got '%s'",
+	ExpectedCommand:      "expected command but got '%s'",
+	NoSuchCommand:        "no such command '%s'",
+	UnknownCommand:       "unknown command '%s'",
+	UnexpectedArgument:   "unexpected argument '%s'",
+	UnexpectedArguments:  "unexpected arguments '%s'",
+	ErrorPrefix:          "%s: error: ",
+	WarningPrefix:        "%s: warning: ",
+	DeprecatedFlag:       "flag --%s is deprecated: %s",
+	RenamedFlag:          "flag --%s is deprecated: use --%s instead",
+	DeprecatedEnvar:      "$%s sets deprecated flag --%s: %s",
+	TryHelp:              "%s, try --help",
+	TryCommandHelp:       "%s, try '%s %s --help'",
+	TemplateError:        "usage template failed: %s",
+	OneOfHelp:            "(one of %s)",
+	ConfirmPrompt:        "%s [y/N] ",
+	NotConfirmed:         "aborted",
+	UsagePrefix:          "usage: ",
+	FlagsTitle:           "Flags:",
+	GlobalFlagsTitle:     "Global flags:",
+	ArgsTitle:            "Args:",
+	CommandsTitle:        "Commands:",
+	AuthorTitle:          "Author:",
+	HomepageTitle:        "Homepage:",
+	BugReportsTitle:      "Report bugs to:",
+}
+
+// valueError is an error from a built-in value, formatted with the messages
+// of the application when reported by a parse.
+type valueError struct {
+	message func(*Messages) string
+	args    []interface{}
+}
+
+func valueErrorf(message func(*Messages) string, args ...interface{}) error {
+	return &valueError{message, args}
+}
+
+func (v *valueError) Error() string {
+	return fmt.Sprintf(v.message(&DefaultMessages), v.args...)
+}
+
+// localizeError formats err with messages if it is from a built-in value.
+func localizeError(err error, messages *Messages) error {
+	if v, ok := err.(*valueError); ok {
+		return fmt.Errorf(v.message(messages), v.args...)
+	}
+	return err
+}
+
+// Messages overrides the catalog of user-facing messages.
+func (a *Application) Messages(messages Messages) *Application {
+	a.messages = &messages
+	return a
+}
@@ -0,0 +1,85 @@
+package kingpin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomMessages(t *testing.T) {
+	messages := DefaultMessages
+	messages.RequiredFlag = "option --%s is mandatory"
+	app := New("test", "").Messages(messages)
+	app.Flag("name", "").Required().String()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "option --name is mandatory")
+	assert.Equal(t, "required flag --%s not provided", DefaultMessages.RequiredFlag)
+}
+
+func TestTranslatedValueErrors(t *testing.T) {
+	messages := DefaultMessages
+	messages.InvalidLogLevel = "niveau %[2]s inconnu (%[1]s)"
+	messages.PercentOutOfRange = "pourcentage %s hors limites"
+	app := New("test", "").Messages(messages)
+	app.Flag("level", "").LogLevel()
+	app.Flag("ratio", "").Percent()
+	_, err := app.Parse([]string{"--level=trace"})
+	assert.EqualError(t, err, "niveau trace inconnu (debug,info,warn,error)")
+	_, err = app.Parse([]string{"--ratio=150%"})
+	assert.EqualError(t, err, "pourcentage 150% hors limites")
+
+	messages.InvalidUDPAddress = "adresse UDP %s invalide : %s"
+	app.Messages(messages)
+	app.Flag("addr", "").Default("127.0.0.1:99999").UDPAddr()
+	errs := app.Check()
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "is invalid: adresse UDP 127.0.0.1:99999 invalide : ")
+	}
+}
+
+func TestTranslatedUsage(t *testing.T) {
+	messages := DefaultMessages
+	messages.UsagePrefix = "utilisation : "
+	messages.FlagsTitle = "Options :"
+	messages.ArgsTitle = "Arguments :"
+	messages.CommandsTitle = "Commandes :"
+	app := New("test", "").Messages(messages)
+	app.Flag("name", "").String()
+	cmd := app.Command("cmd", "")
+	cmd.Arg("file", "").String()
+
+	w := bytes.NewBuffer(nil)
+	app.usage(w, 80)
+	assert.Contains(t, w.String(), "utilisation : test")
+	assert.Contains(t, w.String(), "\nOptions :\n")
+	assert.Contains(t, w.String(), "\nCommandes :\n")
+	assert.NotContains(t, w.String(), "Flags:")
+	w.Reset()
+	app.commandUsage(w, cmd, 80)
+	assert.Contains(t, w.String(), "utilisation : test [<flags>] cmd")
+	assert.Contains(t, w.String(), "\nArguments :\n")
+}
+
+func TestMultibyteUsagePrefix(t *testing.T) {
+	messages := DefaultMessages
+	messages.UsagePrefix = "使用法: "
+	app := New("test", "").Messages(messages)
+	for _, name := range []string{"alpha", "bravo", "charlie", "delta", "echo", "foxtrot"} {
+		app.Flag(name, "").Required().String()
+	}
+
+	w := bytes.NewBuffer(nil)
+	app.writeHelp(40, w)
+	usage := strings.SplitN(w.String(), "\n\n", 2)[0]
+	lines := strings.Split(usage, "\n")
+	assert.True(t, len(lines) > 1)
+	assert.True(t, strings.HasPrefix(lines[0], "使用法: test "))
+	for _, line := range lines {
+		assert.True(t, displayWidth(line) <= 40, line)
+	}
+	for _, line := range lines[1:] {
+		assert.True(t, strings.HasPrefix(line, "        -"), line)
+	}
+}
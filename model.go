@@ -0,0 +1,250 @@
+package kingpin
+
+import "strings"
+
+// Data model for Kingpin applications. A model is an immutable snapshot of
+// the definition of an application, for use by external tools such as
+// documentation generators.
+
+type FlagModel struct {
+	Name     string
+	Help     string
+	Short    byte
+	Default  string
+	Defaults []string
+	// NoOptDefault is the value used when the flag is given without one, if
+	// OptionalValue is true.
+	NoOptDefault  string
+	OptionalValue bool
+	Envar         string
+	PlaceHolder   string
+	Required      bool
+	Hidden        bool
+	Boolean       bool
+	Value         string
+	Section       string
+	Persistent    bool
+	Password      bool
+	Choices       []string
+	// Source and Elements describe the value of the flag in a parse, in
+	// models returned by ParseContext.Model(). Elements are the strings
+	// parsed into the value, in order, and are nil for Password() flags.
+	Source   ValueSource
+	Elements []string
+}
+
+type FlagGroupModel struct {
+	Flags []*FlagModel
+}
+
+type ArgModel struct {
+	Name       string
+	Help       string
+	Default    string
+	Defaults   []string
+	Required   bool
+	Hidden     bool
+	Cumulative bool
+	Value      string
+	Choices    []string
+	// Source and Elements describe the value of the argument in a parse, in
+	// models returned by ParseContext.Model().
+	Source   ValueSource
+	Elements []string
+}
+
+type ArgGroupModel struct {
+	Args []*ArgModel
+}
+
+type CmdModel struct {
+	Name        string
+	Help        string
+	FullCommand string
+	Depth       int
+	*FlagGroupModel
+	*ArgGroupModel
+	*CmdGroupModel
+}
+
+type CmdGroupModel struct {
+	Commands []*CmdModel
+}
+
+type ApplicationModel struct {
+	Name       string
+	Help       string
+	Version    string
+	Author     string
+	Homepage   string
+	BugReports string
+	*FlagGroupModel
+	*ArgGroupModel
+	*CmdGroupModel
+}
+
+// Model returns a snapshot of the definition of the application, including
+// all flags, arguments and nested commands.
+func (a *Application) Model() *ApplicationModel {
+	return a.model(nil)
+}
+
+// Model returns a snapshot of the definition of the application that was
+// parsed, like Application.Model(), with the Source and Elements of each flag
+// and argument set from this parse. This allows, eg., a --show-config command
+// to display where every setting came from. It returns nil if the context
+// was not returned by parsing an application.
+func (p *ParseContext) Model() *ApplicationModel {
+	if p.app == nil {
+		return nil
+	}
+	p.snapshotLock.RLock()
+	defer p.snapshotLock.RUnlock()
+	return p.app.model(p)
+}
+
+func (a *Application) model(context *ParseContext) *ApplicationModel {
+	return &ApplicationModel{
+		Name:           a.Name,
+		Help:           a.Help,
+		Version:        a.version,
+		Author:         a.author,
+		Homepage:       a.homepage,
+		BugReports:     a.bugReports,
+		FlagGroupModel: a.flagGroup.model(context),
+		ArgGroupModel:  a.argGroup.model(context),
+		CmdGroupModel:  a.cmdGroup.model(context),
+	}
+}
+
+func (f *flagGroup) Model() *FlagGroupModel {
+	return f.model(nil)
+}
+
+func (f *flagGroup) model(context *ParseContext) *FlagGroupModel {
+	m := &FlagGroupModel{}
+	for _, flag := range f.flagOrder {
+		m.Flags = append(m.Flags, flag.model(context))
+	}
+	return m
+}
+
+func (f *FlagClause) Model() *FlagModel {
+	return f.model(nil)
+}
+
+func (f *FlagClause) model(context *ParseContext) *FlagModel {
+	m := &FlagModel{
+		Name:          f.name,
+		Help:          f.help,
+		Short:         f.shorthand,
+		Default:       strings.Join(f.defaultValues, ","),
+		Defaults:      append([]string(nil), f.defaultValues...),
+		Envar:         f.envar,
+		PlaceHolder:   f.placeholder,
+		Required:      f.required,
+		Hidden:        f.hidden,
+		Section:       f.section,
+		Persistent:    f.persistent,
+		Password:      f.password,
+		Choices:       append([]string(nil), f.oneOf...),
+		NoOptDefault:  f.noOptDefault,
+		OptionalValue: f.optionalValue,
+	}
+	if f.value != nil {
+		m.Value = context.valueOf(f, f.value).String()
+		if fb, ok := f.value.(boolFlag); ok {
+			m.Boolean = fb.IsBoolFlag()
+		}
+	}
+	if context != nil {
+		m.Source = context.sources[f]
+		m.Elements = append([]string(nil), context.elements[f]...)
+	}
+	if f.password {
+		m.Default = ""
+		m.Defaults = nil
+		m.Value = ""
+		m.Elements = nil
+	}
+	return m
+}
+
+func (a *argGroup) Model() *ArgGroupModel {
+	return a.model(nil)
+}
+
+func (a *argGroup) model(context *ParseContext) *ArgGroupModel {
+	m := &ArgGroupModel{}
+	for _, arg := range a.args {
+		m.Args = append(m.Args, arg.model(context))
+	}
+	return m
+}
+
+func (a *ArgClause) Model() *ArgModel {
+	return a.model(nil)
+}
+
+func (a *ArgClause) model(context *ParseContext) *ArgModel {
+	m := &ArgModel{
+		Name:       a.name,
+		Help:       a.help,
+		Default:    strings.Join(a.defaultValues, ","),
+		Defaults:   append([]string(nil), a.defaultValues...),
+		Required:   a.required,
+		Hidden:     a.hidden,
+		Cumulative: a.consumesRemainder(),
+		Choices:    append([]string(nil), a.oneOf...),
+	}
+	if a.value != nil {
+		m.Value = context.valueOf(a, a.value).String()
+	}
+	if context != nil {
+		m.Source = context.sources[a]
+		m.Elements = append([]string(nil), context.elements[a]...)
+	}
+	return m
+}
+
+func (c *cmdGroup) Model() *CmdGroupModel {
+	return c.model(nil)
+}
+
+func (c *cmdGroup) model(context *ParseContext) *CmdGroupModel {
+	m := &CmdGroupModel{}
+	for _, cmd := range c.commandOrder {
+		m.Commands = append(m.Commands, cmd.model(context))
+	}
+	return m
+}
+
+func (c *CmdClause) Model() *CmdModel {
+	return c.model(nil)
+}
+
+func (c *CmdClause) model(context *ParseContext) *CmdModel {
+	depth := 0
+	for p := c.parent; p != nil; p = p.parent {
+		depth++
+	}
+	return &CmdModel{
+		Name:           c.name,
+		Help:           c.help,
+		FullCommand:    c.FullCommand(),
+		Depth:          depth,
+		FlagGroupModel: c.flagGroup.model(context),
+		ArgGroupModel:  c.argGroup.model(context),
+		CmdGroupModel:  c.cmdGroup.model(context),
+	}
+}
+
+// FlattenedCommands returns all commands in the group, and their
+// sub-commands, depth first.
+func (c *CmdGroupModel) FlattenedCommands() (out []*CmdModel) {
+	for _, cmd := range c.Commands {
+		out = append(out, cmd)
+		out = append(out, cmd.FlattenedCommands()...)
+	}
+	return
+}
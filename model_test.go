@@ -0,0 +1,95 @@
+package kingpin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModel(t *testing.T) {
+	app := New("app", "An app.")
+	app.Flag("debug", "Debug.").Short('d').Bool()
+	cmd := app.Command("remote", "Remotes.")
+	add := cmd.Command("add", "Add a remote.")
+	add.Flag("fetch", "Fetch.").Default("true").Bool()
+	add.Arg("name", "Name.").Required().String()
+	add.Arg("urls", "URLs.").Strings()
+
+	m := app.Model()
+	assert.Equal(t, "app", m.Name)
+	assert.Equal(t, "help", m.Flags[0].Name)
+	assert.Equal(t, byte('d'), m.Flags[1].Short)
+	assert.True(t, m.Flags[1].Boolean)
+	assert.Equal(t, 1, len(m.Commands))
+
+	commands := m.FlattenedCommands()
+	assert.Equal(t, 2, len(commands))
+	addModel := commands[1]
+	assert.Equal(t, "remote add", addModel.FullCommand)
+	assert.Equal(t, 1, addModel.Depth)
+	assert.Equal(t, "true", addModel.Flags[1].Default)
+	assert.True(t, addModel.Args[0].Required)
+	assert.True(t, addModel.Args[1].Cumulative)
+
+	m.Flags[1].Name = "changed"
+	assert.Equal(t, "debug", app.Model().Flags[1].Name)
+}
+
+func TestModelMetadata(t *testing.T) {
+	app := New("app", "").Version("1.0").Author("Alec").Homepage("https://example.com").BugReports("https://example.com/issues")
+	m := app.Model()
+	assert.Equal(t, "1.0", m.Version)
+	assert.Equal(t, "Alec", m.Author)
+	assert.Equal(t, "https://example.com", m.Homepage)
+	assert.Equal(t, "https://example.com/issues", m.BugReports)
+}
+
+func TestModelIsACopy(t *testing.T) {
+	app := New("app", "")
+	flag := app.Flag("tag", "").Default("a", "b").OneOf("a", "b", "c")
+	flag.Strings()
+	arg := app.Arg("name", "").Default("web").OneOf("web", "db")
+	arg.String()
+
+	m := app.Model()
+	m.Flags[1].Defaults[0] = "changed"
+	m.Args[0].Defaults[0] = "changed"
+	assert.Equal(t, []string{"a", "b"}, flag.defaultValues)
+	assert.Equal(t, []string{"web"}, arg.defaultValues)
+
+	m.Flags[1].Choices[0] = "changed"
+	m.Args[0].Choices[0] = "changed"
+	assert.Equal(t, []string{"a", "b", "c"}, flag.oneOf)
+	assert.Equal(t, []string{"web", "db"}, arg.oneOf)
+}
+
+func TestParseContextModel(t *testing.T) {
+	os.Setenv("KINGPIN_TEST_MODEL", "from-env")
+	defer os.Unsetenv("KINGPIN_TEST_MODEL")
+	app := New("app", "")
+	app.Flag("tag", "").Strings()
+	app.Flag("region", "").OverrideDefaultFromEnvar("KINGPIN_TEST_MODEL").String()
+	app.Flag("secret", "").Password().String()
+	cmd := app.Command("run", "")
+	cmd.Arg("name", "").Default("web").String()
+
+	context, err := app.ParseContext([]string{"--tag=a", "--tag=b", "--secret=s", "run"})
+	assert.NoError(t, err)
+	m := context.Model()
+	assert.Equal(t, SourceCommandLine, m.Flags[1].Source)
+	assert.Equal(t, []string{"a", "b"}, m.Flags[1].Elements)
+	assert.Equal(t, SourceEnvar, m.Flags[2].Source)
+	assert.Equal(t, []string{"from-env"}, m.Flags[2].Elements)
+	assert.Equal(t, SourceCommandLine, m.Flags[3].Source)
+	assert.Nil(t, m.Flags[3].Elements)
+	run := m.Commands[1]
+	assert.Equal(t, SourceDefault, run.Args[0].Source)
+	assert.Equal(t, []string{"web"}, run.Args[0].Elements)
+
+	m.Flags[1].Elements[0] = "changed"
+	assert.Equal(t, []string{"a", "b"}, context.Elements(app.GetFlag("tag")))
+
+	assert.Equal(t, SourceNone, app.Model().Flags[1].Source)
+	assert.Nil(t, Tokenize(nil).Model())
+}
@@ -0,0 +1,121 @@
+package kingpin
+
+import (
+	"reflect"
+	"strings"
+)
+
+// NumberFormat describes how numbers are written in a locale.
+type NumberFormat struct {
+	// Grouping contains the characters accepted between groups of thousands.
+	Grouping string
+	// Decimal is the decimal separator.
+	Decimal rune
+}
+
+// Number formats of common locales.
+var (
+	NumberFormatEnglish = NumberFormat{Grouping: ",", Decimal: '.'}
+	NumberFormatGerman  = NumberFormat{Grouping: ".", Decimal: ','}
+	NumberFormatFrench  = NumberFormat{Grouping: " \u00a0\u202f", Decimal: ','}
+	NumberFormatSwiss   = NumberFormat{Grouping: "'\u2019", Decimal: '.'}
+)
+
+// numberFormats maps languages, and locales that differ from their language,
+// to number formats.
+var numberFormats = map[string]NumberFormat{
+	"en": NumberFormatEnglish, "ja": NumberFormatEnglish, "ko": NumberFormatEnglish,
+	"zh": NumberFormatEnglish, "he": NumberFormatEnglish, "th": NumberFormatEnglish,
+	"de": NumberFormatGerman, "da": NumberFormatGerman, "el": NumberFormatGerman,
+	"es": NumberFormatGerman, "id": NumberFormatGerman, "it": NumberFormatGerman,
+	"nl": NumberFormatGerman, "pt": NumberFormatGerman, "ro": NumberFormatGerman,
+	"tr": NumberFormatGerman, "fr": NumberFormatFrench, "cs": NumberFormatFrench,
+	"fi": NumberFormatFrench, "hu": NumberFormatFrench, "nb": NumberFormatFrench,
+	"pl": NumberFormatFrench, "ru": NumberFormatFrench, "sk": NumberFormatFrench,
+	"sv": NumberFormatFrench, "uk": NumberFormatFrench, "de-CH": NumberFormatSwiss,
+	"it-CH": NumberFormatSwiss, "fr-CH": NumberFormatSwiss,
+}
+
+// LocaleNumberFormat returns the number format of a locale, such as "de",
+// "de-CH" or a POSIX locale such as "de_CH.UTF-8" from $LANG. The English
+// format is returned for unknown locales.
+func LocaleNumberFormat(locale string) NumberFormat {
+	if i := strings.IndexAny(locale, ".@"); i != -1 {
+		locale = locale[:i]
+	}
+	locale = strings.Replace(locale, "_", "-", -1)
+	if format, ok := numberFormats[locale]; ok {
+		return format
+	}
+	if i := strings.IndexByte(locale, '-'); i != -1 {
+		locale = locale[:i]
+	}
+	if format, ok := numberFormats[strings.ToLower(locale)]; ok {
+		return format
+	}
+	return NumberFormatEnglish
+}
+
+// NumberFormat accepts numbers given to the built-in numeric values on the
+// command line or in the environment in the given format, eg. "1.234,5" with
+// NumberFormatGerman. Numbers in the usual Go syntax are still accepted where
+// they are not ambiguous, and defaults are always in the Go syntax.
+func (a *Application) NumberFormat(format NumberFormat) *Application {
+	a.numberFormat = &format
+	return a
+}
+
+// normalize converts a number in the format to Go syntax. Strings in which
+// the thousands are not grouped correctly are returned unchanged.
+func (n *NumberFormat) normalize(s string) string {
+	out := strings.Builder{}
+	digits := 0 // Since the last separator.
+	grouped := false
+	decimal := false
+	for _, r := range s {
+		switch {
+		case r == n.Decimal && !decimal:
+			if grouped && digits != 3 {
+				return s
+			}
+			grouped, decimal = false, true
+			out.WriteByte('.')
+		case strings.ContainsRune(n.Grouping, r) && !decimal:
+			if digits == 0 || digits > 3 || (grouped && digits != 3) {
+				return s
+			}
+			grouped, digits = true, 0
+		case r >= '0' && r <= '9':
+			digits++
+			out.WriteRune(r)
+		default:
+			if grouped && digits != 3 {
+				return s
+			}
+			grouped, digits = false, 0
+			out.WriteRune(r)
+		}
+	}
+	if grouped && digits != 3 {
+		return s
+	}
+	return out.String()
+}
+
+// isNumeric returns true if value is one of the built-in numeric values, or
+// accumulates numbers.
+func isNumeric(value Value) bool {
+	switch v := value.(type) {
+	case *intValue, *int64Value, *uintValue, *uint64Value, *float64Value, *percentValue,
+		*bigIntValue, *bigFloatValue, *decimalValue:
+		return true
+	case *cumulativeValue:
+		return isNumeric(v.Value)
+	case *accumulator:
+		switch v.typ.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Float64:
+			return true
+		}
+	}
+	return false
+}
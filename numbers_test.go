@@ -0,0 +1,65 @@
+package kingpin
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberFormatNormalize(t *testing.T) {
+	tests := []struct {
+		format   NumberFormat
+		in, want string
+	}{
+		{NumberFormatEnglish, "1,234,567.5", "1234567.5"},
+		{NumberFormatEnglish, "-1,234", "-1234"},
+		{NumberFormatEnglish, "1,23", "1,23"},
+		{NumberFormatEnglish, "1234,567", "1234,567"},
+		{NumberFormatGerman, "1.234,5", "1234.5"},
+		{NumberFormatGerman, "0,25", "0.25"},
+		{NumberFormatGerman, "1.5", "1.5"},
+		{NumberFormatGerman, "1.500", "1500"},
+		{NumberFormatFrench, "1 234,5", "1234.5"},
+		{NumberFormatFrench, "12 345", "12345"},
+		{NumberFormatSwiss, "1'234.5", "1234.5"},
+		{NumberFormatEnglish, "0x1F", "0x1F"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.want, test.format.normalize(test.in), test.in)
+	}
+}
+
+func TestLocaleNumberFormat(t *testing.T) {
+	assert.Equal(t, NumberFormatGerman, LocaleNumberFormat("de_DE.UTF-8"))
+	assert.Equal(t, NumberFormatSwiss, LocaleNumberFormat("de_CH.UTF-8"))
+	assert.Equal(t, NumberFormatFrench, LocaleNumberFormat("fr"))
+	assert.Equal(t, NumberFormatEnglish, LocaleNumberFormat("C"))
+	assert.Equal(t, NumberFormatEnglish, LocaleNumberFormat(""))
+}
+
+func TestAppNumberFormat(t *testing.T) {
+	app := New("test", "").NumberFormat(NumberFormatGerman)
+	ratioFlag := app.Flag("ratio", "").Default("0.5")
+	ratio := ratioFlag.Float()
+	count := app.Flag("count", "").OverrideDefaultFromEnvar("KINGPIN_TEST_COUNT").Int()
+	sizes := app.Flag("size", "").Ints()
+	name := app.Flag("name", "").String()
+
+	_, err := app.Parse([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, *ratio)
+
+	os.Setenv("KINGPIN_TEST_COUNT", "12.000")
+	defer os.Unsetenv("KINGPIN_TEST_COUNT")
+	context, err := app.ParseContext([]string{"--ratio=1.234,5", "--size=1.000", "--size=2", "--name=1.000"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1234.5, *ratio)
+	assert.Equal(t, 12000, *count)
+	assert.Equal(t, []int{1000, 2}, *sizes)
+	assert.Equal(t, "1.000", *name)
+	assert.Equal(t, []string{"1234.5"}, context.Elements(ratioFlag))
+
+	_, err = app.Parse([]string{"--count=1.00"})
+	assert.Error(t, err)
+}
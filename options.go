@@ -0,0 +1,71 @@
+package kingpin
+
+// A FlagOption configures a flag created with NewFlag(). The options are
+// prefixed with "With" so that they do not clash with other package-level
+// names.
+type FlagOption func(*FlagClause)
+
+// NewFlag creates a flag from a set of options, for later addition to an
+// Application or CmdClause with AddFlag(). This is an alternative to the
+// fluent API for code that builds flag definitions programmatically. eg.
+//
+//	app.AddFlag(kingpin.NewFlag("name", kingpin.WithHelp("Name."), kingpin.WithShort('n'), kingpin.WithRequired()))
+//
+// If no WithValue() option is provided, the flag is a string flag.
+func NewFlag(name string, options ...FlagOption) *FlagClause {
+	f := newFlag(name, "")
+	for _, option := range options {
+		option(f)
+	}
+	if f.value == nil {
+		f.String()
+	}
+	return f
+}
+
+// AddFlag adds a flag created by NewFlag().
+func (f *flagGroup) AddFlag(flag *FlagClause) *FlagClause {
+	f.long[flag.name] = flag
+	f.flagOrder = append(f.flagOrder, flag)
+	return flag
+}
+
+// WithHelp sets the help text of a flag.
+func WithHelp(help string) FlagOption {
+	return func(f *FlagClause) { f.help = help }
+}
+
+// WithShort sets the short name of a flag.
+func WithShort(name byte) FlagOption {
+	return func(f *FlagClause) { f.Short(name) }
+}
+
+// WithRequired makes a flag required.
+func WithRequired() FlagOption {
+	return func(f *FlagClause) { f.Required() }
+}
+
+// WithDefault sets the default value, or values, of a flag.
+func WithDefault(values ...string) FlagOption {
+	return func(f *FlagClause) { f.Default(values...) }
+}
+
+// WithHidden hides a flag from usage.
+func WithHidden() FlagOption {
+	return func(f *FlagClause) { f.Hidden() }
+}
+
+// WithPlaceHolder sets the place-holder string used for a flag's value in help.
+func WithPlaceHolder(placeholder string) FlagOption {
+	return func(f *FlagClause) { f.PlaceHolder(placeholder) }
+}
+
+// WithEnvar overrides the default value of a flag from an environment variable.
+func WithEnvar(envar string) FlagOption {
+	return func(f *FlagClause) { f.OverrideDefaultFromEnvar(envar) }
+}
+
+// WithValue sets the Value a flag parses into.
+func WithValue(value Value) FlagOption {
+	return func(f *FlagClause) { f.SetValue(value) }
+}
@@ -0,0 +1,37 @@
+package kingpin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperValue struct{ s *string }
+
+func (u upperValue) Set(value string) error { *u.s = strings.ToUpper(value); return nil }
+func (u upperValue) String() string         { return *u.s }
+
+func TestNewFlag(t *testing.T) {
+	app := New("test", "")
+	mode := ""
+	app.AddFlag(NewFlag("mode", WithHelp("Mode."), WithShort('m'), WithDefault("fast"), WithValue(upperValue{&mode})))
+	app.AddFlag(NewFlag("name", WithRequired(), WithEnvar("TEST_NAME")))
+	app.AddFlag(NewFlag("secret", WithHidden(), WithPlaceHolder("KEY")))
+
+	context, err := app.ParseContext([]string{"--name=bob"})
+	assert.NoError(t, err)
+	assert.Equal(t, "FAST", mode)
+	assert.Equal(t, "bob", context.StringValue("name"))
+
+	_, err = app.Parse([]string{"--name=bob", "-m", "slow"})
+	assert.NoError(t, err)
+	assert.Equal(t, "SLOW", mode)
+
+	_, err = app.Parse([]string{})
+	assert.EqualError(t, err, "required flag --name not provided")
+
+	assert.Equal(t, "Mode.", app.GetFlag("mode").Model().Help)
+	assert.Equal(t, "TEST_NAME", app.GetFlag("name").Model().Envar)
+	assert.True(t, app.GetFlag("secret").Model().Hidden)
+}
@@ -0,0 +1,54 @@
+package kingpin
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// UsePager enables displaying help with the pager in $PAGER, or less, when
+// the help is taller than the terminal it is displayed on.
+func (a *Application) UsePager(enable bool) *Application {
+	a.usePager = enable
+	return a
+}
+
+// showHelp displays the usage of cmd, or of the application if cmd is nil,
+// on the usage writer.
+func (a *Application) showHelp(cmd *CmdClause) {
+	w := a.usageWriter
+	width := a.width(w)
+	buf := &bytes.Buffer{}
+	if cmd != nil {
+		a.commandUsage(buf, cmd, width)
+	} else {
+		a.usage(buf, width)
+	}
+	if a.usePager && a.page(buf.Bytes()) {
+		return
+	}
+	buf.WriteTo(w)
+}
+
+// page displays text with the pager if it is taller than the terminal,
+// returning false if it was not displayed.
+func (a *Application) page(text []byte) bool {
+	height := guessHeight(a.usageWriter)
+	if height == 0 || bytes.Count(text, []byte("\n")) < height {
+		return false
+	}
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less"}
+	}
+	cmd := exec.Command(pager[0], pager[1:]...)
+	cmd.Stdin = bytes.NewReader(text)
+	cmd.Stdout = a.usageWriter
+	cmd.Stderr = a.errorWriter
+	if os.Getenv("LESS") == "" {
+		// Exit if the text fits after all, and don't clear the screen.
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	return cmd.Run() == nil
+}
@@ -0,0 +1,21 @@
+package kingpin
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsePagerNotTerminal(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	app := New("test", "").UsePager(true).UsageWriter(w).Terminate(func(int) {})
+	for i := 0; i < 100; i++ {
+		app.Flag(strings.Repeat("x", i+1), "").Bool()
+	}
+	_, err := app.Parse([]string{"--help"})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "usage: test")
+	assert.False(t, app.page(w.Bytes()))
+}
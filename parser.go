@@ -1,8 +1,386 @@
 package kingpin
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
 type ParseContext struct {
 	Tokens          Tokens
-	SelectedCommand string
+	SelectedCommand *CmdClause
+	app             *Application
+	command         string
+	values          []valueGroup
+	snapshot        map[interface{}]Value
+	snapshotLock    sync.RWMutex
+	retained        bool
+	collectErrors   bool
+	separateBools   bool
+	errors          []error
+	messages        *Messages
+	warnings        []string
+	onWarning       func(string)
+	flagsSeen       []*FlagClause
+	argsSeen        []*ArgClause
+	commandPath     []*CmdClause
+	args            []string
+	allTokens       Tokens
+	positions       []TokenPosition
+	indexes         map[*Token]int
+	options         map[*Token]TokenPosition
+	partial         bool
+	rest            []string
+	data            map[string]interface{}
+	ctx             context.Context
+	sources         map[interface{}]ValueSource
+	elements        map[interface{}][]string
+	elementStore    []string
+	pure            bool
+	commands        *cmdGroup
+	subCommands     *cmdGroup
+	deferred        Tokens
+	dotEnv          map[string]string
+	numberFormat    *NumberFormat
+}
+
+// ValueSource describes where the value of a flag or argument came from.
+type ValueSource int
+
+// Value sources.
+const (
+	// SourceNone indicates the value was not set, and is its zero value.
+	SourceNone ValueSource = iota
+	SourceCommandLine
+	SourceDefault
+	SourceEnvar
+	// SourcePrompt indicates the value was entered at a prompt, see
+	// FlagClause.Password().
+	SourcePrompt
+)
+
+func (v ValueSource) String() string {
+	switch v {
+	case SourceCommandLine:
+		return "command-line"
+	case SourceDefault:
+		return "default"
+	case SourceEnvar:
+		return "envar"
+	case SourcePrompt:
+		return "prompt"
+	}
+	return "none"
+}
+
+// ParseErrors is returned when Application.CollectErrors() is enabled and
+// more than one error was encountered while parsing.
+type ParseErrors []error
+
+func (p ParseErrors) Error() string {
+	out := make([]string, 0, len(p))
+	for _, err := range p {
+		out = append(out, err.Error())
+	}
+	return strings.Join(out, "; ")
+}
+
+// FlagsSeen returns the flags present on the command line, in the order they
+// were encountered. A flag is included once for each occurrence.
+func (p *ParseContext) FlagsSeen() []*FlagClause {
+	return p.flagsSeen
+}
+
+// ArgsSeen returns the positional arguments present on the command line, in
+// order. Cumulative arguments are included once for each value.
+func (p *ParseContext) ArgsSeen() []*ArgClause {
+	return p.argsSeen
+}
+
+// CommandPath returns the selected command and its parents, outermost first.
+func (p *ParseContext) CommandPath() []*CmdClause {
+	return p.commandPath
+}
+
+// StringValue returns the string value of a flag or argument by its
+// fully-qualified name, as used by Values(), or "" if there is no such value.
+// Values are only available for the application and selected commands, once
+// their flags have been parsed.
+func (p *ParseContext) StringValue(name string) string {
+	p.snapshotLock.RLock()
+	defer p.snapshotLock.RUnlock()
+	if _, value := p.lookup(name); value != nil {
+		return value.String()
+	}
+	return ""
+}
+
+// ValueLookup resolves the final values of flags and arguments by their
+// fully-qualified names, as used by ParseContext.Values(), eg. "port" or
+// "deploy.region". See Application.ValidateValues().
+type ValueLookup struct {
+	context *ParseContext
+}
+
+// Value returns the typed value of a flag or argument, as returned by the
+// Get() method of its Value, or nil if there is no such value.
+func (v ValueLookup) Value(name string) interface{} {
+	v.context.snapshotLock.RLock()
+	defer v.context.snapshotLock.RUnlock()
+	_, value := v.context.lookup(name)
+	if value == nil {
+		return nil
+	}
+	if g, ok := value.(Getter); ok {
+		return g.Get()
+	}
+	return value.String()
+}
+
+// String returns the string value of a flag or argument, or "".
+func (v ValueLookup) String(name string) string {
+	return v.context.StringValue(name)
+}
+
+// IsSet returns true if a flag or argument was given a value, from any
+// source including its default.
+func (v ValueLookup) IsSet(name string) bool {
+	return v.context.Source(name) != SourceNone
+}
+
+// Changed returns true if a flag or argument was given a value by the user,
+// rather than defaulted.
+func (v ValueLookup) Changed(name string) bool {
+	return v.context.Changed(name)
+}
+
+// Source returns where the value of a flag or argument came from.
+func (v ValueLookup) Source(name string) ValueSource {
+	return v.context.Source(name)
+}
+
+// Context returns the ParseContext values are looked up in.
+func (v ValueLookup) Context() *ParseContext {
+	return v.context
+}
+
+// stopPartial stops a partial parse at the current token, recording the
+// remaining arguments. It returns false if this is not a partial parse.
+func (p *ParseContext) stopPartial() bool {
+	if !p.partial {
+		return false
+	}
+	if p.rest == nil {
+		p.rest = p.remainingArgs()
+	}
+	p.Tokens = nil
+	return true
+}
+
+// remainingArgs reconstructs the unparsed command-line arguments. Options
+// from Application.OptionsEnvar() are not command-line arguments, so are
+// omitted.
+func (p *ParseContext) remainingArgs() []string {
+	tokens := p.Tokens
+	for len(tokens) > 0 && p.isOption(tokens[0]) {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return []string{}
+	}
+	first := tokens[0]
+	position, ok := p.positionOf(first)
+	if !ok {
+		return tokens.Strings()
+	}
+	i := position.Arg
+	rest := append([]string{}, p.args[i:]...)
+	if first.Type == TokenShort {
+		// Part of a combined short flag may already have been consumed.
+		shorts := ""
+		for _, token := range tokens {
+			if position, _ := p.positionOf(token); token.Type != TokenShort || position.Arg != i {
+				break
+			}
+			shorts += token.Value
+		}
+		rest[0] = "-" + shorts
+	} else if i > 0 && p.args[i-1] == "--" {
+		rest = append([]string{"--"}, rest...)
+	}
+	return rest
+}
+
+// attached returns true if value is a value given in the same argument as
+// flag, as in "--name=value".
+func (p *ParseContext) attached(flag, value *Token) bool {
+	if value.Type != TokenArg {
+		return false
+	}
+	flagPosition, flagOption, ok := p.locate(flag)
+	if !ok {
+		return false
+	}
+	valuePosition, valueOption, ok := p.locate(value)
+	return ok && valueOption == flagOption && valuePosition.Arg == flagPosition.Arg
+}
+
+// boolValue returns true if value is the separate value of the boolean flag,
+// as in "--name false", which is only accepted with
+// Application.SeparateBoolValues().
+func (p *ParseContext) boolValue(flag, value *Token) bool {
+	if !p.separateBools || value.Type != TokenArg {
+		return false
+	}
+	switch value.Value {
+	case "true", "false", "1", "0":
+	default:
+		return false
+	}
+	// The value must be the next argument, not after "--".
+	flagPosition, flagOption, ok := p.locate(flag)
+	if !ok {
+		return false
+	}
+	valuePosition, valueOption, ok := p.locate(value)
+	return ok && valueOption == flagOption && valuePosition.Arg == flagPosition.Arg+1
+}
+
+// isOption returns true if token is one of the options from
+// Application.OptionsEnvar().
+func (p *ParseContext) isOption(token *Token) bool {
+	_, ok := p.options[token]
+	return ok
+}
+
+// locate returns the position of token within either the command-line
+// arguments or the options from Application.OptionsEnvar(), and true for the
+// latter.
+func (p *ParseContext) locate(token *Token) (position TokenPosition, option bool, ok bool) {
+	if position, ok := p.options[token]; ok {
+		return position, true, true
+	}
+	position, ok = p.positionOf(token)
+	return position, false, ok
+}
+
+// positionOf returns the position of a token produced by Tokenize(). Options
+// from Application.OptionsEnvar() have no position.
+func (p *ParseContext) positionOf(token *Token) (TokenPosition, bool) {
+	i, ok := p.indexes[token]
+	if !ok {
+		return TokenPosition{}, false
+	}
+	return p.positions[i], true
+}
+
+// Set stores an arbitrary value in the context, for retrieval with Get() by
+// later Dispatch() actions. eg. a flag action might open a database and store
+// the handle for use by command actions.
+func (p *ParseContext) Set(key string, value interface{}) {
+	if p.data == nil {
+		p.data = map[string]interface{}{}
+	}
+	p.data[key] = value
+}
+
+// Get retrieves a value stored with Set(), or nil.
+func (p *ParseContext) Get(key string) interface{} {
+	return p.data[key]
+}
+
+// AllTokens returns every token produced from the command line, including
+// those already consumed by parsing.
+func (p *ParseContext) AllTokens() []Token {
+	out := make([]Token, 0, len(p.allTokens))
+	for _, token := range p.allTokens {
+		out = append(out, *token)
+	}
+	return out
+}
+
+// Position returns the position of the i'th token returned by AllTokens().
+func (p *ParseContext) Position(i int) TokenPosition {
+	return p.positions[i]
+}
+
+// StdinPiped returns true if os.Stdin is a pipe or a file rather than a
+// terminal, so that filter commands can decide whether to read from it when
+// no input is named.
+func (p *ParseContext) StdinPiped() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice == 0
+}
+
+// Warn records a non-fatal warning, such as a deprecation notice, and passes
+// it to the application's warning handler.
+func (p *ParseContext) Warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	p.warnings = append(p.warnings, warning)
+	if p.onWarning != nil {
+		p.onWarning(warning)
+	}
+}
+
+// Warnings returns all warnings recorded while parsing.
+func (p *ParseContext) Warnings() []string {
+	return p.warnings
+}
+
+func (p *ParseContext) msg() *Messages {
+	if p.messages == nil {
+		return &DefaultMessages
+	}
+	return p.messages
+}
+
+// A CommandError is a usage error that occurred while parsing the flags or
+// arguments of a command.
+type CommandError struct {
+	Command *CmdClause
+	Err     error
+}
+
+func (c *CommandError) Error() string {
+	return c.Command.FullCommand() + ": " + c.Err.Error()
+}
+
+func (c *CommandError) Unwrap() error { return c.Err }
+
+// annotate annotates usage errors with the selected command, if any.
+func (p *ParseContext) annotate(err error) error {
+	if err == nil || p.SelectedCommand == nil {
+		return err
+	}
+	switch err.(type) {
+	case *CommandError, *categorizedError, ParseErrors:
+		return err
+	}
+	return &CommandError{p.SelectedCommand, err}
+}
+
+// fail reports a recoverable parse error. If errors are being collected the
+// error is recorded and nil is returned, otherwise err is returned.
+func (p *ParseContext) fail(err error) error {
+	err = p.annotate(err)
+	if !p.collectErrors {
+		return err
+	}
+	p.errors = append(p.errors, err)
+	return nil
+}
+
+// err returns the collected errors, if any.
+func (p *ParseContext) err() error {
+	switch len(p.errors) {
+	case 0:
+		return nil
+	case 1:
+		return p.errors[0]
+	}
+	return ParseErrors(p.errors)
 }
 
 func (p *ParseContext) Next() {
@@ -18,5 +396,451 @@ func (p *ParseContext) Return(token *Token) {
 }
 
 func (p *ParseContext) String() string {
-	return p.SelectedCommand + ": " + p.Tokens.String()
+	if p.SelectedCommand == nil {
+		return p.Tokens.String()
+	}
+	return p.SelectedCommand.FullCommand() + ": " + p.Tokens.String()
+}
+
+// valueGroup is a set of flags and arguments whose values are available from
+// the context, under names prefixed with prefix.
+type valueGroup struct {
+	prefix string
+	flags  *flagGroup
+	args   *argGroup
+}
+
+// recordValues makes the values of a group of flags and arguments available.
+// Fully-qualified names are only constructed when values are looked up, to
+// avoid allocating while parsing.
+func (p *ParseContext) recordValues(prefix string, flags *flagGroup, args *argGroup) {
+	p.values = append(p.values, valueGroup{prefix, flags, args})
+}
+
+// snapshotValues copies the value of every recorded flag and argument into
+// the context before another parse of the application reuses the values of
+// its clauses. Custom values that can not be copied remain shared.
+func (p *ParseContext) snapshotValues() {
+	p.snapshotLock.Lock()
+	defer p.snapshotLock.Unlock()
+	p.snapshot = map[interface{}]Value{}
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			p.snapshot[flag] = cloneValue(flag.value)
+		}
+		for _, arg := range group.args.args {
+			p.snapshot[arg] = cloneValue(arg.value)
+		}
+	}
+}
+
+// restoreValues sets the values of the clauses again from the recorded
+// elements, once another parse that took them over, while an action of this
+// parse ran, has finished with them.
+func (p *ParseContext) restoreValues() {
+	p.snapshotLock.Lock()
+	defer p.snapshotLock.Unlock()
+	p.snapshot = nil
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			for _, s := range p.elements[flag] {
+				flag.value.Set(s)
+			}
+		}
+		for _, arg := range group.args.args {
+			for _, s := range p.elements[arg] {
+				arg.value.Set(s)
+			}
+		}
+	}
+}
+
+// dispatch runs a Dispatch() action without holding the parse lock, so that
+// the action may parse the application again or call Check(), Complete() or
+// HelpSnapshot(). If another parse takes over the values meanwhile, they are
+// restored before parsing continues.
+func (p *ParseContext) dispatch(action Dispatch) error {
+	p.retained = true
+	a := p.app
+	if a == nil {
+		return action(p)
+	}
+	a.parseLock.Unlock()
+	defer func() {
+		a.parseLock.Lock()
+		if a.owner != p {
+			a.takeValues(p)
+			p.restoreValues()
+		}
+	}()
+	return action(p)
+}
+
+// valueOf returns the value of a flag or argument in this context: the copy
+// taken when parsing finished or, while parsing or without a context, the
+// value of the clause.
+func (p *ParseContext) valueOf(clause interface{}, value Value) Value {
+	if p == nil {
+		return value
+	}
+	if snapshot, ok := p.snapshot[clause]; ok {
+		return snapshot
+	}
+	return value
+}
+
+// eachValue calls fn with the fully-qualified name, clause and value of every
+// recorded flag and argument.
+func (p *ParseContext) eachValue(fn func(name string, clause interface{}, value Value)) {
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			fn(group.prefix+flag.name, flag, p.valueOf(flag, flag.value))
+		}
+		for _, arg := range group.args.args {
+			fn(group.prefix+arg.name, arg, p.valueOf(arg, arg.value))
+		}
+	}
+}
+
+// lookup returns the clause and value of a flag or argument by its
+// fully-qualified name.
+func (p *ParseContext) lookup(name string) (clause interface{}, value Value) {
+	for i := len(p.values) - 1; i >= 0; i-- {
+		group := p.values[i]
+		if !strings.HasPrefix(name, group.prefix) {
+			continue
+		}
+		short := name[len(group.prefix):]
+		if flag, ok := group.flags.long[short]; ok {
+			return flag, p.valueOf(flag, flag.value)
+		}
+		for _, arg := range group.args.args {
+			if arg.name == short {
+				return arg, p.valueOf(arg, arg.value)
+			}
+		}
+	}
+	return nil, nil
+}
+
+// applyDeferredDefaults sets the values of flags and arguments with a
+// DefaultFrom() default that were not otherwise set. Defaults are resolved
+// once all other values are set, for the application and then each selected
+// command in turn, flags before arguments, in the order they were declared.
+func (p *ParseContext) applyDeferredDefaults() error {
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			value, err := flag.deferredDefault(p, flag)
+			if err != nil {
+				return err
+			}
+			if value != "" {
+				if err := p.set(flag, flag.value, value, SourceDefault); err != nil {
+					return fmt.Errorf(p.msg().InvalidFlagDefault, flag.name, err)
+				}
+			}
+		}
+		for _, arg := range group.args.args {
+			value, err := arg.deferredDefault(p, arg)
+			if err != nil {
+				return err
+			}
+			if value != "" {
+				if err := p.set(arg, arg.value, value, SourceDefault); err != nil {
+					return fmt.Errorf(p.msg().InvalidArgDefault, value, arg.name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveValues parses again the values that depend on other values, such as
+// times in a location given by another flag, once all values are parsed.
+func (p *ParseContext) resolveValues() error {
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			if r, ok := flag.value.(resolver); ok && len(p.Elements(flag)) > 0 {
+				if err := r.resolve(); err != nil {
+					return err
+				}
+			}
+		}
+		for _, arg := range group.args.args {
+			if r, ok := arg.value.(resolver); ok && len(p.Elements(arg)) > 0 {
+				if err := r.resolve(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inheritedFlag returns the persistent flag with the given long or short name
+// of a command selected before the one being parsed, if any.
+func (p *ParseContext) inheritedFlag(name string, short bool) (*FlagClause, bool) {
+	for i := len(p.values) - 1; i >= 0; i-- {
+		flags := p.values[i].flags
+		var flag *FlagClause
+		if short {
+			flag = flags.short[name]
+		} else {
+			flag = flags.lookup(name)
+		}
+		if flag != nil && flag.persistent {
+			return flag, true
+		}
+	}
+	return nil, false
+}
+
+// lenient enables deferFlag() for the flags of the application or command
+// with the given sub-commands, if Application.AllowFlagsBeforeCommand() is
+// enabled.
+func (p *ParseContext) lenient(app *Application, commands *cmdGroup) {
+	p.subCommands = commands
+	p.commands = nil
+	if app.flagsBeforeCmd && commands.have() {
+		p.commands = commands
+	}
+}
+
+// skipUnknown skips an unknown flag, which has been reported, and its value
+// if it appears to have one, so that the value is not also reported as an
+// unexpected argument. A value attached as in "--bogus=value" is always
+// skipped; a separate argument is skipped unless it selects a sub-command.
+func (p *ParseContext) skipUnknown(flag *Token) {
+	p.Next()
+	value := p.Peek()
+	if value.Type != TokenArg {
+		return
+	}
+	if !p.attached(flag, value) && p.subCommands != nil && p.subCommands.selects(p) {
+		return
+	}
+	p.Next()
+}
+
+// deferFlag sets aside a flag that is unknown to the group being parsed, and
+// its value, for a sub-command to parse once it is selected. It returns false
+// if no sub-command defines the flag.
+func (p *ParseContext) deferFlag(token *Token, name string) bool {
+	if p.commands == nil {
+		return false
+	}
+	flag := p.commands.findFlag(name, token.Type == TokenShort)
+	if flag == nil {
+		return false
+	}
+	p.deferred = append(p.deferred, token)
+	p.Next()
+	fb, ok := flag.value.(boolFlag)
+	isBool := ok && fb.IsBoolFlag()
+	optional := flag.optionalValue || isBool
+	if value := p.Peek(); value.Type == TokenArg && (!optional || p.attached(token, value) || (isBool && p.boolValue(token, value))) {
+		p.deferred = append(p.deferred, value)
+		p.Next()
+	}
+	return true
+}
+
+// restoreDeferred returns the flags set aside by deferFlag() to the tokens
+// to be parsed, once a command has been selected.
+func (p *ParseContext) restoreDeferred() {
+	if len(p.deferred) > 0 {
+		p.Tokens = append(p.deferred, p.Tokens...)
+		p.deferred = nil
+	}
+}
+
+// finishPersistentFlags checks the persistent flags of the selected commands
+// and applies their defaults, once all commands have been parsed.
+func (p *ParseContext) finishPersistentFlags() error {
+	for _, group := range p.values {
+		if err := group.flags.finish(p, false, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set parses s into the value of a flag or argument, recording it and its
+// source against the clause.
+func (p *ParseContext) set(clause interface{}, value Value, s string, source ValueSource) error {
+	if p.numberFormat != nil && source != SourceDefault && isNumeric(value) {
+		s = p.numberFormat.normalize(s)
+	}
+	mixin := clauseParser(clause)
+	if mixin != nil {
+		if err := mixin.checkString(p, clause, s); err != nil {
+			return err
+		}
+	}
+	if err := value.Set(s); err != nil {
+		return localizeError(err, p.msg())
+	}
+	if mixin != nil {
+		if err := mixin.checkRange(p, clause); err != nil {
+			return err
+		}
+	}
+	if p.sources == nil {
+		p.sources = make(map[interface{}]ValueSource, 16)
+		p.elements = make(map[interface{}][]string, 16)
+		p.elementStore = make([]string, 0, 16)
+	}
+	p.sources[clause] = source
+	p.elements[clause] = p.addElement(p.elements[clause], s)
+	return nil
+}
+
+// addElement appends s to the elements of a clause. The first element of
+// each clause is allocated from a shared store rather than individually,
+// capped so that appending further elements copies them out of the store.
+func (p *ParseContext) addElement(elements []string, s string) []string {
+	if elements != nil {
+		return append(elements, s)
+	}
+	p.elementStore = append(p.elementStore, s)
+	n := len(p.elementStore)
+	return p.elementStore[n-1 : n : n]
+}
+
+// Elements returns the strings parsed into the value of a flag or argument
+// (a *FlagClause or *ArgClause) by this parse, in order. Unlike the value
+// itself, these are not shared with other parses of the same application.
+func (p *ParseContext) Elements(clause interface{}) []string {
+	return p.elements[clause]
+}
+
+// Source returns where the value of a flag or argument, identified by its
+// fully-qualified name as used by Values(), came from.
+func (p *ParseContext) Source(name string) ValueSource {
+	clause, _ := p.lookup(name)
+	if clause == nil {
+		return SourceNone
+	}
+	return p.sources[clause]
+}
+
+// Changed returns true if the value of a flag or argument, identified by its
+// fully-qualified name as used by Values(), was given by the user, on the
+// command line, in the environment or at a prompt, rather than defaulted.
+func (p *ParseContext) Changed(name string) bool {
+	clause, _ := p.lookup(name)
+	return clause != nil && p.setByUser(clause)
+}
+
+// setByUser returns true if the value of a clause was given by the user.
+func (p *ParseContext) setByUser(clause interface{}) bool {
+	switch p.sources[clause] {
+	case SourceCommandLine, SourceEnvar, SourcePrompt:
+		return true
+	}
+	return false
+}
+
+// VisitSet calls fn for each flag of the application and selected commands
+// that was given a value by the user (see Changed()), in order of
+// declaration. Cumulative flags are visited once for each value given, so the
+// effective invocation can be reproduced, eg. to re-execute a child process,
+// by passing "--<name>=<value>" for each visit. The values of Password()
+// flags are masked; see VisitSetUnmasked().
+func (p *ParseContext) VisitSet(fn func(flag *FlagClause, value string)) {
+	p.visitSet(fn, false)
+}
+
+// VisitSetUnmasked is like VisitSet() but passes the values of Password()
+// flags as given, eg. to pass them on to a child process.
+func (p *ParseContext) VisitSetUnmasked(fn func(flag *FlagClause, value string)) {
+	p.visitSet(fn, true)
+}
+
+func (p *ParseContext) visitSet(fn func(flag *FlagClause, value string), unmasked bool) {
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			if !p.setByUser(flag) {
+				continue
+			}
+			for _, value := range p.elements[flag] {
+				if flag.password && !unmasked {
+					value = maskedSecret
+				}
+				fn(flag, value)
+			}
+		}
+	}
+}
+
+// VisitAll calls fn with every flag of the application and selected commands
+// and its final value, whether or not it was set, in order of declaration.
+// The values of Password() flags are masked; see VisitAllUnmasked().
+func (p *ParseContext) VisitAll(fn func(flag *FlagClause, value string)) {
+	p.visitAll(fn, false)
+}
+
+// VisitAllUnmasked is like VisitAll() but passes the values of Password()
+// flags as given.
+func (p *ParseContext) VisitAllUnmasked(fn func(flag *FlagClause, value string)) {
+	p.visitAll(fn, true)
+}
+
+func (p *ParseContext) visitAll(fn func(flag *FlagClause, value string), unmasked bool) {
+	p.snapshotLock.RLock()
+	defer p.snapshotLock.RUnlock()
+	for _, group := range p.values {
+		for _, flag := range group.flags.flagOrder {
+			if flag.password && !unmasked {
+				fn(flag, maskedSecret)
+			} else {
+				fn(flag, p.valueOf(flag, flag.value).String())
+			}
+		}
+	}
+}
+
+// Sources returns the source of every value returned by Values().
+func (p *ParseContext) Sources() map[string]ValueSource {
+	out := map[string]ValueSource{}
+	p.eachValue(func(name string, clause interface{}, value Value) {
+		out[name] = p.sources[clause]
+	})
+	return out
+}
+
+// Values returns the final string value of every flag and argument of the
+// application and selected commands. Keys are fully-qualified names of the
+// form "<command>.<subcommand>.<name>", or just "<name>" for application
+// level flags and arguments.
+func (p *ParseContext) Values() map[string]string {
+	p.snapshotLock.RLock()
+	defer p.snapshotLock.RUnlock()
+	out := map[string]string{}
+	p.eachValue(func(name string, clause interface{}, value Value) {
+		if isSecret(clause) {
+			out[name] = maskedSecret
+		} else {
+			out[name] = value.String()
+		}
+	})
+	return out
+}
+
+// TypedValues is like Values() but returns the underlying Go values, for
+// those Values implementing Getter.
+func (p *ParseContext) TypedValues() map[string]interface{} {
+	p.snapshotLock.RLock()
+	defer p.snapshotLock.RUnlock()
+	out := map[string]interface{}{}
+	p.eachValue(func(name string, clause interface{}, value Value) {
+		if isSecret(clause) {
+			out[name] = maskedSecret
+		} else if getter, ok := value.(Getter); ok {
+			out[name] = getter.Get()
+		} else {
+			out[name] = value.String()
+		}
+	})
+	return out
 }
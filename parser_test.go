@@ -0,0 +1,312 @@
+package kingpin
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseContextAccessors(t *testing.T) {
+	app := New("app", "")
+	debug := app.Flag("debug", "").Bool()
+	remote := app.Command("remote", "")
+	add := remote.Command("add", "")
+	add.Flag("fetch", "").Bool()
+	add.Arg("urls", "").Strings()
+
+	context, err := app.ParseContext([]string{"--debug", "remote", "add", "a", "b"})
+	assert.NoError(t, err)
+	assert.True(t, *debug)
+	assert.Equal(t, []*FlagClause{app.long["debug"]}, context.FlagsSeen())
+	assert.Equal(t, 2, len(context.ArgsSeen()))
+	assert.Equal(t, []*CmdClause{remote, add}, context.CommandPath())
+	assert.Equal(t, "a,b", context.StringValue("remote.add.urls"))
+	assert.Equal(t, "true", context.StringValue("debug"))
+	assert.Equal(t, "", context.StringValue("missing"))
+}
+
+func TestParseContextValuesAvailableInDispatch(t *testing.T) {
+	app := New("app", "")
+	app.Flag("debug", "").Bool()
+	cmd := app.Command("cmd", "")
+	cmd.Arg("arg", "").String()
+	values := map[string]string{}
+	cmd.Dispatch(func(context *ParseContext) error {
+		values = context.Values()
+		return nil
+	})
+	_, err := app.Parse([]string{"--debug", "cmd", "x"})
+	assert.NoError(t, err)
+	assert.Equal(t, "true", values["debug"])
+	assert.Equal(t, "x", values["cmd.arg"])
+}
+
+func TestSelectedCommand(t *testing.T) {
+	app := New("app", "")
+	remote := app.Command("remote", "")
+	add := remote.Command("add", "")
+	var selected *CmdClause
+	add.Dispatch(func(context *ParseContext) error {
+		selected = context.SelectedCommand
+		return nil
+	})
+	_, err := app.Parse([]string{"remote", "add"})
+	assert.NoError(t, err)
+	assert.Equal(t, add, selected)
+	assert.Equal(t, "add", selected.Name())
+	assert.Equal(t, remote, selected.Parent())
+	assert.Equal(t, "remote add", selected.FullCommand())
+}
+
+func TestParseContextSetGet(t *testing.T) {
+	app := New("app", "")
+	app.Flag("db", "").Dispatch(func(context *ParseContext) error {
+		context.Set("db", "handle")
+		return nil
+	}).String()
+	var db interface{}
+	app.Command("cmd", "").Dispatch(func(context *ParseContext) error {
+		db = context.Get("db")
+		return nil
+	})
+	context, err := app.ParseContext([]string{"--db=x", "cmd"})
+	assert.NoError(t, err)
+	assert.Equal(t, "handle", db)
+	assert.Nil(t, context.Get("missing"))
+}
+
+func TestParseContextSources(t *testing.T) {
+	os.Setenv("KINGPIN_TEST_SOURCE", "from-env")
+	defer os.Unsetenv("KINGPIN_TEST_SOURCE")
+	app := New("app", "")
+	app.Flag("cli", "").String()
+	app.Flag("default", "").Default("x").String()
+	app.Flag("envar", "").OverrideDefaultFromEnvar("KINGPIN_TEST_SOURCE").String()
+	app.Flag("unset", "").String()
+	app.Arg("arg", "").Default("y").String()
+
+	context, err := app.ParseContext([]string{"--cli=a"})
+	assert.NoError(t, err)
+	assert.Equal(t, SourceCommandLine, context.Source("cli"))
+	assert.Equal(t, SourceDefault, context.Source("default"))
+	assert.Equal(t, SourceEnvar, context.Source("envar"))
+	assert.Equal(t, "from-env", context.StringValue("envar"))
+	assert.Equal(t, SourceNone, context.Source("unset"))
+	assert.Equal(t, SourceDefault, context.Sources()["arg"])
+	assert.Equal(t, "command-line", SourceCommandLine.String())
+}
+
+func TestParseContextChanged(t *testing.T) {
+	os.Setenv("KINGPIN_TEST_CHANGED", "from-env")
+	defer os.Unsetenv("KINGPIN_TEST_CHANGED")
+	app := New("app", "")
+	cli := app.Flag("cli", "").Default("x").String()
+	def := app.Flag("default", "").Default("x").String()
+	app.Flag("envar", "").OverrideDefaultFromEnvar("KINGPIN_TEST_CHANGED").String()
+	cmd := app.Command("cmd", "")
+	arg := cmd.Arg("arg", "").Default("y").String()
+
+	context, err := app.ParseContext([]string{"--cli=x", "cmd"})
+	assert.NoError(t, err)
+	assert.True(t, context.Changed("cli"))
+	assert.False(t, context.Changed("default"))
+	assert.True(t, context.Changed("envar"))
+	assert.False(t, context.Changed("cmd.arg"))
+	assert.False(t, context.Changed("missing"))
+	assert.True(t, app.GetFlag("cli").IsSetByUser(context))
+	assert.False(t, app.GetFlag("default").IsSetByUser(context))
+	assert.False(t, cmd.GetArg("arg").IsSetByUser(context))
+	assert.Equal(t, *cli, *def)
+	assert.Equal(t, "y", *arg)
+
+	context, err = app.ParseContext([]string{"cmd", "z"})
+	assert.NoError(t, err)
+	assert.False(t, context.Changed("cli"))
+	assert.True(t, context.Changed("cmd.arg"))
+	assert.True(t, cmd.GetArg("arg").IsSetByUser(context))
+}
+
+func newBenchmarkApplication() *Application {
+	app := New("chat", "")
+	app.Flag("debug", "").Bool()
+	app.Flag("server", "").IP()
+	app.Flag("verbose", "").Short('v').Bool()
+	app.Flag("extra", "").Short('x').Bool()
+	post := app.Command("post", "")
+	post.Flag("image", "").String()
+	post.Flag("channel", "").String()
+	post.Flag("timeout", "").Short('t').Duration()
+	post.Flag("notify", "").Default("true").Bool()
+	post.Arg("text", "").Strings()
+	return app
+}
+
+func BenchmarkParse(b *testing.B) {
+	app := newBenchmarkApplication()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := app.Parse(benchmarkArgs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// TestParseAllocations guards against features that allocate on every parse
+// even when unused.
+func TestParseAllocations(t *testing.T) {
+	app := newBenchmarkApplication()
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := app.Parse(benchmarkArgs); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.True(t, allocs <= 40, "%v allocations per parse", allocs)
+}
+
+func TestRepeatedParseResetsValues(t *testing.T) {
+	app := New("test", "")
+	names := app.Flag("name", "").Strings()
+	debug := app.Flag("debug", "").Bool()
+	_, err := app.Parse([]string{"--name=a", "--name=b", "--debug"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, *names)
+	assert.True(t, *debug)
+	_, err = app.Parse([]string{"--name=c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, *names)
+	assert.False(t, *debug)
+}
+
+func TestConcurrentParse(t *testing.T) {
+	app := New("test", "")
+	nameFlag := app.Flag("name", "").Default("none")
+	name := nameFlag.String()
+	cmd := app.Command("cmd", "")
+	cmd.Arg("args", "").Strings()
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			defer func() { done <- true }()
+			value := fmt.Sprintf("%d", i)
+			context, err := app.ParseContext([]string{"--name", value, "cmd", value})
+			if assert.NoError(t, err) {
+				assert.Equal(t, []string{value}, context.Elements(nameFlag))
+				assert.Equal(t, value, context.StringValue("name"))
+				assert.Equal(t, []string{value}, context.TypedValues()["cmd.args"])
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	assert.NotEqual(t, "none", *name)
+}
+
+func TestStdinPiped(t *testing.T) {
+	withStdin(t, "input")
+	app := New("test", "")
+	piped := false
+	app.Command("cat", "").Dispatch(func(context *ParseContext) error {
+		piped = context.StdinPiped()
+		return nil
+	})
+	_, err := app.Parse([]string{"cat"})
+	assert.NoError(t, err)
+	assert.True(t, piped)
+}
+
+func TestParseContextVisit(t *testing.T) {
+	app := New("app", "")
+	app.Flag("debug", "").Bool()
+	app.Flag("tag", "").Strings()
+	app.Flag("level", "").Default("info").String()
+	cmd := app.Command("cmd", "")
+	cmd.Flag("force", "").Bool()
+
+	context, err := app.ParseContext([]string{"--tag=a", "--tag", "b", "cmd", "--force"})
+	assert.NoError(t, err)
+	set := []string{}
+	context.VisitSet(func(flag *FlagClause, value string) {
+		set = append(set, "--"+flag.Model().Name+"="+value)
+	})
+	assert.Equal(t, []string{"--tag=a", "--tag=b", "--force=true"}, set)
+
+	all := map[string]string{}
+	context.VisitAll(func(flag *FlagClause, value string) {
+		all[flag.Model().Name] = value
+	})
+	assert.Equal(t, map[string]string{
+		"help":  "false",
+		"debug": "false",
+		"tag":   "a,b",
+		"level": "info",
+		"force": "true",
+	}, all)
+}
+
+func TestContextsKeepTheirOwnValues(t *testing.T) {
+	app := New("test", "")
+	n := app.Flag("n", "").String()
+	app.Flag("tags", "").Strings()
+	c1, err := app.ParseContext([]string{"--n=first", "--tags=a"})
+	assert.NoError(t, err)
+	c2, err := app.ParseContext([]string{"--n=second"})
+	assert.NoError(t, err)
+	assert.Equal(t, "second", *n)
+
+	assert.Equal(t, "first", c1.StringValue("n"))
+	assert.Equal(t, map[string]string{"help": "false", "n": "first", "tags": "a"}, c1.Values())
+	assert.Equal(t, []string{"a"}, ValueLookup{c1}.Value("tags"))
+	assert.Equal(t, "first", c1.Model().Flags[1].Value)
+	visited := map[string]string{}
+	c1.VisitAll(func(flag *FlagClause, value string) { visited[flag.name] = value })
+	assert.Equal(t, map[string]string{"help": "false", "n": "first", "tags": "a"}, visited)
+
+	assert.Equal(t, "second", c2.StringValue("n"))
+	assert.Equal(t, "", c2.StringValue("tags"))
+}
+
+func TestParseFromAction(t *testing.T) {
+	app := New("test", "")
+	n := app.Flag("n", "").String()
+	var inner *ParseContext
+	app.Command("outer", "").Dispatch(func(*ParseContext) error {
+		var err error
+		inner, err = app.ParseContext([]string{"--n=inner", "other"})
+		assert.Empty(t, app.Check())
+		_, helpErr := app.HelpSnapshot(80)
+		assert.NoError(t, helpErr)
+		assert.Equal(t, []string{"outer", "other"}, app.Complete([]string{"o"}, 0))
+		return err
+	})
+	app.Command("other", "")
+
+	context, err := app.ParseContext([]string{"--n=outer", "outer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "outer", *n)
+	assert.Equal(t, "outer", context.StringValue("n"))
+	if assert.NotNil(t, inner) {
+		assert.Equal(t, "inner", inner.StringValue("n"))
+		assert.Equal(t, "other", inner.SelectedCommand.FullCommand())
+	}
+}
+
+func TestReparseClosesFiles(t *testing.T) {
+	f, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+	app := New("test", "")
+	file := app.Arg("file", "").File()
+	_, err = app.Parse([]string{f.Name()})
+	assert.NoError(t, err)
+	first := *file
+	_, err = app.Parse([]string{f.Name()})
+	assert.NoError(t, err)
+	assert.Error(t, first.Close(), "the first file was left open")
+	assert.NoError(t, (*file).Close())
+}
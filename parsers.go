@@ -2,27 +2,92 @@ package kingpin
 
 import (
 	"fmt"
+	"io"
+	"math/big"
 	"net"
 	"net/url"
 	"os"
+	"regexp"
+	"text/template"
 	"time"
 
 	"github.com/alecthomas/units"
 )
 
+// DefaultLogLevels is the set of levels accepted by LogLevel() when none are
+// provided, in order of increasing severity.
+var DefaultLogLevels = []string{"debug", "info", "warn", "error"}
+
 type Settings interface {
 	SetValue(value Value)
 }
 
 type parserMixin struct {
-	value    Value
-	required bool
+	value          Value
+	required       bool
+	cumulative     bool
+	hintActions    []HintAction
+	minOccurrences int
+	maxOccurrences int
+	requiredIf     func(*ParseContext) bool
+	defaultFrom    func(*ParseContext) (string, error)
+	min, max       interface{}
+	minLen, maxLen int
+	pattern        *regexp.Regexp
+	oneOf          []string
 }
 
 func (p *parserMixin) SetValue(value Value) {
+	if p.cumulative {
+		value = newCumulativeValue(value)
+	}
 	p.value = value
 }
 
+func (p *parserMixin) setCumulative() {
+	p.cumulative = true
+	if p.value != nil {
+		p.value = newCumulativeValue(p.value)
+	}
+}
+
+// checkOccurrences checks the number of values parsed into a cumulative flag
+// or argument, including any default, against its limits.
+func (p *parserMixin) checkOccurrences(context *ParseContext, clause interface{}) error {
+	n := len(context.Elements(clause))
+	if p.minOccurrences > 0 && n < p.minOccurrences {
+		return fmt.Errorf(context.msg().TooFewOccurrences, clauseName(clause), p.minOccurrences)
+	}
+	if p.maxOccurrences > 0 && n > p.maxOccurrences {
+		return fmt.Errorf(context.msg().TooManyOccurrences, clauseName(clause), p.maxOccurrences)
+	}
+	return nil
+}
+
+// requiredBy reports whether a predicate given to RequiredIf() requires a
+// value that was not provided.
+func (p *parserMixin) requiredBy(context *ParseContext, clause interface{}) bool {
+	if p.requiredIf == nil || len(context.Elements(clause)) > 0 {
+		return false
+	}
+	context.retained = true
+	return p.requiredIf(context)
+}
+
+// deferredDefault returns the value computed by a function given to
+// DefaultFrom(), if the value was not otherwise set.
+func (p *parserMixin) deferredDefault(context *ParseContext, clause interface{}) (string, error) {
+	if p.defaultFrom == nil || len(context.Elements(clause)) > 0 {
+		return "", nil
+	}
+	context.retained = true
+	v, err := p.defaultFrom(context)
+	if err != nil {
+		return "", fmt.Errorf(context.msg().DefaultFuncFailed, clauseName(clause), err)
+	}
+	return v, nil
+}
+
 // String sets the parser to a string parser.
 func (p *parserMixin) String() (target *string) {
 	target = new(string)
@@ -58,6 +123,13 @@ func (p *parserMixin) Int() (target *int) {
 	return
 }
 
+// Ints accumulates int values into a slice.
+func (p *parserMixin) Ints() (target *[]int) {
+	target = new([]int)
+	p.IntsVar(target)
+	return
+}
+
 // Int64 parses an int64
 func (p *parserMixin) Int64() (target *int64) {
 	target = new(int64)
@@ -79,6 +151,68 @@ func (p *parserMixin) Float() (target *float64) {
 	return
 }
 
+// DefaultBigFloatPrec is the precision, in bits, of values parsed by
+// BigFloat().
+const DefaultBigFloatPrec = 256
+
+// BigInt parses an integer of any size, eg. for cryptographic keys.
+// Prefixes such as 0x select the base as for Int().
+func (p *parserMixin) BigInt() (target *big.Int) {
+	target = new(big.Int)
+	p.BigIntVar(target)
+	return
+}
+
+// BigIntVar parses an integer of any size.
+func (p *parserMixin) BigIntVar(target *big.Int) {
+	p.SetValue(newBigIntValue(target))
+}
+
+// BigFloat parses a floating-point number with DefaultBigFloatPrec bits of
+// precision.
+func (p *parserMixin) BigFloat() (target *big.Float) {
+	target = new(big.Float)
+	p.BigFloatVar(target, DefaultBigFloatPrec)
+	return
+}
+
+// BigFloatVar parses a floating-point number with prec bits of precision.
+func (p *parserMixin) BigFloatVar(target *big.Float, prec uint) {
+	p.SetValue(newBigFloatValue(target, prec))
+}
+
+// Decimal parses a decimal number such as "19.99" exactly, without the
+// rounding of binary floating-point, eg. for monetary amounts.
+func (p *parserMixin) Decimal() (target *big.Rat) {
+	target = new(big.Rat)
+	p.DecimalVar(target)
+	return
+}
+
+// DecimalVar parses a decimal number exactly.
+func (p *parserMixin) DecimalVar(target *big.Rat) {
+	p.SetValue(newDecimalValue(target))
+}
+
+// Percent parses a percentage such as "75%" or "0.75" into a ratio between 0
+// and 1. Numbers without a "%" suffix are interpreted as PercentAuto.
+func (p *parserMixin) Percent() (target *float64) {
+	return p.PercentAs(PercentAuto)
+}
+
+// PercentAs parses a percentage into a ratio between 0 and 1, interpreting
+// numbers without a "%" suffix according to mode.
+func (p *parserMixin) PercentAs(mode PercentMode) (target *float64) {
+	target = new(float64)
+	p.PercentVar(target, mode)
+	return
+}
+
+// PercentVar parses a percentage into a ratio between 0 and 1.
+func (p *parserMixin) PercentVar(target *float64, mode PercentMode) {
+	p.SetValue(newPercentValue(target, mode))
+}
+
 // Duration sets the parser to a time.Duration parser.
 func (p *parserMixin) Duration() (target *time.Duration) {
 	target = new(time.Duration)
@@ -93,6 +227,31 @@ func (p *parserMixin) Bytes() (target *units.Base2Bytes) {
 	return
 }
 
+// Base64Bytes decodes base64, in either the standard or URL-safe alphabet and
+// with or without padding, eg. for keys and tokens.
+func (p *parserMixin) Base64Bytes() (target *[]byte) {
+	target = new([]byte)
+	p.Base64BytesVar(target)
+	return
+}
+
+// Base64BytesVar decodes base64 in either alphabet.
+func (p *parserMixin) Base64BytesVar(target *[]byte) {
+	p.SetValue(newBase64Value(target))
+}
+
+// HexBytes decodes hexadecimal, eg. "deadbeef".
+func (p *parserMixin) HexBytes() (target *[]byte) {
+	target = new([]byte)
+	p.HexBytesVar(target)
+	return
+}
+
+// HexBytesVar decodes hexadecimal.
+func (p *parserMixin) HexBytesVar(target *[]byte) {
+	p.SetValue(newHexValue(target))
+}
+
 // IP sets the parser to a net.IP parser.
 func (p *parserMixin) IP() (target *net.IP) {
 	target = new(net.IP)
@@ -100,6 +259,13 @@ func (p *parserMixin) IP() (target *net.IP) {
 	return
 }
 
+// IPs accumulates net.IP values into a slice.
+func (p *parserMixin) IPs() (target *[]net.IP) {
+	target = new([]net.IP)
+	p.IPsVar(target)
+	return
+}
+
 // TCP (host:port) address.
 func (p *parserMixin) TCP() (target **net.TCPAddr) {
 	target = new(*net.TCPAddr)
@@ -124,6 +290,98 @@ func (p *parserMixin) TCPListVar(target *[]*net.TCPAddr) {
 	p.SetValue(newTCPAddrsValue(target))
 }
 
+// Time parses a time in the given layout, eg. time.RFC3339, in the local
+// time zone unless the layout includes one.
+func (p *parserMixin) Time(layout string) (target *time.Time) {
+	return p.TimeIn(layout, nil)
+}
+
+// TimeIn parses a time in the given layout, in the time zone that location
+// refers to once all flags and arguments have been parsed, so that it may be
+// the target of another flag, eg.
+//
+//	tz := app.Flag("tz", "Time zone.").Location()
+//	at := app.Flag("at", "Start time.").TimeIn("2006-01-02 15:04", tz)
+func (p *parserMixin) TimeIn(layout string, location **time.Location) (target *time.Time) {
+	target = new(time.Time)
+	p.TimeVar(target, layout, location)
+	return
+}
+
+// TimeVar parses a time in the given layout, in the time zone location
+// refers to, or the local time zone if location is nil.
+func (p *parserMixin) TimeVar(target *time.Time, layout string, location **time.Location) {
+	p.SetValue(newTimeValue(target, layout, location))
+}
+
+// TimeOfDay parses a wall-clock time such as "14:30" or "2:30pm".
+func (p *parserMixin) TimeOfDay() (target *TimeOfDay) {
+	target = new(TimeOfDay)
+	p.TimeOfDayVar(target)
+	return
+}
+
+// TimeOfDayVar parses a wall-clock time such as "14:30" or "2:30pm".
+func (p *parserMixin) TimeOfDayVar(target *TimeOfDay) {
+	p.SetValue(newTimeOfDayValue(target))
+}
+
+// Location parses a time zone name such as "Europe/Paris", "UTC" or "Local".
+func (p *parserMixin) Location() (target **time.Location) {
+	target = new(*time.Location)
+	p.LocationVar(target)
+	return
+}
+
+// LocationVar parses a time zone name such as "Europe/Paris".
+func (p *parserMixin) LocationVar(target **time.Location) {
+	p.SetValue(newLocationValue(target))
+}
+
+// TCPAddr is an alias for TCP().
+func (p *parserMixin) TCPAddr() (target **net.TCPAddr) {
+	return p.TCP()
+}
+
+// TCPAddrVar is an alias for TCPVar().
+func (p *parserMixin) TCPAddrVar(target **net.TCPAddr) {
+	p.TCPVar(target)
+}
+
+// TCPAddrList is an alias for TCPList().
+func (p *parserMixin) TCPAddrList() (target *[]*net.TCPAddr) {
+	return p.TCPList()
+}
+
+// TCPAddrListVar is an alias for TCPListVar().
+func (p *parserMixin) TCPAddrListVar(target *[]*net.TCPAddr) {
+	p.TCPListVar(target)
+}
+
+// UDPAddr (host:port) address.
+func (p *parserMixin) UDPAddr() (target **net.UDPAddr) {
+	target = new(*net.UDPAddr)
+	p.UDPAddrVar(target)
+	return
+}
+
+// UDPAddrVar (host:port) address.
+func (p *parserMixin) UDPAddrVar(target **net.UDPAddr) {
+	p.SetValue(newUDPAddrValue(target))
+}
+
+// UDPAddrList (host:port) address list.
+func (p *parserMixin) UDPAddrList() (target *[]*net.UDPAddr) {
+	target = new([]*net.UDPAddr)
+	p.UDPAddrListVar(target)
+	return
+}
+
+// UDPAddrListVar (host:port) address list.
+func (p *parserMixin) UDPAddrListVar(target *[]*net.UDPAddr) {
+	p.SetValue(newUDPAddrsValue(target))
+}
+
 // ExistingFile sets the parser to one that requires and returns an existing file.
 func (p *parserMixin) ExistingFile() (target *string) {
 	target = new(string)
@@ -131,6 +389,51 @@ func (p *parserMixin) ExistingFile() (target *string) {
 	return
 }
 
+// ExistingFiles accumulates the paths of existing files into a slice.
+func (p *parserMixin) ExistingFiles() (target *[]string) {
+	target = new([]string)
+	p.ExistingFilesVar(target)
+	return
+}
+
+// ExistingDirTree requires existing directories, and returns the paths of the
+// files within them and their sub-directories whose names match pattern, eg.
+// "*.jpg", or all files if pattern is empty.
+func (p *parserMixin) ExistingDirTree(pattern string) (target *[]string) {
+	target = new([]string)
+	p.ExistingDirTreeVar(target, pattern)
+	return
+}
+
+// ExistingDirTreeVar requires existing directories, and accumulates the paths
+// of the files within them whose names match pattern.
+func (p *parserMixin) ExistingDirTreeVar(target *[]string, pattern string) {
+	p.SetValue(newDirTreeValue(target, pattern))
+}
+
+// GlobFiles expands shell-style patterns such as "*.txt" into the paths of
+// the matching files, for platforms such as Windows where the shell does not.
+// A pattern that matches nothing is kept as is, as in the shell.
+func (p *parserMixin) GlobFiles() (target *[]string) {
+	target = new([]string)
+	p.GlobFilesVar(target, false)
+	return
+}
+
+// GlobFilesMustMatch is like GlobFiles(), but fails if a pattern matches no
+// files.
+func (p *parserMixin) GlobFilesMustMatch() (target *[]string) {
+	target = new([]string)
+	p.GlobFilesVar(target, true)
+	return
+}
+
+// GlobFilesVar expands shell-style patterns into the paths of the matching
+// files. If mustMatch is true, a pattern that matches no files is an error.
+func (p *parserMixin) GlobFilesVar(target *[]string, mustMatch bool) {
+	p.SetValue(newGlobFilesValue(target, mustMatch))
+}
+
 // ExistingDir sets the parser to one that requires and returns an existing directory.
 func (p *parserMixin) ExistingDir() (target *string) {
 	target = new(string)
@@ -152,6 +455,55 @@ func (p *parserMixin) OpenFile(flag int, perm os.FileMode) (target **os.File) {
 	return
 }
 
+// ReaderArg opens the named file for reading, or reads os.Stdin if the value
+// is "-", as is conventional for filters. Closing the reader does not close
+// os.Stdin.
+func (p *parserMixin) ReaderArg() (target *io.ReadCloser) {
+	target = new(io.ReadCloser)
+	p.ReaderArgVar(target)
+	return
+}
+
+// Template parses a text/template, given directly or as @<file>, so that
+// invalid templates are reported as usage errors.
+func (p *parserMixin) Template() (target **template.Template) {
+	target = new(*template.Template)
+	p.TemplateVar(target, nil)
+	return
+}
+
+// TemplateVar parses a text/template, given directly or as @<file>, with the
+// given functions available to it.
+func (p *parserMixin) TemplateVar(target **template.Template, funcs template.FuncMap) {
+	p.SetValue(newTemplateValue(target, funcs))
+}
+
+// JSON decodes a JSON document, given directly or as @<file>, into target,
+// which must be a pointer, eg. to a struct or map. Fields of the document
+// that are not in a target struct are ignored.
+func (p *parserMixin) JSON(target interface{}) {
+	p.SetValue(newJSONValue(target, false))
+}
+
+// StrictJSON is like JSON(), but fields of the document that are not in a
+// target struct are an error.
+func (p *parserMixin) StrictJSON(target interface{}) {
+	p.SetValue(newJSONValue(target, true))
+}
+
+// YAML decodes a YAML document, given directly or as @<file>, into target,
+// which must be a pointer, eg. to a struct or map. Fields of the document
+// that are not in a target struct are ignored.
+func (p *parserMixin) YAML(target interface{}) {
+	p.SetValue(newYAMLValue(target, false))
+}
+
+// StrictYAML is like YAML(), but fields of the document that are not in a
+// target struct are an error.
+func (p *parserMixin) StrictYAML(target interface{}) {
+	p.SetValue(newYAMLValue(target, true))
+}
+
 // URL provides a valid, parsed url.URL.
 func (p *parserMixin) URL() (target **url.URL) {
 	target = new(*url.URL)
@@ -214,13 +566,36 @@ func (p *parserMixin) IPVar(target *net.IP) {
 	p.SetValue(newIPValue(target))
 }
 
+// IPsVar accumulates net.IP values into a slice.
+func (p *parserMixin) IPsVar(target *[]net.IP) {
+	p.SetValue(NewAccumulator(target, func(v interface{}) Value {
+		return newIPValue(v.(*net.IP))
+	}))
+}
+
+// IntsVar accumulates int values into a slice.
+func (p *parserMixin) IntsVar(target *[]int) {
+	p.SetValue(NewAccumulator(target, func(v interface{}) Value {
+		return newIntValue(0, v.(*int))
+	}))
+}
+
+func isExistingFile(s os.FileInfo) error {
+	if s.IsDir() {
+		return valueErrorf(func(m *Messages) string { return m.PathIsDirectory }, s.Name())
+	}
+	return nil
+}
+
 // ExistingFile sets the parser to one that requires and returns an existing file.
 func (p *parserMixin) ExistingFileVar(target *string) {
-	p.SetValue(newFileStatValue(target, func(s os.FileInfo) error {
-		if s.IsDir() {
-			return fmt.Errorf("'%s' is a directory", s.Name())
-		}
-		return nil
+	p.SetValue(newFileStatValue(target, isExistingFile))
+}
+
+// ExistingFilesVar accumulates the paths of existing files into a slice.
+func (p *parserMixin) ExistingFilesVar(target *[]string) {
+	p.SetValue(NewAccumulator(target, func(v interface{}) Value {
+		return newFileStatValue(v.(*string), isExistingFile)
 	}))
 }
 
@@ -228,7 +603,7 @@ func (p *parserMixin) ExistingFileVar(target *string) {
 func (p *parserMixin) ExistingDirVar(target *string) {
 	p.SetValue(newFileStatValue(target, func(s os.FileInfo) error {
 		if !s.IsDir() {
-			return fmt.Errorf("'%s' is a file", s.Name())
+			return valueErrorf(func(m *Messages) string { return m.PathIsFile }, s.Name())
 		}
 		return nil
 	}))
@@ -244,6 +619,11 @@ func (p *parserMixin) OpenFileVar(target **os.File, flag int, perm os.FileMode)
 	p.SetValue(newFileValue(target, flag, perm))
 }
 
+// ReaderArgVar opens the named file, or os.Stdin for "-".
+func (p *parserMixin) ReaderArgVar(target *io.ReadCloser) {
+	p.SetValue(newReaderValue(target))
+}
+
 // URL provides a valid, parsed url.URL.
 func (p *parserMixin) URLVar(target **url.URL) {
 	p.SetValue(newURLValue(target))
@@ -261,6 +641,21 @@ func (p *parserMixin) URLListVar(target *[]*url.URL) {
 	p.SetValue(newURLListValue(target))
 }
 
+// URLs accumulates parsed url.URL values into a slice. Unlike URLList(), it
+// is cumulative, so as an argument it consumes all remaining arguments.
+func (p *parserMixin) URLs() (target *[]*url.URL) {
+	target = new([]*url.URL)
+	p.URLsVar(target)
+	return
+}
+
+// URLsVar accumulates parsed url.URL values into a slice.
+func (p *parserMixin) URLsVar(target *[]*url.URL) {
+	p.SetValue(NewAccumulator(target, func(v interface{}) Value {
+		return newURLValue(v.(**url.URL))
+	}))
+}
+
 // Enum allows a value from a set of options.
 func (p *parserMixin) Enum(options ...string) (target *string) {
 	target = new(string)
@@ -284,3 +679,21 @@ func (p *parserMixin) Enums(options ...string) (target *[]string) {
 func (p *parserMixin) EnumsVar(target *[]string, options ...string) {
 	p.SetValue(newEnumsFlag(target, options...))
 }
+
+// LogLevel accepts one of a set of log levels (case-insensitive) and returns
+// the index of the selected level. If no levels are given, DefaultLogLevels
+// is used.
+func (p *parserMixin) LogLevel(levels ...string) (target *int) {
+	target = new(int)
+	p.LogLevelVar(target, levels...)
+	return
+}
+
+// LogLevelVar accepts one of a set of log levels and stores the index of the
+// selected level.
+func (p *parserMixin) LogLevelVar(target *int, levels ...string) {
+	if len(levels) == 0 {
+		levels = DefaultLogLevels
+	}
+	p.SetValue(newLogLevelValue(target, levels...))
+}
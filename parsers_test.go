@@ -1,8 +1,15 @@
 package kingpin
 
 import (
+	"io/ioutil"
+	"math/big"
 	"net"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -52,6 +59,151 @@ func TestParseExistingFile(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestParseReaderArg(t *testing.T) {
+	f, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("from file")
+	f.Close()
+
+	p := parserMixin{}
+	v := p.ReaderArg()
+	assert.NoError(t, p.value.Set(f.Name()))
+	data, err := ioutil.ReadAll(*v)
+	assert.NoError(t, err)
+	assert.Equal(t, "from file", string(data))
+	assert.Equal(t, f.Name(), p.value.String())
+	(*v).Close()
+
+	withStdin(t, "from stdin")
+	assert.NoError(t, p.value.Set("-"))
+	data, err = ioutil.ReadAll(*v)
+	assert.NoError(t, err)
+	assert.Equal(t, "from stdin", string(data))
+	assert.NoError(t, (*v).Close())
+	_, err = os.Stdin.Stat()
+	assert.NoError(t, err, "closing the reader closed stdin")
+
+	assert.Error(t, p.value.Set("/etc/hostsDEFINITELYMISSING"))
+}
+
+func TestParseReaderArgFromStdin(t *testing.T) {
+	app := New("test", "")
+	in := app.Flag("in", "").ReaderArg()
+	out := app.Arg("out", "").ReaderArg()
+
+	withStdin(t, "from stdin")
+	_, err := app.Parse([]string{"-"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, *out) {
+		data, err := ioutil.ReadAll(*out)
+		assert.NoError(t, err)
+		assert.Equal(t, "from stdin", string(data))
+	}
+
+	withStdin(t, "flag stdin")
+	_, err = app.Parse([]string{"--in", "-"})
+	assert.NoError(t, err)
+	if assert.NotNil(t, *in) {
+		data, err := ioutil.ReadAll(*in)
+		assert.NoError(t, err)
+		assert.Equal(t, "flag stdin", string(data))
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	p := parserMixin{}
+	v := p.TimeOfDay()
+	for _, s := range []string{"14:30", "2:30pm", "2:30PM"} {
+		assert.NoError(t, p.value.Set(s))
+		assert.Equal(t, TimeOfDay{14, 30, 0}, *v)
+	}
+	assert.NoError(t, p.value.Set("09:05:07"))
+	assert.Equal(t, "09:05:07", p.value.String())
+	assert.Equal(t, 9*time.Hour+5*time.Minute+7*time.Second, v.Duration())
+	date := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2020, 3, 1, 9, 5, 7, 0, time.UTC), v.On(date))
+	assert.EqualError(t, p.value.Set("25:00"), "expected time of day such as 14:30 but got '25:00'")
+}
+
+func TestParseTimeInLocationFromFlag(t *testing.T) {
+	app := New("test", "")
+	tz := app.Flag("tz", "").Default("UTC").Location()
+	at := app.Flag("at", "").TimeIn("2006-01-02 15:04", tz)
+	_, err := app.Parse([]string{"--at=2020-03-01 14:30", "--tz=America/New_York"})
+	assert.NoError(t, err)
+	assert.Equal(t, "America/New_York", (*tz).String())
+	assert.Equal(t, "2020-03-01T14:30:00-05:00", at.Format(time.RFC3339))
+
+	_, err = app.Parse([]string{"--at=2020-03-01 14:30"})
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-03-01T14:30:00Z", at.Format(time.RFC3339))
+
+	_, err = app.Parse([]string{"--tz=Nowhere/Special"})
+	assert.EqualError(t, err, "unknown time zone 'Nowhere/Special'")
+	_, err = app.Parse([]string{"--at=14:30"})
+	assert.EqualError(t, err, "expected time in the form 2006-01-02 15:04 but got '14:30'")
+}
+
+func TestParsePercent(t *testing.T) {
+	tests := []struct {
+		mode PercentMode
+		in   string
+		want float64
+	}{
+		{PercentAuto, "75%", 0.75},
+		{PercentAuto, "75", 0.75},
+		{PercentAuto, "0.75", 0.75},
+		{PercentAuto, "1", 1},
+		{PercentRatio, "0.5", 0.5},
+		{PercentRatio, "0.5%", 0.005},
+		{PercentPoints, "0.5", 0.005},
+		{PercentPoints, "100", 1},
+	}
+	for _, test := range tests {
+		p := parserMixin{}
+		v := p.PercentAs(test.mode)
+		assert.NoError(t, p.value.Set(test.in), test.in)
+		assert.InDelta(t, test.want, *v, 1e-12, test.in)
+	}
+
+	p := parserMixin{}
+	v := p.Percent()
+	assert.NoError(t, p.value.Set("12.5%"))
+	assert.Equal(t, "12.5%", p.value.String())
+	assert.Equal(t, 0.125, *v)
+	assert.EqualError(t, p.value.Set("150%"), "percentage must be between 0% and 100% but got '150%'")
+	assert.EqualError(t, p.value.Set("-5"), "percentage must be between 0% and 100% but got '-5'")
+	assert.EqualError(t, p.value.Set("lots"), "expected percentage such as 75% but got 'lots'")
+}
+
+func TestParseBigNumbers(t *testing.T) {
+	p := parserMixin{}
+	i := p.BigInt()
+	assert.NoError(t, p.value.Set("123456789012345678901234567890"))
+	assert.Equal(t, "123456789012345678901234567890", i.String())
+	assert.NoError(t, p.value.Set("0xff"))
+	assert.Equal(t, int64(255), i.Int64())
+	assert.EqualError(t, p.value.Set("1.5"), "expected integer but got '1.5'")
+
+	f := p.BigFloat()
+	assert.NoError(t, p.value.Set("1.000000000000000000000000000001"))
+	assert.Equal(t, uint(DefaultBigFloatPrec), f.Prec())
+	assert.Equal(t, 1, f.Cmp(big.NewFloat(1)))
+	assert.EqualError(t, p.value.Set("x"), "expected number but got 'x'")
+
+	d := p.Decimal()
+	assert.NoError(t, p.value.Set("0.1"))
+	assert.NoError(t, p.value.Set("19.990"))
+	assert.Equal(t, big.NewRat(1999, 100), d)
+	assert.Equal(t, "19.99", p.value.String())
+	assert.NoError(t, p.value.Set("1e-20"))
+	assert.Equal(t, "0.00000000000000000001", p.value.String())
+	assert.NoError(t, p.value.Set("-42"))
+	assert.Equal(t, "-42", p.value.String())
+	assert.EqualError(t, p.value.Set("1/3"), "expected decimal number but got '1/3'")
+}
+
 func TestParseTCPAddr(t *testing.T) {
 	p := parserMixin{}
 	v := p.TCP()
@@ -71,3 +223,308 @@ func TestParseTCPAddrList(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "127.0.0.1:1234,127.0.0.1:1235", (*tcpAddrsValue)(v).String())
 }
+
+func TestParseLogLevel(t *testing.T) {
+	p := parserMixin{}
+	v := p.LogLevel()
+	assert.NoError(t, p.value.Set("WARN"))
+	assert.Equal(t, 2, *v)
+	assert.Equal(t, "warn", p.value.String())
+	assert.Error(t, p.value.Set("fatal"))
+}
+
+func TestParseLogLevelCustomLevels(t *testing.T) {
+	p := parserMixin{}
+	v := p.LogLevel("trace", "debug", "fatal")
+	assert.NoError(t, p.value.Set("fatal"))
+	assert.Equal(t, 2, *v)
+}
+
+func TestParseCumulativeWrapsValue(t *testing.T) {
+	p := parserMixin{}
+	p.String()
+	p.setCumulative()
+	r, ok := p.value.(remainderArg)
+	assert.True(t, ok)
+	assert.True(t, r.IsCumulative())
+}
+
+func TestAccumulator(t *testing.T) {
+	target := []int{}
+	v := NewAccumulator(&target, func(v interface{}) Value { return newIntValue(0, v.(*int)) })
+	assert.NoError(t, v.Set("1"))
+	assert.NoError(t, v.Set("2"))
+	assert.Error(t, v.Set("x"))
+	assert.Equal(t, []int{1, 2}, target)
+	assert.Equal(t, "1,2", v.String())
+}
+
+func TestParseIPs(t *testing.T) {
+	p := parserMixin{}
+	v := p.IPs()
+	assert.NoError(t, p.value.Set("10.1.1.2"))
+	assert.NoError(t, p.value.Set("10.1.1.3"))
+	assert.Error(t, p.value.Set("10.1.1"))
+	assert.Equal(t, []net.IP{net.ParseIP("10.1.1.2"), net.ParseIP("10.1.1.3")}, *v)
+}
+
+func TestParseInts(t *testing.T) {
+	app := New("test", "")
+	v := app.Arg("ports", "").Ints()
+	_, err := app.Parse([]string{"80", "443"})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{80, 443}, *v)
+	_, err = app.Parse([]string{"80", "https"})
+	assert.Error(t, err)
+}
+
+func TestParseExistingFiles(t *testing.T) {
+	p := parserMixin{}
+	v := p.ExistingFiles()
+	assert.NoError(t, p.value.Set("/etc/hosts"))
+	assert.Error(t, p.value.Set("/etc/hostsDEFINITELYMISSING"))
+	assert.Error(t, p.value.Set("/etc"))
+	assert.Equal(t, []string{"/etc/hosts"}, *v)
+}
+
+func TestParseURLs(t *testing.T) {
+	app := New("test", "")
+	v := app.Arg("urls", "").URLs()
+	_, err := app.Parse([]string{"http://a", "http://b"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(*v))
+	assert.Equal(t, "http://a", (*v)[0].String())
+	assert.Equal(t, "http://b", (*v)[1].String())
+	_, err = app.Parse([]string{"http://a", "%zz"})
+	assert.Error(t, err)
+}
+
+func TestParseUDPAddr(t *testing.T) {
+	p := parserMixin{}
+	v := p.UDPAddr()
+	assert.NoError(t, p.value.Set("127.0.0.1:53"))
+	expected, err := net.ResolveUDPAddr("udp", "127.0.0.1:53")
+	assert.NoError(t, err)
+	assert.Equal(t, *expected, **v)
+	assert.Equal(t, "127.0.0.1:53", p.value.String())
+	assert.Error(t, p.value.Set("127.0.0.1:port"))
+}
+
+func TestAddrListArgs(t *testing.T) {
+	app := New("test", "")
+	peers := app.Flag("peer", "").TCPAddrList()
+	_, err := app.Parse([]string{"--peer=127.0.0.1:1234", "--peer=127.0.0.1:1235"})
+	assert.NoError(t, err)
+	assert.Len(t, *peers, 2)
+	assert.Equal(t, "127.0.0.1:1235", (*peers)[1].String())
+
+	app = New("test", "")
+	servers := app.Flag("server", "").UDPAddrList()
+	_, err = app.Parse([]string{"--server=127.0.0.1:53", "--server=127.0.0.2:53"})
+	assert.NoError(t, err)
+	assert.Len(t, *servers, 2)
+	assert.Equal(t, "127.0.0.2:53", (*servers)[1].String())
+
+	// As arguments, both list types take a single value, like TCPList().
+	app = New("test", "")
+	tcp := app.Arg("tcp", "").TCPAddrList()
+	udp := app.Arg("udp", "").UDPAddrList()
+	_, err = app.Parse([]string{"127.0.0.1:1234", "127.0.0.1:53"})
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:1234", newTCPAddrsValue(tcp).String())
+	assert.Equal(t, "127.0.0.1:53", newUDPAddrsValue(udp).String())
+	_, err = app.Parse([]string{"127.0.0.1:1234", "127.0.0.1:53", "127.0.0.2:53"})
+	assert.EqualError(t, err, "unexpected argument '127.0.0.2:53'")
+}
+
+func TestParseGlobFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kingpin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a.txt", "b.txt", "c.log"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	app := New("test", "")
+	files := app.Arg("files", "").GlobFiles()
+	_, err = app.Parse([]string{filepath.Join(dir, "*.txt"), filepath.Join(dir, "c.log"), filepath.Join(dir, "*.md")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.log"), filepath.Join(dir, "*.md"),
+	}, *files)
+
+	p := parserMixin{}
+	p.GlobFilesMustMatch()
+	assert.NoError(t, p.value.Set(filepath.Join(dir, "*.log")))
+	assert.EqualError(t, p.value.Set(filepath.Join(dir, "*.md")), "no files match '"+filepath.Join(dir, "*.md")+"'")
+	assert.Error(t, p.value.Set("["))
+}
+
+func TestParseExistingDirTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kingpin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub", "deeper"), 0700))
+	for _, name := range []string{"a.jpg", "b.txt", "sub/c.jpg", "sub/deeper/d.jpg"} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0600))
+	}
+
+	p := parserMixin{}
+	v := p.ExistingDirTree("*.jpg")
+	assert.NoError(t, p.value.Set(dir))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a.jpg"), filepath.Join(dir, "sub", "c.jpg"), filepath.Join(dir, "sub", "deeper", "d.jpg"),
+	}, *v)
+	assert.EqualError(t, p.value.Set(filepath.Join(dir, "a.jpg")), "'a.jpg' is a file")
+	assert.Error(t, p.value.Set(filepath.Join(dir, "missing")))
+
+	app := New("test", "")
+	all := app.Arg("dirs", "").ExistingDirTree("")
+	_, err = app.Parse([]string{filepath.Join(dir, "sub", "deeper"), filepath.Join(dir, "sub")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "sub", "deeper", "d.jpg"), filepath.Join(dir, "sub", "c.jpg"), filepath.Join(dir, "sub", "deeper", "d.jpg"),
+	}, *all)
+}
+
+func TestParseTemplate(t *testing.T) {
+	p := parserMixin{}
+	v := p.Template()
+	assert.NoError(t, p.value.Set("Hello {{.}}"))
+	w := &strings.Builder{}
+	assert.NoError(t, (*v).Execute(w, "world"))
+	assert.Equal(t, "Hello world", w.String())
+	assert.Equal(t, "Hello {{.}}", p.value.String())
+	assert.EqualError(t, p.value.Set("{{.Missing"), "invalid template: template: template:1: unclosed action")
+
+	f, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString("{{upper .}}")
+	f.Close()
+	p.TemplateVar(v, template.FuncMap{"upper": strings.ToUpper})
+	assert.NoError(t, p.value.Set("@"+f.Name()))
+	w.Reset()
+	assert.NoError(t, (*v).Execute(w, "shout"))
+	assert.Equal(t, "SHOUT", w.String())
+	assert.Error(t, p.value.Set("@"+f.Name()+".missing"))
+}
+
+func TestParseJSON(t *testing.T) {
+	type filter struct {
+		State  string
+		Labels []string
+	}
+	app := New("test", "")
+	f := filter{}
+	app.Flag("filter", "").JSON(&f)
+	m := map[string]int{}
+	app.Flag("limits", "").JSON(&m)
+
+	_, err := app.Parse([]string{`--filter={"state":"open","labels":["bug"]}`, `--limits={"cpu":2}`})
+	assert.NoError(t, err)
+	assert.Equal(t, filter{"open", []string{"bug"}}, f)
+	assert.Equal(t, map[string]int{"cpu": 2}, m)
+
+	_, err = app.Parse([]string{`--filter={"state":"closed"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, filter{State: "closed"}, f)
+	assert.Empty(t, m)
+
+	_, err = app.Parse([]string{`--filter={"state":"open","colour":"red"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, filter{State: "open"}, f)
+	_, err = app.Parse([]string{`--filter={`})
+	assert.EqualError(t, err, "invalid JSON: unexpected EOF")
+
+	file, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString(`{"state": "merged"}`)
+	file.Close()
+	_, err = app.Parse([]string{"--filter=@" + file.Name()})
+	assert.NoError(t, err)
+	assert.Equal(t, "merged", f.State)
+	assert.Equal(t, "@"+file.Name(), app.GetFlag("filter").Model().Value)
+}
+
+func TestParseStrictJSON(t *testing.T) {
+	app := New("test", "")
+	f := struct{ State string }{}
+	app.Flag("filter", "").StrictJSON(&f)
+
+	_, err := app.Parse([]string{`--filter={"state":"open"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, "open", f.State)
+
+	_, err = app.Parse([]string{`--filter={"colour":"red"}`})
+	assert.EqualError(t, err, `invalid JSON: json: unknown field "colour"`)
+}
+
+func TestParseYAML(t *testing.T) {
+	type resources struct {
+		CPU    string
+		Memory string
+	}
+	app := New("test", "")
+	r := resources{}
+	app.Flag("resources", "").YAML(&r)
+
+	_, err := app.Parse([]string{"--resources={cpu: 500m, memory: 1Gi}"})
+	assert.NoError(t, err)
+	assert.Equal(t, resources{"500m", "1Gi"}, r)
+
+	file, err := ioutil.TempFile("", "kingpin")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+	file.WriteString("cpu: \"2\"\n")
+	file.Close()
+	_, err = app.Parse([]string{"--resources=@" + file.Name()})
+	assert.NoError(t, err)
+	assert.Equal(t, resources{CPU: "2"}, r)
+	assert.Equal(t, "@"+file.Name(), app.GetFlag("resources").Model().Value)
+
+	_, err = app.Parse([]string{"--resources="})
+	assert.NoError(t, err)
+	assert.Equal(t, resources{}, r)
+
+	_, err = app.Parse([]string{"--resources={cpu: 1, gpu: 1}"})
+	assert.NoError(t, err)
+	assert.Equal(t, resources{CPU: "1"}, r)
+}
+
+func TestParseStrictYAML(t *testing.T) {
+	type resources struct {
+		CPU string
+	}
+	app := New("test", "")
+	r := resources{}
+	app.Flag("resources", "").StrictYAML(&r)
+
+	_, err := app.Parse([]string{"--resources=cpu: 1"})
+	assert.NoError(t, err)
+	assert.Equal(t, resources{"1"}, r)
+
+	_, err = app.Parse([]string{"--resources=gpu: 1"})
+	assert.EqualError(t, err, "invalid YAML: yaml: unmarshal errors:\n  line 1: field gpu not found in type kingpin.resources")
+}
+
+func TestParseBase64Bytes(t *testing.T) {
+	p := parserMixin{}
+	v := p.Base64Bytes()
+	for _, s := range []string{"+/8=", "+/8", "-_8=", "-_8"} {
+		assert.NoError(t, p.value.Set(s), s)
+		assert.Equal(t, []byte{0xfb, 0xff}, *v, s)
+	}
+	assert.Equal(t, "+/8=", p.value.String())
+	assert.EqualError(t, p.value.Set("not base64!"), "expected base64 but got 'not base64!'")
+}
+
+func TestParseHexBytes(t *testing.T) {
+	p := parserMixin{}
+	v := p.HexBytes()
+	assert.NoError(t, p.value.Set("DEADbeef"))
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, *v)
+	assert.Equal(t, "deadbeef", p.value.String())
+	assert.EqualError(t, p.value.Set("abc"), "expected hex but got 'abc'")
+}
@@ -0,0 +1,73 @@
+package kingpin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// maskedSecret is displayed in place of the values of password flags.
+const maskedSecret = "********"
+
+// Password marks the flag as holding a secret. If the flag is Required() but
+// not provided, its value is read from the terminal with echo disabled. The
+// value is never displayed in help, in ParseContext.Values() or in errors.
+func (f *FlagClause) Password() *FlagClause {
+	f.password = true
+	return f
+}
+
+// isSecret returns true if clause is a password flag.
+func isSecret(clause interface{}) bool {
+	flag, ok := clause.(*FlagClause)
+	return ok && flag.password
+}
+
+// secretError hides err, which may contain the value, if the flag is a
+// password.
+func (f *FlagClause) secretError(context *ParseContext, err error) error {
+	if f.password {
+		return fmt.Errorf(context.msg().InvalidSecret, f.name)
+	}
+	return err
+}
+
+// prompt reads the value of a missing password flag from the terminal. It
+// returns false if the value could not be read.
+func (f *FlagClause) prompt(context *ParseContext) (bool, error) {
+	if !f.password || context.pure {
+		return false, nil
+	}
+	value, err := promptPassword(fmt.Sprintf(context.msg().PasswordPrompt, f.name))
+	if err != nil || value == "" {
+		return false, nil
+	}
+	if err := context.set(f, f.value, value, SourcePrompt); err != nil {
+		return false, f.secretError(context, err)
+	}
+	return true, nil
+}
+
+// promptPassword displays prompt on the controlling terminal and reads a line
+// without echoing it. It is a variable so that tests can replace it.
+var promptPassword = func(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer tty.Close()
+	fmt.Fprint(tty, prompt)
+	defer fmt.Fprintln(tty)
+	restore, err := disableEcho(tty)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
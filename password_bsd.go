@@ -0,0 +1,11 @@
+//go:build freebsd || darwin || dragonfly || netbsd || openbsd
+// +build freebsd darwin dragonfly netbsd openbsd
+
+package kingpin
+
+import "syscall"
+
+const (
+	ioctlReadTermios  = syscall.TIOCGETA
+	ioctlWriteTermios = syscall.TIOCSETA
+)
@@ -0,0 +1,13 @@
+//go:build !linux && !freebsd && !darwin && !dragonfly && !netbsd && !openbsd
+// +build !linux,!freebsd,!darwin,!dragonfly,!netbsd,!openbsd
+
+package kingpin
+
+import (
+	"errors"
+	"os"
+)
+
+func disableEcho(tty *os.File) (func(), error) {
+	return nil, errors.New("reading passwords is not supported on this platform")
+}
@@ -0,0 +1,70 @@
+package kingpin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withPasswordPrompt(t *testing.T, value string, err error) *[]string {
+	prompts := []string{}
+	original := promptPassword
+	promptPassword = func(prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return value, err
+	}
+	t.Cleanup(func() { promptPassword = original })
+	return &prompts
+}
+
+func TestPasswordPrompt(t *testing.T) {
+	prompts := withPasswordPrompt(t, "s3cret", nil)
+	app := New("test", "")
+	password := app.Flag("password", "").Required().Password().String()
+	context, err := app.ParseContext([]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", *password)
+	assert.Equal(t, []string{"Value for --password: "}, *prompts)
+	assert.Equal(t, SourcePrompt, context.Source("password"))
+	assert.Equal(t, maskedSecret, context.Values()["password"])
+	visited := map[string]string{}
+	context.VisitAll(func(flag *FlagClause, value string) { visited[flag.name] = value })
+	assert.Equal(t, maskedSecret, visited["password"])
+	context.VisitAllUnmasked(func(flag *FlagClause, value string) { visited[flag.name] = value })
+	assert.Equal(t, "s3cret", visited["password"])
+	context.VisitSet(func(flag *FlagClause, value string) { visited[flag.name] = value })
+	assert.Equal(t, maskedSecret, visited["password"])
+	context.VisitSetUnmasked(func(flag *FlagClause, value string) { visited[flag.name] = value })
+	assert.Equal(t, "s3cret", visited["password"])
+
+	_, err = app.ParseContext([]string{"--password=given"})
+	assert.NoError(t, err)
+	assert.Equal(t, "given", *password)
+	assert.Equal(t, 1, len(*prompts))
+
+	_, err = app.ParseArgs([]string{})
+	assert.EqualError(t, err, "required flag --password not provided")
+	assert.Equal(t, 1, len(*prompts))
+}
+
+func TestPasswordPromptFails(t *testing.T) {
+	withPasswordPrompt(t, "", errors.New("no terminal"))
+	app := New("test", "")
+	app.Flag("password", "").Required().Password().String()
+	_, err := app.Parse([]string{})
+	assert.EqualError(t, err, "required flag --password not provided")
+}
+
+func TestPasswordNeverDisplayed(t *testing.T) {
+	app := New("test", "")
+	app.Flag("pin", "").Default("1234").Password().Int()
+	_, err := app.Parse([]string{"--pin=s3cret"})
+	assert.EqualError(t, err, "invalid value for --pin")
+
+	w := bytes.NewBuffer(nil)
+	app.usage(w, 80)
+	assert.NotContains(t, w.String(), "1234")
+	assert.Equal(t, "", app.Model().Flags[1].Default)
+}
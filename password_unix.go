@@ -0,0 +1,38 @@
+//go:build linux || freebsd || darwin || dragonfly || netbsd || openbsd
+// +build linux freebsd darwin dragonfly netbsd openbsd
+
+package kingpin
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func termios(tty *os.File, request uintptr, state *syscall.Termios) error {
+	if _, _, err := syscall.Syscall6(
+		syscall.SYS_IOCTL,
+		tty.Fd(),
+		request,
+		uintptr(unsafe.Pointer(state)),
+		0, 0, 0,
+	); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// disableEcho stops the terminal echoing input, returning a function that
+// restores its previous state.
+func disableEcho(tty *os.File) (func(), error) {
+	var state syscall.Termios
+	if err := termios(tty, ioctlReadTermios, &state); err != nil {
+		return nil, err
+	}
+	noEcho := state
+	noEcho.Lflag &^= syscall.ECHO
+	if err := termios(tty, ioctlWriteTermios, &noEcho); err != nil {
+		return nil, err
+	}
+	return func() { termios(tty, ioctlWriteTermios, &state) }, nil
+}
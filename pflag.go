@@ -0,0 +1,69 @@
+package kingpin
+
+import (
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// pflagValue adapts a Value to the pflag.Value interface.
+type pflagValue struct {
+	Value
+}
+
+func (p *pflagValue) Type() string {
+	if fb, ok := p.Value.(boolFlag); ok && fb.IsBoolFlag() {
+		return "bool"
+	}
+	return "string"
+}
+
+func (p *pflagValue) IsBoolFlag() bool {
+	fb, ok := p.Value.(boolFlag)
+	return ok && fb.IsBoolFlag()
+}
+
+// PFlagSet returns a spf13/pflag FlagSet exposing the flags in this group.
+// The pflag flags share their backing values with the kingpin flags.
+func (f *flagGroup) PFlagSet(name string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	for _, flag := range f.flagOrder {
+		if flag.value == nil {
+			continue
+		}
+		shorthand := ""
+		if flag.shorthand != 0 {
+			shorthand = string(flag.shorthand)
+		}
+		value := &pflagValue{flag.value}
+		fl := fs.VarPF(value, flag.name, shorthand, flag.help)
+		if value.IsBoolFlag() {
+			fl.NoOptDefVal = "true"
+		} else if flag.optionalValue {
+			fl.NoOptDefVal = flag.noOptDefault
+		}
+		if len(flag.defaultValues) > 0 {
+			fl.DefValue = strings.Join(flag.defaultValues, ",")
+		}
+		fl.Hidden = flag.hidden
+	}
+	return fs
+}
+
+// ImportPFlagSet defines a flag for each flag in the spf13/pflag FlagSet fs,
+// sharing the backing values of the originals.
+func (f *flagGroup) ImportPFlagSet(fs *pflag.FlagSet) {
+	fs.VisitAll(func(fl *pflag.Flag) {
+		clause := f.Flag(fl.Name, fl.Usage)
+		if len(fl.Shorthand) == 1 {
+			clause.Short(fl.Shorthand[0])
+		}
+		if fl.Hidden {
+			clause.Hidden()
+		}
+		clause.SetValue(fl.Value)
+		if fb, ok := fl.Value.(boolFlag); fl.NoOptDefVal != "" && (!ok || !fb.IsBoolFlag()) {
+			clause.NoOptDefault(fl.NoOptDefVal)
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package kingpin
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPFlagSet(t *testing.T) {
+	app := New("test", "")
+	debug := app.Flag("debug", "Debug mode.").Short('d').Bool()
+	name := app.Flag("name", "Name.").Default("bob").String()
+
+	fs := app.PFlagSet("test")
+	assert.NoError(t, fs.Parse([]string{"-d", "--name=alice"}))
+	assert.True(t, *debug)
+	assert.Equal(t, "alice", *name)
+	assert.Equal(t, "bob", fs.Lookup("name").DefValue)
+}
+
+func TestImportPFlagSet(t *testing.T) {
+	fs := pflag.NewFlagSet("lib", pflag.ContinueOnError)
+	verbose := fs.BoolP("verbose", "v", false, "Verbose.")
+	count := fs.Int("count", 1, "Count.")
+
+	app := New("test", "")
+	app.ImportPFlagSet(fs)
+	_, err := app.Parse([]string{"-v", "--count", "3"})
+	assert.NoError(t, err)
+	assert.True(t, *verbose)
+	assert.Equal(t, 3, *count)
+}
@@ -0,0 +1,121 @@
+package kingpin
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// rstEscaper escapes the characters with inline meaning in reStructuredText.
+var rstEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"*", `\*`,
+	"`", "\\`",
+	"_", `\_`,
+	"|", `\|`,
+)
+
+// WriteRST writes reference documentation for the application and all of its
+// commands as reStructuredText, eg. for inclusion in Sphinx documentation.
+// Flags and arguments are written as Sphinx "option" directives, so they can
+// be cross-referenced with :option:. Hidden flags and arguments are omitted.
+func (a *Application) WriteRST(w io.Writer) error {
+	if err := a.init(); err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	rstHeading(buf, a.Name, '=')
+	if a.Help != "" {
+		fmt.Fprintf(buf, "%s\n\n", rstEscaper.Replace(a.Help))
+	}
+	usage := formatArgsAndFlags(a.Name, a.argGroup, a.flagGroup, a.cmdGroup)
+	if len(a.commands) > 0 {
+		usage += " <command> [<flags>] [<args> ...]"
+	}
+	fmt.Fprintf(buf, "::\n\n    %s\n\n", usage)
+	a.flagGroup.writeRST(buf, a.messages.FlagsTitle, '-', a.messages)
+	a.argGroup.writeRST(buf, a.messages.ArgsTitle, '-', a.messages)
+	if len(a.commands) > 0 {
+		rstHeading(buf, strings.TrimSuffix(a.messages.CommandsTitle, ":"), '-')
+		for _, cmd := range a.flattenedCommands() {
+			cmd.writeRST(buf, a.messages)
+		}
+	}
+	if a.author != "" || a.homepage != "" || a.bugReports != "" {
+		for _, row := range [][2]string{
+			{a.messages.AuthorTitle, a.author},
+			{a.messages.HomepageTitle, a.homepage},
+			{a.messages.BugReportsTitle, a.bugReports},
+		} {
+			if row[1] != "" {
+				fmt.Fprintf(buf, ":%s: %s\n", strings.TrimSuffix(row[0], ":"), rstEscaper.Replace(row[1]))
+			}
+		}
+		fmt.Fprintf(buf, "\n")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (c *CmdClause) writeRST(w io.Writer, msg *Messages) {
+	rstHeading(w, c.FullCommand(), '~')
+	if c.help != "" {
+		fmt.Fprintf(w, "%s\n\n", rstEscaper.Replace(c.help))
+	}
+	usage := formatArgsAndFlags(c.app.Name+" "+c.FullCommand(), c.argGroup, c.flagGroup, c.cmdGroup)
+	fmt.Fprintf(w, "::\n\n    %s\n\n", usage)
+	c.flagGroup.writeRST(w, msg.FlagsTitle, '^', msg)
+	c.argGroup.writeRST(w, msg.ArgsTitle, '^', msg)
+}
+
+func (f *flagGroup) writeRST(w io.Writer, title string, underline byte, msg *Messages) {
+	if f.visibleFlags() == 0 {
+		return
+	}
+	rstHeading(w, strings.TrimSuffix(title, ":"), underline)
+	for _, flag := range f.flagOrder {
+		if flag.hidden {
+			continue
+		}
+		fmt.Fprintf(w, ".. option:: %s\n\n", formatFlag(flag))
+		rstBody(w, flag.helpText(flag.help, msg), flag.envar)
+	}
+}
+
+func (a *argGroup) writeRST(w io.Writer, title string, underline byte, msg *Messages) {
+	if a.visibleArgs() == 0 {
+		return
+	}
+	rstHeading(w, strings.TrimSuffix(title, ":"), underline)
+	for _, arg := range a.args {
+		if arg.hidden {
+			continue
+		}
+		fmt.Fprintf(w, ".. option:: <%s>\n\n", arg.name)
+		rstBody(w, arg.helpText(arg.help, msg), "")
+	}
+}
+
+// rstHeading writes a section title, underlined with the given character.
+func rstHeading(w io.Writer, title string, underline byte) {
+	title = rstEscaper.Replace(title)
+	fmt.Fprintf(w, "%s\n%s\n\n", title, strings.Repeat(string(underline), displayWidth(title)))
+}
+
+// rstBody writes the indented body of an option directive.
+func rstBody(w io.Writer, help, envar string) {
+	if help != "" {
+		for _, line := range strings.Split(rstEscaper.Replace(help), "\n") {
+			if line == "" {
+				fmt.Fprintf(w, "\n")
+			} else {
+				fmt.Fprintf(w, "   %s\n", line)
+			}
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if envar != "" {
+		fmt.Fprintf(w, "   Environment variable: ``%s``\n\n", envar)
+	}
+}
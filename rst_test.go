@@ -0,0 +1,88 @@
+package kingpin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRST(t *testing.T) {
+	app := New("tool", "A *useful* tool.").Author("Alice")
+	app.Flag("debug", "Enable debug_mode.").Short('d').Bool()
+	app.Flag("secret", "").Hidden().String()
+	add := app.Command("add", "Add a file.")
+	add.Flag("mode", "File mode.").OverrideDefaultFromEnvar("TOOL_MODE").OneOf("ro", "rw").String()
+	add.Arg("file", "File to add.").Required().String()
+
+	w := &bytes.Buffer{}
+	assert.NoError(t, app.WriteRST(w))
+	expected := `tool
+====
+
+A \*useful\* tool.
+
+::
+
+    tool [<flags>] <command> [<flags>] [<args> ...]
+
+Flags
+-----
+
+.. option:: -h, --help
+
+   Show help.
+
+.. option:: -d, --debug
+
+   Enable debug\_mode.
+
+Commands
+--------
+
+help
+~~~~
+
+Show help for a command.
+
+::
+
+    tool help [<command>]
+
+Args
+^^^^
+
+.. option:: <command>
+
+   Command name.
+
+add
+~~~
+
+Add a file.
+
+::
+
+    tool add [<flags>] <file>
+
+Flags
+^^^^^
+
+.. option:: --mode=MODE
+
+   File mode. (one of ro, rw)
+
+   Environment variable: ` + "``TOOL_MODE``" + `
+
+Args
+^^^^
+
+.. option:: <file>
+
+   File to add.
+
+:Author: Alice
+
+`
+	assert.Equal(t, expected, w.String())
+}
@@ -0,0 +1,359 @@
+package kingpin
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/alecthomas/units"
+)
+
+// Struct defines a flag for each exported field of the struct pointed to by
+// v, binding the parsed values directly to the fields.
+//
+// Flags are configured with a "kingpin" field tag of the form:
+//
+//	Verbose bool   `kingpin:"verbose,short=v,help=Enable verbose output."`
+//	Server  string `kingpin:"server,required,help=Server address."`
+//	Retries int    `kingpin:",default=3,help=Number of retries."`
+//
+// The first element is the flag name, which defaults to the hyphenated field
+// name (eg. "ServerPort" becomes "server-port"). Subsequent elements are
+// options: short=<c>, required, hidden, default=<value>, placeholder=<text>,
+// envar=<NAME> and help=<text>. As help text may contain commas, help must be
+// the last option; everything after "help=" is help. A field tagged with "-"
+// is skipped.
+//
+// Fields that are themselves structs define flags prefixed with the field's
+// flag name and the separator set by StructSeparator(), so Server.Port
+// becomes --server.port. Embedded structs, which must be of exported types,
+// are not prefixed. Options other than the name are not supported on struct
+// fields, and structs without exported fields, such as time.Time, are not
+// supported at all.
+//
+// Fields tagged with `positional:"true"` define positional arguments rather
+// than flags, in field order. Arguments support the required, help and
+// default options. A trailing slice field captures all remaining arguments.
+//
+// The values fields hold when Struct() is called become the defaults of
+// their flags and arguments, unless a default is given in the tag or the
+// flag or argument is required, so a struct may be pre-populated with
+// configuration that the command line overrides. Fields other than slices
+// and maps keep their values until parsed.
+//
+// If any field can not be bound, an error naming the field is returned and
+// no flags or arguments are added.
+func (a *Application) Struct(v interface{}) error {
+	return bindStruct(a.flagGroup, a.argGroup, v, a.structSeparator)
+}
+
+// StructSeparator sets the separator used between the names of nested struct
+// fields bound with Struct(). The default is ".".
+func (a *Application) StructSeparator(separator string) *Application {
+	a.structSeparator = separator
+	return a
+}
+
+// Struct defines flags and arguments for this command from the fields of a
+// struct. See Application.Struct() for details.
+func (c *CmdClause) Struct(v interface{}) error {
+	return bindStruct(c.flagGroup, c.argGroup, v, c.app.structSeparator)
+}
+
+type structTag struct {
+	name    string
+	skip    bool
+	options map[string]string
+}
+
+func parseStructTag(tag string) (*structTag, error) {
+	out := &structTag{options: map[string]string{}}
+	if tag == "-" {
+		out.skip = true
+		return out, nil
+	}
+	if i := strings.Index(tag, ",help="); i != -1 {
+		out.options["help"] = tag[i+len(",help="):]
+		tag = tag[:i]
+	}
+	parts := strings.Split(tag, ",")
+	out.name = parts[0]
+	last := ""
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		switch {
+		case len(kv) == 2:
+			last = kv[0]
+			out.options[last] = kv[1]
+		case part == "required" || part == "hidden":
+			out.options[part] = ""
+		case last != "":
+			// Commas are permitted in option values, eg. defaults.
+			out.options[last] += "," + part
+		default:
+			return nil, fmt.Errorf("unknown option '%s' in tag '%s'", part, tag)
+		}
+	}
+	return out, nil
+}
+
+// bindStruct adds the flags and arguments defined by the fields of v, only
+// once every field has been bound, so that an error leaves none behind.
+func bindStruct(flags *flagGroup, args *argGroup, v interface{}, separator string) error {
+	clauses := &structClauses{}
+	if err := clauses.bind(v, "", "", separator); err != nil {
+		return err
+	}
+	for _, flag := range clauses.flags {
+		flags.AddFlag(flag)
+	}
+	args.args = append(args.args, clauses.args...)
+	return nil
+}
+
+// structClauses collects the flags and arguments defined by a struct.
+type structClauses struct {
+	flags []*FlagClause
+	args  []*ArgClause
+}
+
+// bind defines flags and arguments for the fields of v. Flag names are
+// prefixed with prefix, and errors with path, the path of the fields of v.
+func (c *structClauses) bind(v interface{}, prefix, path, separator string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct but got %T", v)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag, err := parseStructTag(field.Tag.Get("kingpin"))
+		if err != nil {
+			return fmt.Errorf("%s%s: %s", path, field.Name, err)
+		}
+		if tag.skip {
+			continue
+		}
+		name := tag.name
+		if name == "" {
+			name = hyphenate(field.Name)
+		}
+		addr := rv.Field(i).Addr().Interface()
+		if _, ok := addr.(Value); !ok && field.Type.Kind() == reflect.Struct && hasExportedFields(field.Type) {
+			if len(tag.options) > 0 || field.Tag.Get("positional") != "" {
+				return fmt.Errorf("%s%s: options are not supported on struct fields", path, field.Name)
+			}
+			nested := prefix
+			if !field.Anonymous {
+				nested += name + separator
+			}
+			if err := c.bind(addr, nested, path+field.Name+".", separator); err != nil {
+				return err
+			}
+			continue
+		}
+		// Binding zeroes most fields, so the value is kept to restore and to
+		// use as the default.
+		var value Value
+		current := reflect.New(field.Type).Elem()
+		current.Set(rv.Field(i))
+		defaults := fieldDefaults(current)
+		if _, ok := tag.options["default"]; ok {
+			defaults = nil
+		}
+		if _, ok := tag.options["required"]; ok {
+			defaults = nil
+		}
+		if field.Tag.Get("positional") == "true" {
+			arg := newArg(prefix+name, tag.options["help"])
+			if err := applyArgStructTag(arg, tag); err != nil {
+				return fmt.Errorf("%s%s: %s", path, field.Name, err)
+			}
+			if err := bindField(&arg.parserMixin, addr); err != nil {
+				return fmt.Errorf("%s%s: %s", path, field.Name, err)
+			}
+			if len(defaults) > 0 {
+				arg.Default(defaults...)
+			}
+			value = arg.value
+			c.args = append(c.args, arg)
+		} else {
+			flag := newFlag(prefix+name, tag.options["help"])
+			if err := applyStructTag(flag, tag); err != nil {
+				return fmt.Errorf("%s%s: %s", path, field.Name, err)
+			}
+			if err := bindField(&flag.parserMixin, addr); err != nil {
+				return fmt.Errorf("%s%s: %s", path, field.Name, err)
+			}
+			if len(defaults) > 0 {
+				flag.Default(defaults...)
+			}
+			value = flag.value
+			c.flags = append(c.flags, flag)
+		}
+		if kind := field.Type.Kind(); kind != reflect.Slice && kind != reflect.Map {
+			rv.Field(i).Set(current)
+		} else if len(defaults) > 0 {
+			// Values are added to slices and maps, so they must start empty
+			// for the defaults to replace rather than repeat them.
+			resetValue(value)
+		}
+	}
+	return nil
+}
+
+// fieldDefaults returns the value of a struct field as the default values of
+// its flag or argument: one for each element of a slice, "key=value" for
+// each entry of a map, or none if the field is zero. Custom Values and files
+// have no defaults. v must be addressable.
+func fieldDefaults(v reflect.Value) []string {
+	if v.IsZero() {
+		return nil
+	}
+	if _, ok := v.Addr().Interface().(Value); ok {
+		return nil
+	}
+	if _, ok := v.Interface().(*os.File); ok {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		out := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return out
+	case reflect.Map:
+		out := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			out = append(out, fmt.Sprintf("%v=%v", key.Interface(), v.MapIndex(key).Interface()))
+		}
+		sort.Strings(out)
+		return out
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// hasExportedFields returns true if the struct type t has any exported
+// fields.
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func applyArgStructTag(arg *ArgClause, tag *structTag) error {
+	for option, value := range tag.options {
+		switch option {
+		case "required":
+			arg.Required()
+		case "default":
+			arg.Default(value)
+		case "help":
+		default:
+			return fmt.Errorf("option '%s' is not supported by positional arguments", option)
+		}
+	}
+	return nil
+}
+
+func applyStructTag(flag *FlagClause, tag *structTag) error {
+	for option, value := range tag.options {
+		switch option {
+		case "short":
+			if len(value) != 1 {
+				return fmt.Errorf("short flag must be a single character but got '%s'", value)
+			}
+			flag.Short(value[0])
+		case "required":
+			flag.Required()
+		case "hidden":
+			flag.Hidden()
+		case "default":
+			flag.Default(value)
+		case "placeholder":
+			flag.PlaceHolder(value)
+		case "envar":
+			flag.OverrideDefaultFromEnvar(value)
+		case "help":
+		default:
+			return fmt.Errorf("option '%s' is not supported by flags", option)
+		}
+	}
+	return nil
+}
+
+// bindField sets the parser for a clause from a pointer to a struct field.
+func bindField(p *parserMixin, field interface{}) error {
+	switch target := field.(type) {
+	case Value:
+		p.SetValue(target)
+	case *string:
+		p.StringVar(target)
+	case *[]string:
+		p.StringsVar(target)
+	case *map[string]string:
+		if *target == nil {
+			*target = map[string]string{}
+		}
+		p.StringMapVar(target)
+	case *bool:
+		p.BoolVar(target)
+	case *int:
+		p.IntVar(target)
+	case *int64:
+		p.Int64Var(target)
+	case *uint64:
+		p.Uint64Var(target)
+	case *float64:
+		p.FloatVar(target)
+	case *time.Duration:
+		p.DurationVar(target)
+	case *units.Base2Bytes:
+		p.BytesVar(target)
+	case *net.IP:
+		p.IPVar(target)
+	case **net.TCPAddr:
+		p.TCPVar(target)
+	case *[]*net.TCPAddr:
+		p.TCPListVar(target)
+	case **url.URL:
+		p.URLVar(target)
+	case *[]*url.URL:
+		p.URLListVar(target)
+	case **os.File:
+		p.FileVar(target)
+	default:
+		return fmt.Errorf("unsupported field type %T", field)
+	}
+	return nil
+}
+
+// hyphenate converts a CamelCase identifier to lower-case-hyphenated form.
+func hyphenate(name string) string {
+	out := []rune{}
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				out = append(out, '-')
+			}
+			r = unicode.ToLower(r)
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
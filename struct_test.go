@@ -0,0 +1,180 @@
+package kingpin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStruct(t *testing.T) {
+	cfg := struct {
+		Verbose    bool          `kingpin:"verbose,short=v,help=Enable verbose output."`
+		Server     string        `kingpin:",required,help=Server address, as host:port."`
+		Timeout    time.Duration `kingpin:",default=5s"`
+		MaxRetries int
+		Ignored    string `kingpin:"-"`
+		internal   string
+	}{}
+	app := New("test", "")
+	assert.NoError(t, app.Struct(&cfg))
+	_, err := app.Parse([]string{"-v", "--server=localhost:80", "--max-retries=3"})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Verbose)
+	assert.Equal(t, "localhost:80", cfg.Server)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, 3, cfg.MaxRetries)
+	assert.Equal(t, "Server address, as host:port.", app.long["server"].help)
+	_, ok := app.long["ignored"]
+	assert.False(t, ok)
+}
+
+func TestStructPrepopulated(t *testing.T) {
+	cfg := struct {
+		Port    int
+		Host    string
+		Tags    []string
+		Labels  map[string]string
+		Retries int    `kingpin:",default=3"`
+		Name    string `positional:"true"`
+	}{Port: 8080, Host: "example", Tags: []string{"a", "b"}, Labels: map[string]string{"k": "v"}, Retries: 5, Name: "default"}
+	app := New("test", "")
+	assert.NoError(t, app.Struct(&cfg))
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "example", cfg.Host)
+	assert.Equal(t, []string{"8080"}, app.GetFlag("port").defaultValues)
+
+	_, err := app.Parse([]string{"--host=override"})
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "override", cfg.Host)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, map[string]string{"k": "v"}, cfg.Labels)
+	assert.Equal(t, 3, cfg.Retries)
+	assert.Equal(t, "default", cfg.Name)
+
+	_, err = app.Parse([]string{"--tags=c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c"}, cfg.Tags)
+}
+
+func TestStructUnknownOption(t *testing.T) {
+	cfg := struct {
+		Retries int `kingpin:",defualt=3"`
+	}{}
+	err := New("test", "").Struct(&cfg)
+	assert.EqualError(t, err, "Retries: option 'defualt' is not supported by flags")
+}
+
+func TestStructUnsupportedField(t *testing.T) {
+	cfg := struct {
+		C chan int
+	}{}
+	app := New("test", "")
+	assert.Error(t, app.Struct(&cfg))
+	assert.Error(t, app.Struct(cfg))
+}
+
+func TestHyphenate(t *testing.T) {
+	assert.Equal(t, "server-port", hyphenate("ServerPort"))
+	assert.Equal(t, "http-server", hyphenate("HTTPServer"))
+	assert.Equal(t, "id", hyphenate("ID"))
+}
+
+type testServerConfig struct {
+	Host string `kingpin:",default=localhost"`
+	Port int
+}
+
+type CommonTestConfig struct {
+	Debug bool
+}
+
+func TestStructNested(t *testing.T) {
+	cfg := struct {
+		CommonTestConfig
+		Server  testServerConfig
+		Backend testServerConfig `kingpin:"db"`
+	}{}
+	app := New("test", "")
+	assert.NoError(t, app.Struct(&cfg))
+	_, err := app.Parse([]string{"--debug", "--server.port=80", "--db.host=db"})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Debug)
+	assert.Equal(t, "localhost", cfg.Server.Host)
+	assert.Equal(t, 80, cfg.Server.Port)
+	assert.Equal(t, "db", cfg.Backend.Host)
+}
+
+func TestStructNestedSeparator(t *testing.T) {
+	cfg := struct {
+		Server testServerConfig
+	}{}
+	app := New("test", "").StructSeparator("-")
+	assert.NoError(t, app.Struct(&cfg))
+	_, err := app.Parse([]string{"--server-port=80"})
+	assert.NoError(t, err)
+	assert.Equal(t, 80, cfg.Server.Port)
+}
+
+func TestStructPositional(t *testing.T) {
+	cfg := struct {
+		Force  bool
+		Source string   `positional:"true" kingpin:",required,help=Source file."`
+		Dests  []string `positional:"true"`
+	}{}
+	app := New("test", "")
+	cmd := app.Command("copy", "")
+	assert.NoError(t, cmd.Struct(&cfg))
+	_, err := app.Parse([]string{"copy", "--force", "a", "b", "c"})
+	assert.NoError(t, err)
+	assert.True(t, cfg.Force)
+	assert.Equal(t, "a", cfg.Source)
+	assert.Equal(t, []string{"b", "c"}, cfg.Dests)
+	_, err = app.Parse([]string{"copy"})
+	assert.Error(t, err)
+}
+
+func TestStructPositionalInvalidOption(t *testing.T) {
+	cfg := struct {
+		Source string `positional:"true" kingpin:",short=s"`
+	}{}
+	assert.Error(t, New("test", "").Struct(&cfg))
+}
+
+func TestStructHelpIsLast(t *testing.T) {
+	cfg := struct {
+		Mode string `kingpin:"mode,short=m,help=Mode, required unless --auto,hidden"`
+	}{}
+	app := New("test", "")
+	assert.NoError(t, app.Struct(&cfg))
+	flag := app.GetFlag("mode")
+	assert.Equal(t, "Mode, required unless --auto,hidden", flag.help)
+	assert.False(t, flag.required)
+	assert.False(t, flag.hidden)
+}
+
+func TestStructUnsupportedNestedFields(t *testing.T) {
+	type inner struct {
+		Name string
+		Ch   chan int
+	}
+	app := New("test", "")
+	err := app.Struct(&struct {
+		Debug bool
+		Inner inner
+	}{})
+	assert.EqualError(t, err, "Inner.Ch: unsupported field type *chan int")
+	// No flags are added unless every field is bound.
+	assert.Nil(t, app.GetFlag("inner.ch"))
+	assert.Nil(t, app.GetFlag("inner.name"))
+	assert.Nil(t, app.GetFlag("debug"))
+
+	err = New("test", "").Struct(&struct{ Created time.Time }{})
+	assert.EqualError(t, err, "Created: unsupported field type *time.Time")
+
+	err = New("test", "").Struct(&struct {
+		Inner inner `kingpin:"in,required"`
+	}{})
+	assert.EqualError(t, err, "Inner: options are not supported on struct fields")
+}
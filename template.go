@@ -0,0 +1,96 @@
+package kingpin
+
+import (
+	"bytes"
+	"io"
+	"text/template"
+)
+
+// TemplateContext is the data passed to custom usage templates.
+type TemplateContext struct {
+	// App is the model of the whole application.
+	App *ApplicationModel
+	// SelectedCommand is the command usage is being displayed for, or nil
+	// when displaying the usage of the application.
+	SelectedCommand *CmdModel
+	// Flags and Args are those of the selected command, or of the application
+	// if there is no selected command.
+	Flags []*FlagModel
+	Args  []*ArgModel
+	// InheritedFlags are the persistent flags of the selected command's
+	// parents that are also available to it.
+	InheritedFlags []*FlagModel
+	// Width is the width of the terminal.
+	Width int
+	// Version and Author are the application's metadata.
+	Version string
+	Author  string
+}
+
+// newTemplateContext creates the context for rendering the usage of cmd,
+// which may be nil.
+func (a *Application) newTemplateContext(cmd *CmdClause, width int) *TemplateContext {
+	app := a.Model()
+	context := &TemplateContext{
+		App:     app,
+		Flags:   app.Flags,
+		Args:    app.Args,
+		Width:   width,
+		Version: app.Version,
+		Author:  app.Author,
+	}
+	if cmd != nil {
+		for _, model := range app.FlattenedCommands() {
+			if model.FullCommand == cmd.FullCommand() {
+				context.SelectedCommand = model
+				context.Flags = model.Flags
+				context.Args = model.Args
+			}
+		}
+		for _, flag := range cmd.inheritedFlags() {
+			context.InheritedFlags = append(context.InheritedFlags, flag.Model())
+		}
+	}
+	return context
+}
+
+// UsageTemplate sets a custom text/template used to render usage, in place of
+// the built-in usage output. The template is executed with a TemplateContext.
+//
+// If the template fails to parse or execute, the built-in usage is displayed
+// instead and the error is reported to the OnTemplateError() handler.
+func (a *Application) UsageTemplate(text string) *Application {
+	a.usageTemplate = text
+	return a
+}
+
+// OnTemplateError sets a function to be called when a custom usage template
+// fails. By default the error is printed to the error writer.
+func (a *Application) OnTemplateError(handler func(err error)) *Application {
+	a.onTemplateError = handler
+	return a
+}
+
+// renderTemplate renders the custom usage template, if any, to w. It returns
+// false if there is no template or it failed, in which case nothing is
+// written to w.
+func (a *Application) renderTemplate(w io.Writer, cmd *CmdClause, width int) bool {
+	if a.usageTemplate == "" {
+		return false
+	}
+	buf := bytes.NewBuffer(nil)
+	tmpl, err := template.New("usage").Parse(a.usageTemplate)
+	if err == nil {
+		err = tmpl.Execute(buf, a.newTemplateContext(cmd, width))
+	}
+	if err != nil {
+		if a.onTemplateError != nil {
+			a.onTemplateError(err)
+		} else {
+			a.Errorf(a.errorWriter, a.messages.TemplateError, err)
+		}
+		return false
+	}
+	buf.WriteTo(w)
+	return true
+}
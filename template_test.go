@@ -0,0 +1,53 @@
+package kingpin
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageTemplate(t *testing.T) {
+	app := New("test", "A test.").UsageTemplate("{{.App.Name}}: {{.App.Help}}\n")
+	w := bytes.NewBuffer(nil)
+	app.Usage(w)
+	assert.Equal(t, "test: A test.\n", w.String())
+}
+
+func TestUsageTemplateFallback(t *testing.T) {
+	var templateErr error
+	app := New("test", "A test.").
+		UsageTemplate("{{.App.Missing}}").
+		OnTemplateError(func(err error) { templateErr = err })
+	w := bytes.NewBuffer(nil)
+	app.Usage(w)
+	assert.Error(t, templateErr)
+	assert.Contains(t, w.String(), "usage: test")
+}
+
+func TestUsageTemplateContext(t *testing.T) {
+	app := New("test", "").Version("1.0").Author("Alec").
+		UsageTemplate("{{.Version}} {{.Author}} {{.Width}}{{with .SelectedCommand}} {{.FullCommand}}{{end}}:{{range .Flags}} --{{.Name}}{{end}}{{range .Args}} <{{.Name}}>{{end}}\n")
+	app.Flag("debug", "").Bool()
+	cmd := app.Command("cmd", "")
+	cmd.Flag("force", "").Bool()
+	cmd.Arg("file", "").String()
+	w := bytes.NewBuffer(nil)
+	app.usage(w, 80)
+	assert.Equal(t, "1.0 Alec 80: --help --version --debug\n", w.String())
+	w.Reset()
+	app.commandUsage(w, cmd, 80)
+	assert.Equal(t, "1.0 Alec 80 cmd: --help --force <file>\n", w.String())
+}
+
+func TestUsageTemplateInheritedFlags(t *testing.T) {
+	app := New("test", "").UsageTemplate("{{range .Flags}}--{{.Name}} {{end}}|{{range .InheritedFlags}} --{{.Name}}{{end}}\n")
+	app.Flag("debug", "").Bool()
+	remote := app.Command("remote", "")
+	remote.PersistentFlag("verbose", "").Bool()
+	add := remote.Command("add", "")
+	add.Flag("fetch", "").Bool()
+	w := bytes.NewBuffer(nil)
+	app.commandUsage(w, add, 80)
+	assert.Equal(t, "--help --fetch | --verbose\n", w.String())
+}
@@ -6,17 +6,54 @@ import (
 	"go/doc"
 	"io"
 	"strings"
+	"unicode"
 )
 
 var (
 	preIndent = "  "
 )
 
-func formatTwoColumns(w io.Writer, indent, padding, width int, rows [][2]string) {
+// UsageLayout controls the layout of the built-in usage output.
+type UsageLayout struct {
+	// Indent is the number of spaces before each flag, argument and command.
+	Indent int
+	// Padding is the number of spaces between flags or arguments and their
+	// help.
+	Padding int
+	// MaxFirstColumn is the widest flag or argument that help is aligned
+	// after. The help of wider ones starts on the next line.
+	MaxFirstColumn int
+	// MaxWidth, if not 0, limits the width of usage on wide terminals.
+	MaxWidth int
+}
+
+// DefaultUsageLayout is the layout used unless overridden with
+// Application.UsageLayout().
+var DefaultUsageLayout = UsageLayout{
+	Indent:         2,
+	Padding:        2,
+	MaxFirstColumn: 20,
+}
+
+// UsageLayout sets the indentation and wrapping of the built-in usage
+// output, eg. to match house style. The layout is used as given, so fields
+// may be set to 0; to change only some of them, start from a copy of
+// DefaultUsageLayout:
+//
+//	layout := kingpin.DefaultUsageLayout
+//	layout.MaxWidth = 100
+//	app.UsageLayout(layout)
+func (a *Application) UsageLayout(layout UsageLayout) *Application {
+	a.layout = &layout
+	return a
+}
+
+func (l *UsageLayout) formatTwoColumns(w io.Writer, width int, rows [][2]string) {
+	indent, padding := l.Indent, l.Padding
 	// Find size of first column.
 	s := 0
 	for _, row := range rows {
-		if c := len(row[0]); c > s && c < 20 {
+		if c := displayWidth(row[0]); c > s && c < l.MaxFirstColumn {
 			s = c
 		}
 	}
@@ -28,8 +65,9 @@ func formatTwoColumns(w io.Writer, indent, padding, width int, rows [][2]string)
 		buf := bytes.NewBuffer(nil)
 		doc.ToText(buf, row[1], "", preIndent, width-s-padding-indent)
 		lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
-		fmt.Fprintf(w, "%s%-*s%*s", indentStr, s, row[0], padding, "")
-		if len(row[0]) >= 20 {
+		c := displayWidth(row[0])
+		fmt.Fprintf(w, "%s%s%*s", indentStr, row[0], padding+maxInt(s-c, 0), "")
+		if c >= l.MaxFirstColumn {
 			fmt.Fprintf(w, "\n%s%s", indentStr, offsetStr)
 		}
 		fmt.Fprintf(w, "%s\n", lines[0])
@@ -39,37 +77,110 @@ func formatTwoColumns(w io.Writer, indent, padding, width int, rows [][2]string)
 	}
 }
 
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// wideRanges are the East Asian wide and full-width code points, which are
+// displayed in two columns.
+var wideRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{0x1100, 0x115f, 1},
+		{0x2e80, 0x303e, 1},
+		{0x3041, 0x33ff, 1},
+		{0x3400, 0x4dbf, 1},
+		{0x4e00, 0x9fff, 1},
+		{0xa000, 0xa4cf, 1},
+		{0xac00, 0xd7a3, 1},
+		{0xf900, 0xfaff, 1},
+		{0xfe30, 0xfe4f, 1},
+		{0xff00, 0xff60, 1},
+		{0xffe0, 0xffe6, 1},
+	},
+	R32: []unicode.Range32{
+		{0x1f300, 0x1f64f, 1},
+		{0x1f900, 0x1f9ff, 1},
+		{0x20000, 0x2fffd, 1},
+		{0x30000, 0x3fffd, 1},
+	},
+}
+
+// displayWidth returns the number of terminal columns used to display s.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case unicode.Is(wideRanges, r):
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
 func (a *Application) Usage(w io.Writer) {
-	a.writeHelp(guessWidth(w), w)
+	a.usage(w, a.width(w))
+}
+
+func (a *Application) usage(w io.Writer, width int) {
+	if a.renderTemplate(w, nil, width) {
+		return
+	}
+	a.writeHelp(width, w)
 }
 
 func (a *Application) CommandUsage(w io.Writer, command string) {
 	cmd := a.findCommand(command)
 	if cmd == nil {
-		a.Fatalf(w, "unknown command '%s'", command)
+		a.Fatalf(w, a.messages.UnknownCommand, command)
+		return
+	}
+	a.commandUsage(w, cmd, a.width(w))
+}
+
+func (a *Application) commandUsage(w io.Writer, cmd *CmdClause, width int) {
+	if a.renderTemplate(w, cmd, width) {
+		return
 	}
 	s := []string{formatArgsAndFlags(a.Name, a.argGroup, a.flagGroup, cmd.cmdGroup)}
 	s = append(s, formatArgsAndFlags(cmd.FullCommand(), cmd.argGroup, cmd.flagGroup, cmd.cmdGroup))
-	fmt.Fprintf(w, "usage: %s\n", strings.Join(s, " "))
+	fmt.Fprintf(w, "%s%s\n", a.messages.UsagePrefix, strings.Join(s, " "))
 	if cmd.help != "" {
 		fmt.Fprintf(w, "\n%s\n", cmd.help)
 	}
-	cmd.writeHelp(guessWidth(w), w)
+	cmd.writeHelp(width, a.layout, w, a.messages)
+	a.writeFooter(w)
 }
 
-func (a *Application) findCommand(command string) *CmdClause {
-	parts := strings.Split(command, " ")
-	var cmd *CmdClause
-	group := a.cmdGroup
-	for _, part := range parts {
-		next, ok := group.commands[part]
-		if !ok {
-			return nil
-		}
-		cmd = next
-		group = next.cmdGroup
+// HelpSnapshot renders the usage of the application followed by the usage of
+// every command, at a fixed width. The output does not depend on the terminal
+// or environment, so it is suitable for comparing against a golden file to
+// detect unintended changes to help.
+func (a *Application) HelpSnapshot(width int) (string, error) {
+	a.parseLock.Lock()
+	defer a.parseLock.Unlock()
+	if err := a.init(); err != nil {
+		return "", err
 	}
-	return cmd
+	w := &bytes.Buffer{}
+	a.usage(w, width)
+	err := a.Walk(func(clause interface{}, path []string) error {
+		if cmd, ok := clause.(*CmdClause); ok {
+			fmt.Fprintf(w, "\n--- %s ---\n\n", cmd.FullCommand())
+			a.commandUsage(w, cmd, width)
+		}
+		return nil
+	})
+	return w.String(), err
+}
+
+func (a *Application) findCommand(command string) *CmdClause {
+	return a.GetCommand(strings.Split(command, " ")...)
 }
 
 func (a *Application) writeHelp(width int, w io.Writer) {
@@ -78,40 +189,75 @@ func (a *Application) writeHelp(width int, w io.Writer) {
 		s = append(s, "<command>", "[<flags>]", "[<args> ...]")
 	}
 
-	prefix := "usage: "
+	prefix := a.messages.UsagePrefix
 	usage := strings.Join(s, " ")
 	buf := bytes.NewBuffer(nil)
-	doc.ToText(buf, usage, "", preIndent, width-len(prefix))
+	doc.ToText(buf, usage, "", preIndent, width-displayWidth(prefix))
 	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
 
 	fmt.Fprintf(w, "%s%s\n", prefix, lines[0])
 	for _, l := range lines[1:] {
-		fmt.Fprintf(w, "%*s%s\n", len(prefix), "", l)
+		fmt.Fprintf(w, "%*s%s\n", displayWidth(prefix), "", l)
 	}
 	if a.Help != "" {
 		fmt.Fprintf(w, "\n")
 		doc.ToText(w, a.Help, "", preIndent, width)
 	}
 
-	a.flagGroup.writeHelp(width, w)
-	a.argGroup.writeHelp(width, w)
-	a.cmdGroup.writeHelp(width, w)
+	a.flagGroup.writeHelp(width, a.layout, w, a.messages)
+	a.argGroup.writeHelp(width, a.layout, w, a.messages)
+	a.cmdGroup.writeHelp(width, a.layout, w, a.messages)
+	a.writeFooter(w)
 }
 
-func (f *flagGroup) writeHelp(width int, w io.Writer) {
-	if f.visibleFlags() == 0 {
+// writeFooter writes the application's metadata, if any.
+func (a *Application) writeFooter(w io.Writer) {
+	rows := [][2]string{}
+	if a.author != "" {
+		rows = append(rows, [2]string{a.messages.AuthorTitle, a.author})
+	}
+	if a.homepage != "" {
+		rows = append(rows, [2]string{a.messages.HomepageTitle, a.homepage})
+	}
+	if a.bugReports != "" {
+		rows = append(rows, [2]string{a.messages.BugReportsTitle, a.bugReports})
+	}
+	if len(rows) == 0 {
 		return
 	}
+	fmt.Fprintf(w, "\n")
+	for _, row := range rows {
+		fmt.Fprintf(w, "%s %s\n", row[0], row[1])
+	}
+}
 
-	fmt.Fprintf(w, "\nFlags:\n")
+func (f *flagGroup) writeHelp(width int, layout *UsageLayout, w io.Writer, msg *Messages) {
+	if f.visibleFlags() == 0 {
+		return
+	}
 
-	rows := [][2]string{}
+	sections := []string{""}
+	rows := map[string][][2]string{}
 	for _, flag := range f.flagOrder {
-		if !flag.hidden {
-			rows = append(rows, [2]string{formatFlag(flag), flag.help})
+		if flag.hidden {
+			continue
 		}
+		if _, ok := rows[flag.section]; !ok && flag.section != "" {
+			sections = append(sections, flag.section)
+		}
+		rows[flag.section] = append(rows[flag.section], [2]string{formatFlag(flag), flag.helpText(flag.help, msg)})
+	}
+	for _, section := range sections {
+		if len(rows[section]) == 0 {
+			continue
+		}
+		title := section + ":"
+		if section == "" {
+			title = msg.FlagsTitle
+		}
+		fmt.Fprintf(w, "\n%s\n", title)
+		layout.formatTwoColumns(w, width, rows[section])
 	}
-	formatTwoColumns(w, 2, 2, width, rows)
 }
 
 func (f *flagGroup) gatherFlagSummary() (out []string) {
@@ -124,6 +270,8 @@ func (f *flagGroup) gatherFlagSummary() (out []string) {
 			fb, ok := flag.value.(boolFlag)
 			if ok && fb.IsBoolFlag() {
 				out = append(out, fmt.Sprintf("--%s", flag.name))
+			} else if flag.optionalValue {
+				out = append(out, fmt.Sprintf("--%s[=%s]", flag.name, flag.formatPlaceHolder()))
 			} else {
 				out = append(out, fmt.Sprintf("--%s=%s", flag.name, flag.formatPlaceHolder()))
 			}
@@ -135,44 +283,64 @@ func (f *flagGroup) gatherFlagSummary() (out []string) {
 	return
 }
 
-func (a *argGroup) writeHelp(width int, w io.Writer) {
-	if len(a.args) == 0 {
+func (a *argGroup) writeHelp(width int, layout *UsageLayout, w io.Writer, msg *Messages) {
+	if a.visibleArgs() == 0 {
 		return
 	}
 
-	fmt.Fprintf(w, "\nArgs:\n")
+	fmt.Fprintf(w, "\n%s\n", msg.ArgsTitle)
 
 	rows := [][2]string{}
 	for _, arg := range a.args {
+		if arg.hidden {
+			continue
+		}
 		s := "<" + arg.name + ">"
 		if !arg.required {
 			s = "[" + s + "]"
 		}
-		rows = append(rows, [2]string{s, arg.help})
+		rows = append(rows, [2]string{s, arg.helpText(arg.help, msg)})
 	}
 
-	formatTwoColumns(w, 2, 2, width, rows)
+	layout.formatTwoColumns(w, width, rows)
+}
+
+func (a *CmdClause) writeHelp(width int, layout *UsageLayout, w io.Writer, msg *Messages) {
+	a.flagGroup.writeHelp(width, layout, w, msg)
+	a.writeInheritedFlags(width, layout, w, msg)
+	a.argGroup.writeHelp(width, layout, w, msg)
+	a.cmdGroup.writeHelp(width, layout, w, msg)
 }
 
-func (a *CmdClause) writeHelp(width int, w io.Writer) {
-	a.flagGroup.writeHelp(width, w)
-	a.argGroup.writeHelp(width, w)
-	a.cmdGroup.writeHelp(width, w)
+// writeInheritedFlags writes the persistent flags of the command's parents.
+func (a *CmdClause) writeInheritedFlags(width int, layout *UsageLayout, w io.Writer, msg *Messages) {
+	rows := [][2]string{}
+	for _, flag := range a.inheritedFlags() {
+		if !flag.hidden {
+			rows = append(rows, [2]string{formatFlag(flag), flag.helpText(flag.help, msg)})
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", msg.GlobalFlagsTitle)
+	layout.formatTwoColumns(w, width, rows)
 }
 
-func (c *cmdGroup) writeHelp(width int, w io.Writer) {
+func (c *cmdGroup) writeHelp(width int, layout *UsageLayout, w io.Writer, msg *Messages) {
 	if len(c.commands) == 0 {
 		return
 	}
-	fmt.Fprintf(w, "\nCommands:\n")
+	fmt.Fprintf(w, "\n%s\n", msg.CommandsTitle)
+	indent := strings.Repeat(" ", layout.Indent)
 	flattened := c.flattenedCommands()
 	for _, cmd := range flattened {
-		fmt.Fprintf(w, "  %s\n", formatArgsAndFlags(cmd.FullCommand(), cmd.argGroup, cmd.flagGroup, cmd.cmdGroup))
+		fmt.Fprintf(w, "%s%s\n", indent, formatArgsAndFlags(cmd.FullCommand(), cmd.argGroup, cmd.flagGroup, cmd.cmdGroup))
 		buf := bytes.NewBuffer(nil)
-		doc.ToText(buf, cmd.help, "", preIndent, width-4)
+		doc.ToText(buf, cmd.help, "", preIndent, width-2*layout.Indent)
 		lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
 		for _, line := range lines {
-			fmt.Fprintf(w, "    %s\n", line)
+			fmt.Fprintf(w, "%s%s%s\n", indent, indent, line)
 		}
 		fmt.Fprintf(w, "\n")
 	}
@@ -183,6 +351,9 @@ func formatArgsAndFlags(name string, args *argGroup, flags *flagGroup, commands
 	s = append(s, flags.gatherFlagSummary()...)
 	depth := 0
 	for _, arg := range args.args {
+		if arg.hidden {
+			continue
+		}
 		h := "<" + arg.name + ">"
 		if !arg.required {
 			h = "[" + h
@@ -201,7 +372,9 @@ func formatFlag(flag *FlagClause) string {
 	}
 	flagString += fmt.Sprintf("--%s", flag.name)
 	fb, ok := flag.value.(boolFlag)
-	if !ok || !fb.IsBoolFlag() {
+	if flag.optionalValue {
+		flagString += fmt.Sprintf("[=%s]", flag.formatPlaceHolder())
+	} else if !ok || !fb.IsBoolFlag() {
 		flagString += fmt.Sprintf("=%s", flag.formatPlaceHolder())
 	}
 	return flagString
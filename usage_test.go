@@ -3,6 +3,8 @@ package kingpin
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"testing"
 
@@ -11,7 +13,7 @@ import (
 
 func TestFormatTwoColumns(t *testing.T) {
 	buf := bytes.NewBuffer(nil)
-	formatTwoColumns(buf, 2, 2, 20, [][2]string{
+	DefaultUsageLayout.formatTwoColumns(buf, 20, [][2]string{
 		{"--hello", "Hello world help with something that is cool."},
 	})
 	expected := `  --hello  Hello
@@ -29,7 +31,8 @@ func TestFormatTwoColumnsWide(t *testing.T) {
 		{strings.Repeat("x", 19), "19 chars"},
 		{strings.Repeat("x", 20), "20 chars"}}
 	buf := bytes.NewBuffer(nil)
-	formatTwoColumns(buf, 0, 0, 200, samples)
+	layout := UsageLayout{MaxFirstColumn: 20}
+	layout.formatTwoColumns(buf, 200, samples)
 	fmt.Println(buf.String())
 	expected := `xxxxxxxxxxxxxxxxxxx19 chars
 xxxxxxxxxxxxxxxxxxxx
@@ -37,3 +40,177 @@ xxxxxxxxxxxxxxxxxxxx
 `
 	assert.Equal(t, expected, buf.String())
 }
+
+func TestHelpSnapshot(t *testing.T) {
+	app := New("test", "A test.")
+	app.Flag("debug", "Enable debug.").Bool()
+	cmd := app.Command("cmd", "A command.")
+	cmd.Flag("force", "Force.").Bool()
+	cmd.Command("sub", "A sub-command.").Arg("arg", "An argument.").String()
+
+	os.Setenv("COLUMNS", "20")
+	defer os.Unsetenv("COLUMNS")
+	snapshot, err := app.HelpSnapshot(80)
+	assert.NoError(t, err)
+	assert.Contains(t, snapshot, "usage: test [<flags>] <command> [<flags>] [<args> ...]\n")
+	assert.Contains(t, snapshot, "--- cmd ---\n\nusage: test [<flags>] cmd [<flags>]\n")
+	assert.Contains(t, snapshot, "--- cmd sub ---\n\nusage: test [<flags>] cmd sub [<arg>]\n")
+	assert.Contains(t, snapshot, "  --force  Force.\n")
+	again, err := app.HelpSnapshot(80)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshot, again)
+}
+
+func TestUsageFooter(t *testing.T) {
+	app := New("test", "").Author("Alec").BugReports("https://example.com/issues")
+	app.Command("cmd", "")
+	w := bytes.NewBuffer(nil)
+	app.usage(w, 80)
+	assert.True(t, strings.HasSuffix(w.String(), "\nAuthor: Alec\nReport bugs to: https://example.com/issues\n"), w.String())
+	w.Reset()
+	app.commandUsage(w, app.findCommand("cmd"), 80)
+	assert.Contains(t, w.String(), "Author: Alec\n")
+}
+
+func TestDisplayWidth(t *testing.T) {
+	assert.Equal(t, 5, displayWidth("hello"))
+	assert.Equal(t, 6, displayWidth("--ñame"))
+	assert.Equal(t, 4, displayWidth("名前"))
+	assert.Equal(t, 1, displayWidth("e\u0301"))
+}
+
+func TestFormatTwoColumnsUnicode(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	DefaultUsageLayout.formatTwoColumns(buf, 80, [][2]string{
+		{"--名前", "名前を設定します。"},
+		{"--ñame", "Name."},
+		{"--name", "Name."},
+	})
+	expected := `  --名前  名前を設定します。
+  --ñame  Name.
+  --name  Name.
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestFlagSections(t *testing.T) {
+	app := New("test", "")
+	app.Flag("output", "Output file.").Section("Output options").String()
+	app.Flag("verbose", "Verbose.").Bool()
+	app.Flag("timeout", "Timeout.").Section("Connection options").Duration()
+	app.Flag("silent", "Silent.").Section("Output options").Bool()
+	app.Flag("secret", "Secret.").Section("Secret options").Hidden().Bool()
+	w := bytes.NewBuffer(nil)
+	app.usage(w, 80)
+	expected := `
+Flags:
+  --help     Show help.
+  --verbose  Verbose.
+
+Output options:
+  --output=OUTPUT  Output file.
+  --silent         Silent.
+
+Connection options:
+  --timeout=TIMEOUT  Timeout.
+`
+	assert.Contains(t, w.String(), expected)
+	assert.NotContains(t, w.String(), "Secret")
+}
+
+func TestCommandUsageIsScoped(t *testing.T) {
+	app := New("tool", "")
+	app.Flag("debug", "Debug.").Bool()
+	remote := app.Command("remote", "")
+	remote.PersistentFlag("verbose", "Verbose.").Bool()
+	remote.Flag("local", "Local.").Bool()
+	add := remote.Command("add", "Add.")
+	add.Flag("fetch", "Fetch.").Bool()
+	w := bytes.NewBuffer(nil)
+	app.commandUsage(w, add, 80)
+	expected := `usage: tool [<flags>] remote add [<flags>]
+
+Add.
+
+Flags:
+  --fetch  Fetch.
+
+Global flags:
+  --verbose  Verbose.
+`
+	assert.Equal(t, expected, w.String())
+}
+
+func TestWidthDetector(t *testing.T) {
+	var detected io.Writer
+	app := New("test", "").WidthDetector(WidthDetectorFunc(func(w io.Writer) int {
+		detected = w
+		return 40
+	}))
+	app.Flag("name", "The name of the thing that is being configured.").String()
+	w := &bytes.Buffer{}
+	app.Usage(w)
+	assert.Equal(t, w, detected)
+	assert.Contains(t, w.String(), "  --name=NAME  The name of the thing\n               that is being configured.\n")
+
+	w.Reset()
+	app.WidthDetector(FixedWidth(200)).Usage(w)
+	assert.Contains(t, w.String(), "  --name=NAME  The name of the thing that is being configured.\n")
+}
+
+func TestUsageLayout(t *testing.T) {
+	app := New("test", "").WidthDetector(FixedWidth(200)).UsageLayout(UsageLayout{
+		Indent:         4,
+		Padding:        1,
+		MaxFirstColumn: 12,
+		MaxWidth:       40,
+	})
+	app.Flag("name", "The name of the thing that is being configured.").String()
+	app.Flag("a-very-long-flag", "Long.").Bool()
+	app.Command("run", "Run the thing that has been configured.")
+	w := &bytes.Buffer{}
+	app.Usage(w)
+	assert.Contains(t, w.String(), `
+    --name=NAME The name of the
+                thing that is being
+                configured.
+    --a-very-long-flag `+`
+                Long.
+`)
+	assert.Contains(t, w.String(), `
+    run
+        Run the thing that has been
+        configured.
+`)
+}
+
+func TestPartialUsageLayout(t *testing.T) {
+	layout := DefaultUsageLayout
+	layout.MaxWidth = 40
+	app := New("test", "").WidthDetector(FixedWidth(200)).UsageLayout(layout)
+	app.Flag("name", "The name of the thing that is being configured.").String()
+	app.Command("run", "Run the thing that has been configured.")
+	w := &bytes.Buffer{}
+	app.Usage(w)
+	assert.Contains(t, w.String(), `
+  --help       Show help.
+  --name=NAME  The name of the thing
+               that is being configured.
+`)
+	assert.Contains(t, w.String(), `
+  run
+    Run the thing that has been
+    configured.
+`)
+}
+
+func TestUsageLayoutWithoutIndent(t *testing.T) {
+	app := New("test", "").WidthDetector(FixedWidth(80)).UsageLayout(UsageLayout{
+		Padding:        1,
+		MaxFirstColumn: 20,
+	})
+	app.Flag("name", "The name.").String()
+	w := &bytes.Buffer{}
+	app.Usage(w)
+	assert.Contains(t, w.String(), "\n--help      Show help.\n--name=NAME The name.\n")
+}
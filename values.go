@@ -1,16 +1,26 @@
 package kingpin
 
 import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
 	"net"
 	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/alecthomas/units"
+	"gopkg.in/yaml.v3"
 )
 
 // NOTE: Most of the base type values were lifted from:
@@ -22,7 +32,7 @@ import (
 // If a Value has an IsBoolFlag() bool method returning true, the command-line
 // parser makes --name equivalent to -name=true rather than using the next
 // command-line argument, and adds a --no-name counterpart for negating the
-// flag.
+// flag. An explicit value may still be attached, as in --name=false.
 type Value interface {
 	String() string
 	Set(string) error
@@ -44,6 +54,12 @@ type boolFlag interface {
 	IsBoolFlag() bool
 }
 
+// Optional interface for values that depend on other values, and are parsed
+// again once all values have been parsed.
+type resolver interface {
+	resolve() error
+}
+
 // Optional interface for arguments that cumulatively consume all remaining
 // input.
 type remainderArg interface {
@@ -51,6 +67,86 @@ type remainderArg interface {
 	IsCumulative() bool
 }
 
+func isCumulative(value Value) bool {
+	r, ok := value.(remainderArg)
+	return ok && r.IsCumulative()
+}
+
+// -- cumulative Value wrapper
+type cumulativeValue struct {
+	Value
+}
+
+// newCumulativeValue marks value as cumulative, unless it already is.
+func newCumulativeValue(value Value) Value {
+	if r, ok := value.(remainderArg); ok && r.IsCumulative() {
+		return value
+	}
+	return &cumulativeValue{value}
+}
+
+func (c *cumulativeValue) Get() interface{} {
+	if g, ok := c.Value.(Getter); ok {
+		return g.Get()
+	}
+	return c.Value.String()
+}
+
+func (c *cumulativeValue) IsCumulative() bool { return true }
+
+// -- accumulator Value
+type accumulator struct {
+	element func(value interface{}) Value
+	typ     reflect.Type
+	slice   reflect.Value
+}
+
+// NewAccumulator returns a cumulative Value that appends a new element to
+// slice (which must be a pointer to a slice) for every occurrence of a flag
+// or argument.
+//
+// element is called with a pointer to a new, zero valued element of the
+// slice and must return a Value that parses into it. eg.
+//
+//	headers := []Header{}
+//	NewAccumulator(&headers, func(v interface{}) Value { return (*headerValue)(v.(*Header)) })
+func NewAccumulator(slice interface{}, element func(value interface{}) Value) Value {
+	typ := reflect.TypeOf(slice)
+	if typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Slice {
+		panic("expected a pointer to a slice")
+	}
+	return &accumulator{
+		element: element,
+		typ:     typ.Elem().Elem(),
+		slice:   reflect.ValueOf(slice),
+	}
+}
+
+func (a *accumulator) String() string {
+	out := []string{}
+	s := a.slice.Elem()
+	for i := 0; i < s.Len(); i++ {
+		out = append(out, fmt.Sprintf("%v", s.Index(i).Interface()))
+	}
+	return strings.Join(out, ",")
+}
+
+func (a *accumulator) Set(value string) error {
+	e := reflect.New(a.typ)
+	if err := a.element(e.Interface()).Set(value); err != nil {
+		return err
+	}
+	slice := reflect.Append(a.slice.Elem(), e.Elem())
+	a.slice.Elem().Set(slice)
+	return nil
+}
+
+func (a *accumulator) Get() interface{} {
+	return a.slice.Elem().Interface()
+}
+
+func (a *accumulator) IsCumulative() bool { return true }
+
 // -- bool Value
 type boolValue bool
 
@@ -181,6 +277,166 @@ func (f *float64Value) Get() interface{} { return float64(*f) }
 
 func (f *float64Value) String() string { return fmt.Sprintf("%v", *f) }
 
+// -- *big.Int Value
+type bigIntValue big.Int
+
+func newBigIntValue(p *big.Int) *bigIntValue {
+	return (*bigIntValue)(p)
+}
+
+func (b *bigIntValue) Set(s string) error {
+	if _, ok := (*big.Int)(b).SetString(s, 0); !ok {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedInteger }, s)
+	}
+	return nil
+}
+
+func (b *bigIntValue) Get() interface{} { return (*big.Int)(b) }
+
+func (b *bigIntValue) String() string { return (*big.Int)(b).String() }
+
+// -- *big.Float Value
+type bigFloatValue big.Float
+
+func newBigFloatValue(p *big.Float, prec uint) *bigFloatValue {
+	p.SetPrec(prec)
+	return (*bigFloatValue)(p)
+}
+
+func (b *bigFloatValue) Set(s string) error {
+	if _, ok := (*big.Float)(b).SetString(s); !ok {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedNumber }, s)
+	}
+	return nil
+}
+
+func (b *bigFloatValue) Get() interface{} { return (*big.Float)(b) }
+
+func (b *bigFloatValue) String() string { return (*big.Float)(b).Text('g', -1) }
+
+// -- decimal (*big.Rat) Value
+type decimalValue big.Rat
+
+func newDecimalValue(p *big.Rat) *decimalValue {
+	return (*decimalValue)(p)
+}
+
+func (d *decimalValue) Set(s string) error {
+	// Fractions such as "1/3" are not decimals.
+	if strings.Contains(s, "/") {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedDecimal }, s)
+	}
+	if _, ok := (*big.Rat)(d).SetString(s); !ok {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedDecimal }, s)
+	}
+	return nil
+}
+
+func (d *decimalValue) Get() interface{} { return (*big.Rat)(d) }
+
+func (d *decimalValue) String() string {
+	r := (*big.Rat)(d)
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	// Decimals have exact representations with fewer digits than this,
+	// after which the zeros are trimmed.
+	return strings.TrimRight(r.FloatString(4*len(r.Denom().String())), "0")
+}
+
+// PercentMode controls how Percent() interprets numbers without a "%" suffix.
+type PercentMode int
+
+// Interpretations of numbers without a "%" suffix.
+const (
+	// PercentAuto treats numbers greater than 1 as percentages, and others
+	// as ratios, so that "75" and "0.75" are equivalent.
+	PercentAuto PercentMode = iota
+	// PercentRatio treats numbers as ratios, eg. "0.75".
+	PercentRatio
+	// PercentPoints treats numbers as percentages, eg. "75".
+	PercentPoints
+)
+
+// -- percent Value
+type percentValue struct {
+	f    *float64
+	mode PercentMode
+}
+
+func newPercentValue(p *float64, mode PercentMode) *percentValue {
+	return &percentValue{p, mode}
+}
+
+func (p *percentValue) Set(s string) error {
+	number := strings.TrimSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedPercent }, s)
+	}
+	if number != s || p.mode == PercentPoints || (p.mode == PercentAuto && v > 1) {
+		v /= 100
+	}
+	if v < 0 || v > 1 {
+		return valueErrorf(func(m *Messages) string { return m.PercentOutOfRange }, s)
+	}
+	*p.f = v
+	return nil
+}
+
+func (p *percentValue) Get() interface{} { return *p.f }
+
+func (p *percentValue) String() string {
+	return strconv.FormatFloat(*p.f*100, 'f', -1, 64) + "%"
+}
+
+// -- base64 []byte Value
+type base64Value []byte
+
+func newBase64Value(p *[]byte) *base64Value {
+	return (*base64Value)(p)
+}
+
+// base64Encodings are the encodings accepted by Base64Bytes(), which are
+// distinguished by their alphabets and padding.
+var base64Encodings = []*base64.Encoding{
+	base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+}
+
+func (b *base64Value) Set(s string) error {
+	for _, encoding := range base64Encodings {
+		if v, err := encoding.DecodeString(s); err == nil {
+			*b = v
+			return nil
+		}
+	}
+	return valueErrorf(func(m *Messages) string { return m.ExpectedBase64 }, s)
+}
+
+func (b *base64Value) Get() interface{} { return []byte(*b) }
+
+func (b *base64Value) String() string { return base64.StdEncoding.EncodeToString(*b) }
+
+// -- hex []byte Value
+type hexValue []byte
+
+func newHexValue(p *[]byte) *hexValue {
+	return (*hexValue)(p)
+}
+
+func (h *hexValue) Set(s string) error {
+	v, err := hex.DecodeString(s)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedHex }, s)
+	}
+	*h = v
+	return nil
+}
+
+func (h *hexValue) Get() interface{} { return []byte(*h) }
+
+func (h *hexValue) String() string { return hex.EncodeToString(*h) }
+
 // -- time.Duration Value
 type durationValue time.Duration
 
@@ -199,6 +455,131 @@ func (d *durationValue) Get() interface{} { return time.Duration(*d) }
 
 func (d *durationValue) String() string { return (*time.Duration)(d).String() }
 
+// -- time.Time Value
+type timeValue struct {
+	t        *time.Time
+	layout   string
+	location **time.Location
+	text     string
+}
+
+func newTimeValue(p *time.Time, layout string, location **time.Location) *timeValue {
+	return &timeValue{t: p, layout: layout, location: location}
+}
+
+func (t *timeValue) Set(s string) error {
+	t.text = s
+	return t.resolve()
+}
+
+// resolve parses the value again once all values, including the location,
+// have been parsed.
+func (t *timeValue) resolve() error {
+	location := time.Local
+	if t.location != nil && *t.location != nil {
+		location = *t.location
+	}
+	v, err := time.ParseInLocation(t.layout, t.text, location)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.ExpectedTime }, t.layout, t.text)
+	}
+	*t.t = v
+	return nil
+}
+
+func (t *timeValue) Get() interface{} { return *t.t }
+
+func (t *timeValue) String() string {
+	if t.t.IsZero() {
+		return ""
+	}
+	return t.t.Format(t.layout)
+}
+
+// -- *time.Location Value
+type locationValue struct {
+	l **time.Location
+}
+
+func newLocationValue(p **time.Location) *locationValue {
+	return &locationValue{p}
+}
+
+func (l *locationValue) Set(s string) error {
+	location, err := time.LoadLocation(s)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.UnknownTimeZone }, s)
+	}
+	*l.l = location
+	return nil
+}
+
+func (l *locationValue) Get() interface{} { return *l.l }
+
+func (l *locationValue) String() string {
+	if *l.l == nil {
+		return ""
+	}
+	return (*l.l).String()
+}
+
+// TimeOfDay is a wall-clock time, parsed by TimeOfDay().
+type TimeOfDay struct {
+	Hour, Minute, Second int
+}
+
+// timeOfDayLayouts are the forms accepted by TimeOfDay().
+var timeOfDayLayouts = []string{"15:04", "15:04:05", "3:04pm", "3:04PM", "3:04:05pm", "3:04:05PM", "3pm", "3PM"}
+
+// ParseTimeOfDay parses a time of day such as "14:30", "14:30:15" or
+// "2:30pm".
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	for _, layout := range timeOfDayLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return TimeOfDay{t.Hour(), t.Minute(), t.Second()}, nil
+		}
+	}
+	return TimeOfDay{}, valueErrorf(func(m *Messages) string { return m.ExpectedTimeOfDay }, s)
+}
+
+// On returns the time of day on the date of t, in the location of t.
+func (t TimeOfDay) On(date time.Time) time.Time {
+	year, month, day := date.Date()
+	return time.Date(year, month, day, t.Hour, t.Minute, t.Second, 0, date.Location())
+}
+
+// Duration returns the time elapsed since midnight.
+func (t TimeOfDay) Duration() time.Duration {
+	return time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute + time.Duration(t.Second)*time.Second
+}
+
+func (t TimeOfDay) String() string {
+	if t.Second != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	}
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// -- TimeOfDay Value
+type timeOfDayValue TimeOfDay
+
+func newTimeOfDayValue(p *TimeOfDay) *timeOfDayValue {
+	return (*timeOfDayValue)(p)
+}
+
+func (t *timeOfDayValue) Set(s string) error {
+	v, err := ParseTimeOfDay(s)
+	if err != nil {
+		return err
+	}
+	*t = timeOfDayValue(v)
+	return nil
+}
+
+func (t *timeOfDayValue) Get() interface{} { return TimeOfDay(*t) }
+
+func (t *timeOfDayValue) String() string { return TimeOfDay(*t).String() }
+
 // -- []string Value
 type stringsValue []string
 
@@ -211,6 +592,8 @@ func (s *stringsValue) Set(value string) error {
 	return nil
 }
 
+func (s *stringsValue) Get() interface{} { return []string(*s) }
+
 func (s *stringsValue) String() string {
 	return strings.Join(*s, ",")
 }
@@ -231,11 +614,14 @@ var stringMapRegex = regexp.MustCompile("[:=]")
 func (s *stringMapValue) Set(value string) error {
 	parts := stringMapRegex.Split(value, 2)
 	if len(parts) != 2 {
-		return fmt.Errorf("expected KEY=VALUE got '%s'", value)
+		return valueErrorf(func(m *Messages) string { return m.ExpectedKeyValue }, value)
 	}
 	(*s)[parts[0]] = parts[1]
 	return nil
 }
+
+func (s *stringMapValue) Get() interface{} { return map[string]string(*s) }
+
 func (s *stringMapValue) String() string {
 	return fmt.Sprintf("%s", map[string]string(*s))
 }
@@ -253,13 +639,15 @@ func newIPValue(p *net.IP) *ipValue {
 
 func (i *ipValue) Set(value string) error {
 	if ip := net.ParseIP(value); ip == nil {
-		return fmt.Errorf("'%s' is not an IP address", value)
+		return valueErrorf(func(m *Messages) string { return m.InvalidIP }, value)
 	} else {
 		*i = *(*ipValue)(&ip)
 		return nil
 	}
 }
 
+func (i *ipValue) Get() interface{} { return net.IP(*i) }
+
 func (i *ipValue) String() string {
 	return (*net.IP)(i).String()
 }
@@ -275,13 +663,15 @@ func newTCPAddrValue(p **net.TCPAddr) *tcpAddrValue {
 
 func (i *tcpAddrValue) Set(value string) error {
 	if addr, err := net.ResolveTCPAddr("tcp", value); err != nil {
-		return fmt.Errorf("'%s' is not a valid TCP address: %s", value, err)
+		return valueErrorf(func(m *Messages) string { return m.InvalidTCPAddress }, value, err)
 	} else {
 		*i.addr = addr
 		return nil
 	}
 }
 
+func (i *tcpAddrValue) Get() interface{} { return *i.addr }
+
 func (i *tcpAddrValue) String() string {
 	return (*i.addr).String()
 }
@@ -295,13 +685,15 @@ func newTCPAddrsValue(p *[]*net.TCPAddr) *tcpAddrsValue {
 
 func (i *tcpAddrsValue) Set(value string) error {
 	if addr, err := net.ResolveTCPAddr("tcp", value); err != nil {
-		return fmt.Errorf("'%s' is not a valid TCP address: %s", value, err)
+		return valueErrorf(func(m *Messages) string { return m.InvalidTCPAddress }, value, err)
 	} else {
 		*i = append(*i, addr)
 		return nil
 	}
 }
 
+func (i *tcpAddrsValue) Get() interface{} { return []*net.TCPAddr(*i) }
+
 func (i *tcpAddrsValue) String() string {
 	s := make([]string, 0, len(*i))
 	for _, a := range *i {
@@ -310,6 +702,56 @@ func (i *tcpAddrsValue) String() string {
 	return strings.Join(s, ",")
 }
 
+// -- *net.UDPAddr Value
+type udpAddrValue struct {
+	addr **net.UDPAddr
+}
+
+func newUDPAddrValue(p **net.UDPAddr) *udpAddrValue {
+	return &udpAddrValue{p}
+}
+
+func (i *udpAddrValue) Set(value string) error {
+	if addr, err := net.ResolveUDPAddr("udp", value); err != nil {
+		return valueErrorf(func(m *Messages) string { return m.InvalidUDPAddress }, value, err)
+	} else {
+		*i.addr = addr
+		return nil
+	}
+}
+
+func (i *udpAddrValue) Get() interface{} { return *i.addr }
+
+func (i *udpAddrValue) String() string {
+	return (*i.addr).String()
+}
+
+// -- []*net.UDPAddr Value
+type udpAddrsValue []*net.UDPAddr
+
+func newUDPAddrsValue(p *[]*net.UDPAddr) *udpAddrsValue {
+	return (*udpAddrsValue)(p)
+}
+
+func (i *udpAddrsValue) Set(value string) error {
+	if addr, err := net.ResolveUDPAddr("udp", value); err != nil {
+		return valueErrorf(func(m *Messages) string { return m.InvalidUDPAddress }, value, err)
+	} else {
+		*i = append(*i, addr)
+		return nil
+	}
+}
+
+func (i *udpAddrsValue) Get() interface{} { return []*net.UDPAddr(*i) }
+
+func (i *udpAddrsValue) String() string {
+	s := make([]string, 0, len(*i))
+	for _, a := range *i {
+		s = append(s, a.String())
+	}
+	return strings.Join(s, ",")
+}
+
 // -- existingFile Value
 
 type fileStatValue struct {
@@ -326,7 +768,7 @@ func newFileStatValue(p *string, predicate func(os.FileInfo) error) *fileStatVal
 
 func (e *fileStatValue) Set(value string) error {
 	if s, err := os.Stat(value); os.IsNotExist(err) {
-		return fmt.Errorf("path '%s' does not exist", value)
+		return valueErrorf(func(m *Messages) string { return m.PathNotFound }, value)
 	} else if err != nil {
 		return err
 	} else if err := e.predicate(s); err != nil {
@@ -336,10 +778,88 @@ func (e *fileStatValue) Set(value string) error {
 	return nil
 }
 
+func (e *fileStatValue) Get() interface{} { return *e.path }
+
 func (e *fileStatValue) String() string {
 	return *e.path
 }
 
+// -- glob []string Value
+type globFilesValue struct {
+	paths     *[]string
+	mustMatch bool
+}
+
+func newGlobFilesValue(p *[]string, mustMatch bool) *globFilesValue {
+	return &globFilesValue{p, mustMatch}
+}
+
+func (g *globFilesValue) Set(value string) error {
+	matches, err := filepath.Glob(value)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.InvalidPattern }, value, err)
+	}
+	if len(matches) == 0 {
+		if g.mustMatch {
+			return valueErrorf(func(m *Messages) string { return m.NoMatchingFiles }, value)
+		}
+		// As in the shell, a pattern that matches nothing is kept.
+		matches = []string{value}
+	}
+	*g.paths = append(*g.paths, matches...)
+	return nil
+}
+
+func (g *globFilesValue) Get() interface{} { return *g.paths }
+
+func (g *globFilesValue) String() string { return strings.Join(*g.paths, ",") }
+
+func (g *globFilesValue) IsCumulative() bool { return true }
+
+// -- directory tree []string Value
+type dirTreeValue struct {
+	files   *[]string
+	pattern string
+}
+
+func newDirTreeValue(p *[]string, pattern string) *dirTreeValue {
+	return &dirTreeValue{p, pattern}
+}
+
+func (d *dirTreeValue) Set(value string) error {
+	if s, err := os.Stat(value); os.IsNotExist(err) {
+		return valueErrorf(func(m *Messages) string { return m.PathNotFound }, value)
+	} else if err != nil {
+		return err
+	} else if !s.IsDir() {
+		return valueErrorf(func(m *Messages) string { return m.PathIsFile }, s.Name())
+	}
+	files := []string{}
+	err := filepath.Walk(value, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		if d.pattern != "" {
+			if ok, err := filepath.Match(d.pattern, info.Name()); err != nil || !ok {
+				return err
+			}
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	*d.files = append(*d.files, files...)
+	return nil
+}
+
+func (d *dirTreeValue) Get() interface{} { return *d.files }
+
+func (d *dirTreeValue) String() string { return strings.Join(*d.files, ",") }
+
+func (d *dirTreeValue) IsCumulative() bool { return true }
+
 // -- os.File value
 
 type fileValue struct {
@@ -361,6 +881,8 @@ func (f *fileValue) Set(value string) error {
 	}
 }
 
+func (f *fileValue) Get() interface{} { return *f.f }
+
 func (f *fileValue) String() string {
 	if *f.f == nil {
 		return "<nil>"
@@ -368,6 +890,162 @@ func (f *fileValue) String() string {
 	return (*f.f).Name()
 }
 
+// -- io.ReadCloser Value
+type readerValue struct {
+	r    *io.ReadCloser
+	name string
+}
+
+func newReaderValue(p *io.ReadCloser) *readerValue {
+	return &readerValue{r: p}
+}
+
+func (r *readerValue) Set(value string) error {
+	if value == "-" {
+		// Closing the reader must not close stdin.
+		*r.r = ioutil.NopCloser(os.Stdin)
+	} else if fd, err := os.Open(value); err != nil {
+		return err
+	} else {
+		*r.r = fd
+	}
+	r.name = value
+	return nil
+}
+
+func (r *readerValue) Get() interface{} { return *r.r }
+
+func (r *readerValue) String() string {
+	if *r.r == nil {
+		return "<nil>"
+	}
+	return r.name
+}
+
+// -- text/template Value
+type templateValue struct {
+	t     **template.Template
+	funcs template.FuncMap
+	text  string
+}
+
+func newTemplateValue(p **template.Template, funcs template.FuncMap) *templateValue {
+	return &templateValue{t: p, funcs: funcs}
+}
+
+func (t *templateValue) Set(value string) error {
+	name := "template"
+	if strings.HasPrefix(value, "@") {
+		name = value[1:]
+	}
+	text, err := readAtFile(value)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New(name).Funcs(t.funcs).Parse(text)
+	if err != nil {
+		return valueErrorf(func(m *Messages) string { return m.InvalidTemplate }, err)
+	}
+	*t.t = tmpl
+	t.text = value
+	return nil
+}
+
+func (t *templateValue) Get() interface{} { return *t.t }
+
+func (t *templateValue) String() string { return t.text }
+
+// readAtFile returns the contents of the file named by a value of the form
+// @<file>, or the value itself.
+func readAtFile(value string) (string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	data, err := ioutil.ReadFile(value[1:])
+	return string(data), err
+}
+
+// -- JSON Value
+type jsonValue struct {
+	target interface{}
+	strict bool
+	text   string
+}
+
+func newJSONValue(target interface{}, strict bool) *jsonValue {
+	return &jsonValue{target: target, strict: strict}
+}
+
+func (j *jsonValue) Set(value string) error {
+	text, err := readAtFile(value)
+	if err != nil {
+		return err
+	}
+	resetTarget(j.target)
+	decoder := json.NewDecoder(strings.NewReader(text))
+	if j.strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(j.target); err != nil {
+		return valueErrorf(func(m *Messages) string { return m.InvalidJSON }, err)
+	}
+	j.text = value
+	return nil
+}
+
+func (j *jsonValue) Get() interface{} { return j.target }
+
+func (j *jsonValue) String() string { return j.text }
+
+// -- YAML Value
+type yamlValue struct {
+	target interface{}
+	strict bool
+	text   string
+}
+
+func newYAMLValue(target interface{}, strict bool) *yamlValue {
+	return &yamlValue{target: target, strict: strict}
+}
+
+func (y *yamlValue) Set(value string) error {
+	text, err := readAtFile(value)
+	if err != nil {
+		return err
+	}
+	resetTarget(y.target)
+	decoder := yaml.NewDecoder(strings.NewReader(text))
+	decoder.KnownFields(y.strict)
+	// An empty document leaves the target empty.
+	if err := decoder.Decode(y.target); err != nil && err != io.EOF {
+		return valueErrorf(func(m *Messages) string { return m.InvalidYAML }, err)
+	}
+	y.text = value
+	return nil
+}
+
+func (y *yamlValue) Get() interface{} { return y.target }
+
+func (y *yamlValue) String() string { return y.text }
+
+// resetTarget sets the value target points to to its zero value.
+func resetTarget(target interface{}) {
+	if v := reflect.ValueOf(target); v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+}
+
+// cloneTarget returns a pointer to a copy of the value target points to.
+func cloneTarget(target interface{}) interface{} {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return target
+	}
+	out := reflect.New(v.Elem().Type())
+	out.Elem().Set(v.Elem())
+	return out.Interface()
+}
+
 // -- url.URL Value
 type urlValue struct {
 	u **url.URL
@@ -379,13 +1057,15 @@ func newURLValue(p **url.URL) *urlValue {
 
 func (u *urlValue) Set(value string) error {
 	if url, err := url.Parse(value); err != nil {
-		return fmt.Errorf("invalid URL: %s", err)
+		return valueErrorf(func(m *Messages) string { return m.InvalidURL }, err)
 	} else {
 		*u.u = url
 		return nil
 	}
 }
 
+func (u *urlValue) Get() interface{} { return *u.u }
+
 func (u *urlValue) String() string {
 	if *u.u == nil {
 		return "<nil>"
@@ -402,13 +1082,15 @@ func newURLListValue(p *[]*url.URL) *urlListValue {
 
 func (u *urlListValue) Set(value string) error {
 	if url, err := url.Parse(value); err != nil {
-		return fmt.Errorf("invalid URL: %s", err)
+		return valueErrorf(func(m *Messages) string { return m.InvalidURL }, err)
 	} else {
 		*u = append(*u, url)
 		return nil
 	}
 }
 
+func (u *urlListValue) Get() interface{} { return []*url.URL(*u) }
+
 func (u *urlListValue) String() string {
 	out := []string{}
 	for _, url := range *u {
@@ -430,6 +1112,8 @@ func newEnumFlag(target **string, options ...string) *enumValue {
 	}
 }
 
+func (a *enumValue) Get() interface{} { return *a.value }
+
 func (a *enumValue) String() string {
 	return *a.value
 }
@@ -441,7 +1125,7 @@ func (a *enumValue) Set(value string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("enum value must be one of %s, got '%s'", strings.Join(a.options, ","), value)
+	return valueErrorf(func(m *Messages) string { return m.InvalidEnum }, strings.Join(a.options, ","), value)
 }
 
 // -- []string Enum Value
@@ -464,9 +1148,11 @@ func (s *enumsValue) Set(value string) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("enum value must be one of %s, got '%s'", strings.Join(s.options, ","), value)
+	return valueErrorf(func(m *Messages) string { return m.InvalidEnum }, strings.Join(s.options, ","), value)
 }
 
+func (s *enumsValue) Get() interface{} { return *s.value }
+
 func (s *enumsValue) String() string {
 	return strings.Join(*s.value, ",")
 }
@@ -492,3 +1178,334 @@ func (d *bytesValue) Set(s string) error {
 func (d *bytesValue) Get() interface{} { return units.Base2Bytes(*d) }
 
 func (d *bytesValue) String() string { return (*units.Base2Bytes)(d).String() }
+
+// -- log level Value
+type logLevelValue struct {
+	value  *int
+	levels []string
+}
+
+func newLogLevelValue(p *int, levels ...string) *logLevelValue {
+	return &logLevelValue{
+		value:  p,
+		levels: levels,
+	}
+}
+
+func (l *logLevelValue) Set(value string) error {
+	for i, level := range l.levels {
+		if strings.EqualFold(level, value) {
+			*l.value = i
+			return nil
+		}
+	}
+	return valueErrorf(func(m *Messages) string { return m.InvalidLogLevel }, strings.Join(l.levels, ","), value)
+}
+
+func (l *logLevelValue) Get() interface{} { return *l.value }
+
+func (l *logLevelValue) String() string {
+	if *l.value >= 0 && *l.value < len(l.levels) {
+		return l.levels[*l.value]
+	}
+	return ""
+}
+
+// -- Resetting values
+
+// resetter is implemented by Values that can be returned to their zero state,
+// so that an application can be parsed more than once without values
+// accumulating or leaking from one parse into the next.
+type resetter interface {
+	reset()
+}
+
+func resetValue(value Value) {
+	if r, ok := value.(resetter); ok {
+		r.reset()
+	}
+}
+
+func (c *cumulativeValue) reset() { resetValue(c.Value) }
+func (a *accumulator) reset()     { a.slice.Elem().Set(reflect.Zero(a.slice.Elem().Type())) }
+func (b *boolValue) reset()       { *b = false }
+func (i *intValue) reset()        { *i = 0 }
+func (i *int64Value) reset()      { *i = 0 }
+func (i *uintValue) reset()       { *i = 0 }
+func (i *uint64Value) reset()     { *i = 0 }
+func (s *stringValue) reset()     { *s = "" }
+func (f *float64Value) reset()    { *f = 0 }
+func (d *durationValue) reset()   { *d = 0 }
+func (s *stringsValue) reset()    { *s = nil }
+func (s *stringMapValue) reset()  { *s = stringMapValue{} }
+func (i *ipValue) reset()         { *i = nil }
+func (i *tcpAddrValue) reset()    { *i.addr = nil }
+func (i *tcpAddrsValue) reset()   { *i = nil }
+func (i *udpAddrValue) reset()    { *i.addr = nil }
+func (i *udpAddrsValue) reset()   { *i = nil }
+func (e *fileStatValue) reset()   { *e.path = "" }
+func (g *globFilesValue) reset()  { *g.paths = nil }
+func (d *dirTreeValue) reset()    { *d.files = nil }
+func (u *urlValue) reset()        { *u.u = nil }
+func (u *urlListValue) reset()    { *u = nil }
+func (a *enumValue) reset()       { *a.value = "" }
+func (s *enumsValue) reset()      { *s.value = nil }
+func (d *bytesValue) reset()      { *d = 0 }
+func (l *logLevelValue) reset()   { *l.value = 0 }
+
+func (t *timeValue) reset() {
+	*t.t = time.Time{}
+	t.text = ""
+}
+
+func (l *locationValue) reset()  { *l.l = nil }
+func (b *bigIntValue) reset()    { (*big.Int)(b).SetInt64(0) }
+func (b *bigFloatValue) reset()  { (*big.Float)(b).SetInt64(0) }
+func (d *decimalValue) reset()   { (*big.Rat)(d).SetInt64(0) }
+func (p *percentValue) reset()   { *p.f = 0 }
+func (b *base64Value) reset()    { *b = nil }
+func (h *hexValue) reset()       { *h = nil }
+func (t *timeOfDayValue) reset() { *t = timeOfDayValue{} }
+
+func (t *templateValue) reset() {
+	*t.t = nil
+	t.text = ""
+}
+
+func (j *jsonValue) reset() {
+	resetTarget(j.target)
+	j.text = ""
+}
+
+func (y *yamlValue) reset() {
+	resetTarget(y.target)
+	y.text = ""
+}
+
+func (f *fileValue) reset() {
+	if *f.f != nil {
+		(*f.f).Close()
+	}
+	*f.f = nil
+}
+
+func (r *readerValue) reset() {
+	if *r.r != nil {
+		(*r.r).Close()
+	}
+	*r.r = nil
+	r.name = ""
+}
+
+// -- Cloning values
+
+// cloner is implemented by Values that can copy themselves, and the value
+// they hold, into new storage.
+type cloner interface {
+	clone() Value
+}
+
+// cloneValue returns a copy of value, or value itself if it can not be
+// copied.
+func cloneValue(value Value) Value {
+	if c, ok := value.(cloner); ok {
+		return c.clone()
+	}
+	return value
+}
+
+func (c *cumulativeValue) clone() Value { return &cumulativeValue{cloneValue(c.Value)} }
+
+func (a *accumulator) clone() Value {
+	slice := reflect.New(a.slice.Elem().Type())
+	slice.Elem().Set(reflect.AppendSlice(slice.Elem(), a.slice.Elem()))
+	return &accumulator{element: a.element, typ: a.typ, slice: slice}
+}
+
+func (b *boolValue) clone() Value {
+	v := *b
+	return &v
+}
+
+func (i *intValue) clone() Value {
+	v := *i
+	return &v
+}
+
+func (i *int64Value) clone() Value {
+	v := *i
+	return &v
+}
+
+func (i *uintValue) clone() Value {
+	v := *i
+	return &v
+}
+
+func (i *uint64Value) clone() Value {
+	v := *i
+	return &v
+}
+
+func (s *stringValue) clone() Value {
+	v := *s
+	return &v
+}
+
+func (f *float64Value) clone() Value {
+	v := *f
+	return &v
+}
+
+func (d *durationValue) clone() Value {
+	v := *d
+	return &v
+}
+
+func (d *bytesValue) clone() Value {
+	v := *d
+	return &v
+}
+
+func (i *ipValue) clone() Value {
+	v := *i
+	return &v
+}
+
+func (s *stringsValue) clone() Value {
+	v := append(stringsValue(nil), *s...)
+	return &v
+}
+
+func (i *tcpAddrsValue) clone() Value {
+	v := append(tcpAddrsValue(nil), *i...)
+	return &v
+}
+
+func (i *udpAddrsValue) clone() Value {
+	v := append(udpAddrsValue(nil), *i...)
+	return &v
+}
+
+func (u *urlListValue) clone() Value {
+	v := append(urlListValue(nil), *u...)
+	return &v
+}
+
+func (s *stringMapValue) clone() Value {
+	v := stringMapValue{}
+	for key, value := range *s {
+		v[key] = value
+	}
+	return &v
+}
+
+func (i *tcpAddrValue) clone() Value {
+	addr := *i.addr
+	return &tcpAddrValue{&addr}
+}
+
+func (i *udpAddrValue) clone() Value {
+	addr := *i.addr
+	return &udpAddrValue{&addr}
+}
+
+func (e *fileStatValue) clone() Value {
+	path := *e.path
+	return &fileStatValue{&path, e.predicate}
+}
+
+func (f *fileValue) clone() Value {
+	file := *f.f
+	return &fileValue{&file, f.flag, f.perm}
+}
+
+func (t *timeValue) clone() Value {
+	v := *t.t
+	return &timeValue{&v, t.layout, t.location, t.text}
+}
+
+func (b *bigIntValue) clone() Value {
+	return (*bigIntValue)(new(big.Int).Set((*big.Int)(b)))
+}
+
+func (b *bigFloatValue) clone() Value {
+	return (*bigFloatValue)(new(big.Float).Copy((*big.Float)(b)))
+}
+
+func (d *decimalValue) clone() Value {
+	return (*decimalValue)(new(big.Rat).Set((*big.Rat)(d)))
+}
+
+func (b *base64Value) clone() Value {
+	v := append(base64Value(nil), *b...)
+	return &v
+}
+
+func (h *hexValue) clone() Value {
+	v := append(hexValue(nil), *h...)
+	return &v
+}
+
+func (p *percentValue) clone() Value {
+	v := *p.f
+	return &percentValue{&v, p.mode}
+}
+
+func (l *locationValue) clone() Value {
+	location := *l.l
+	return &locationValue{&location}
+}
+
+func (t *timeOfDayValue) clone() Value {
+	v := *t
+	return &v
+}
+
+func (g *globFilesValue) clone() Value {
+	paths := append([]string(nil), *g.paths...)
+	return &globFilesValue{&paths, g.mustMatch}
+}
+
+func (d *dirTreeValue) clone() Value {
+	files := append([]string(nil), *d.files...)
+	return &dirTreeValue{&files, d.pattern}
+}
+
+func (t *templateValue) clone() Value {
+	tmpl := *t.t
+	return &templateValue{&tmpl, t.funcs, t.text}
+}
+
+func (j *jsonValue) clone() Value {
+	return &jsonValue{cloneTarget(j.target), j.strict, j.text}
+}
+
+func (y *yamlValue) clone() Value {
+	return &yamlValue{cloneTarget(y.target), y.strict, y.text}
+}
+
+func (r *readerValue) clone() Value {
+	reader := *r.r
+	return &readerValue{&reader, r.name}
+}
+
+func (u *urlValue) clone() Value {
+	url := *u.u
+	return &urlValue{&url}
+}
+
+func (a *enumValue) clone() Value {
+	value := *a.value
+	return &enumValue{&value, a.options}
+}
+
+func (s *enumsValue) clone() Value {
+	value := append([]string(nil), *s.value...)
+	return &enumsValue{&value, s.options}
+}
+
+func (l *logLevelValue) clone() Value {
+	value := *l.value
+	return &logLevelValue{&value, l.levels}
+}
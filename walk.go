@@ -0,0 +1,37 @@
+package kingpin
+
+// WalkFunc is called by Walk() for each clause in an application. clause is a
+// *FlagClause, *ArgClause or *CmdClause, and path contains the names of the
+// commands enclosing it.
+type WalkFunc func(clause interface{}, path []string) error
+
+// Walk visits every command, flag and argument of the application depth
+// first, in definition order. Each command is visited before its flags,
+// arguments and sub-commands. If fn returns an error, the walk stops and the
+// error is returned.
+func (a *Application) Walk(fn WalkFunc) error {
+	return walkGroups(fn, []string{}, a.flagGroup, a.argGroup, a.cmdGroup)
+}
+
+func walkGroups(fn WalkFunc, path []string, flags *flagGroup, args *argGroup, commands *cmdGroup) error {
+	for _, flag := range flags.flagOrder {
+		if err := fn(flag, path); err != nil {
+			return err
+		}
+	}
+	for _, arg := range args.args {
+		if err := fn(arg, path); err != nil {
+			return err
+		}
+	}
+	for _, cmd := range commands.commandOrder {
+		if err := fn(cmd, path); err != nil {
+			return err
+		}
+		child := append(append([]string{}, path...), cmd.name)
+		if err := walkGroups(fn, child, cmd.flagGroup, cmd.argGroup, cmd.cmdGroup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package kingpin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalk(t *testing.T) {
+	app := New("app", "")
+	app.Flag("debug", "").Bool()
+	remote := app.Command("remote", "")
+	remote.Command("add", "").Arg("name", "").String()
+
+	visited := []string{}
+	err := app.Walk(func(clause interface{}, path []string) error {
+		name := ""
+		switch c := clause.(type) {
+		case *FlagClause:
+			name = "--" + c.name
+		case *ArgClause:
+			name = "<" + c.name + ">"
+		case *CmdClause:
+			name = c.name
+		}
+		visited = append(visited, strings.Join(append(path, name), " "))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"--help", "--debug",
+		"remote", "remote --help",
+		"remote add", "remote add --help", "remote add <name>",
+	}, visited)
+
+	stop := errors.New("stop")
+	count := 0
+	err = app.Walk(func(interface{}, []string) error {
+		count++
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, 1, count)
+}
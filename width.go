@@ -0,0 +1,51 @@
+package kingpin
+
+import "io"
+
+// A WidthDetector determines the width, in columns, of the terminal that
+// usage is written to. See Application.WidthDetector().
+type WidthDetector interface {
+	Width(w io.Writer) int
+}
+
+// WidthDetectorFunc adapts a function to a WidthDetector.
+type WidthDetectorFunc func(w io.Writer) int
+
+// Width calls f(w).
+func (f WidthDetectorFunc) Width(w io.Writer) int {
+	return f(w)
+}
+
+// FixedWidth returns a WidthDetector that always reports width, eg. for tests
+// that compare usage against expected output.
+func FixedWidth(width int) WidthDetector {
+	return WidthDetectorFunc(func(io.Writer) int { return width })
+}
+
+// TerminalWidth is the default WidthDetector. It uses $COLUMNS if set, then
+// asks the terminal w is connected to, falling back on 80 columns.
+var TerminalWidth WidthDetector = WidthDetectorFunc(guessWidth)
+
+// WidthDetector sets how the width of the terminal is determined when
+// displaying usage. The default is TerminalWidth. Embedders such as TUIs and
+// web terminals may supply their own, and tests may use FixedWidth().
+func (a *Application) WidthDetector(detector WidthDetector) *Application {
+	a.widthDetector = detector
+	return a
+}
+
+// width returns the width of the terminal w is connected to, limited to the
+// UsageLayout's MaxWidth.
+func (a *Application) width(w io.Writer) int {
+	width := 0
+	if a.widthDetector != nil {
+		width = a.widthDetector.Width(w)
+	}
+	if width <= 0 {
+		width = TerminalWidth.Width(w)
+	}
+	if a.layout.MaxWidth > 0 && width > a.layout.MaxWidth {
+		width = a.layout.MaxWidth
+	}
+	return width
+}